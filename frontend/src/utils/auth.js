@@ -1,5 +1,7 @@
 // Authentication utility functions
 
+import { PUBLIC_API_URL } from 'astro:env/client';
+
 export const auth = {
   // Get the current token from localStorage
   getToken() {
@@ -18,19 +20,43 @@ export const auth = {
   },
 
   // Set authentication data
-  setAuth(token, user) {
+  setAuth(token, user, refreshToken) {
     localStorage.setItem('token', token);
     localStorage.setItem('user', JSON.stringify(user));
+    localStorage.setItem('refreshToken', refreshToken);
   },
 
   // Clear authentication data
   clearAuth() {
     localStorage.removeItem('token');
     localStorage.removeItem('user');
+    localStorage.removeItem('refreshToken');
   },
 
-  // Make authenticated API request
-  async apiRequest(url, options = {}) {
+  // Exchange the refresh token for a new access token. Refresh tokens are
+  // single use, so the new one replaces it.
+  async refresh() {
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (!refreshToken) {
+      return false;
+    }
+
+    const response = await fetch(`${PUBLIC_API_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken })
+    });
+    if (!response.ok) {
+      return false;
+    }
+
+    const data = await response.json();
+    this.setAuth(data.token, data.user, data.refresh_token);
+    return true;
+  },
+
+  // Make authenticated API request, refreshing an expired access token once
+  async apiRequest(url, options = {}, retried = false) {
     const token = this.getToken();
     if (!token) {
       throw new Error('No authentication token');
@@ -45,7 +71,11 @@ export const auth = {
     };
 
     const response = await fetch(url, { ...defaultOptions, ...options });
-    
+
+    if (response.status === 401 && !retried && await this.refresh()) {
+      return this.apiRequest(url, options, true);
+    }
+
     if (response.status === 401) {
       this.clearAuth();
       window.location.href = '/login';
@@ -55,8 +85,15 @@ export const auth = {
     return response;
   },
 
-  // Logout and redirect
-  logout() {
+  // Logout, ending the session on the server, and redirect
+  async logout() {
+    const token = this.getToken();
+    if (token) {
+      await fetch(`${PUBLIC_API_URL}/auth/logout`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` }
+      }).catch(() => {});
+    }
     this.clearAuth();
     window.location.href = '/login';
   }
@@ -1,14 +1,20 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	"github.com/nahue/playlists/internal/database"
 	"github.com/nahue/playlists/internal/handlers"
+	"github.com/nahue/playlists/internal/live"
+	"github.com/nahue/playlists/internal/mailer"
+	"github.com/nahue/playlists/internal/oidc"
 	"github.com/nahue/playlists/migrations"
 )
 
@@ -20,19 +26,100 @@ type Application struct {
 	BandHandler         *handlers.BandHandler
 	AuthHandler         *handlers.AuthHandler
 	BandPlaylistHandler *handlers.BandPlaylistHandler
+	PlaylistHandler     *handlers.PlaylistHandler
+	BandUserHandler     *handlers.BandUserHandler
+	InvitationHandler   *handlers.InvitationHandler
+	AccountHandler      *handlers.AccountHandler
+	MFAHandler          *handlers.MFAHandler
+	TokenHandler        *handlers.PersonalAccessTokenHandler
+	OIDCHandler         *handlers.OIDCHandler
+	BandSongHandler     *handlers.BandSongHandler
+	GigHandler          *handlers.GigHandler
+	CalendarHandler     *handlers.CalendarHandler
+	RehearsalHandler    *handlers.RehearsalHandler
+	ReadinessHandler    *handlers.ReadinessHandler
+	LiveHandler         *handlers.LiveHandler
 }
 
+// defaultJWTSecret is only accepted in development
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
 // Config holds application configuration
 type Config struct {
-	Port string
-	Host string
+	Port      string
+	Host      string
+	Env       string // "development" or "production"
+	AppURL    string // public URL of the frontend, used in emailed links
+	APIURL    string // public URL of the API, used in OIDC redirect URLs
+	JWTSecret string
+	JWTIssuer string
+	JWTTTL    time.Duration // lifetime of access tokens
+	// JWTRefreshTTL is how long a session survives without being refreshed
+	JWTRefreshTTL time.Duration
+	// OIDCProviders are the identity providers users can sign in with
+	OIDCProviders []oidc.Config
 }
 
 // NewConfig creates a new application config from environment variables
 func NewConfig() *Config {
+	apiURL := strings.TrimRight(getEnv("API_URL", "http://localhost:8080"), "/")
+
 	return &Config{
-		Port: getEnv("SERVER_PORT", "8080"),
-		Host: getEnv("SERVER_HOST", ""),
+		Port:      getEnv("SERVER_PORT", "8080"),
+		Host:      getEnv("SERVER_HOST", ""),
+		Env:       getEnv("APP_ENV", "production"),
+		AppURL:    getEnv("APP_URL", "http://localhost:4321"),
+		APIURL:    apiURL,
+		JWTSecret: getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTIssuer: getEnv("JWT_ISSUER", "playlists-app"),
+		JWTTTL:    getDurationEnv("JWT_TTL", 15*time.Minute),
+
+		JWTRefreshTTL: getDurationEnv("JWT_REFRESH_TTL", 30*24*time.Hour),
+		OIDCProviders: getOIDCProviders(apiURL),
+	}
+}
+
+// IsDevelopment reports whether the application runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "development"
+}
+
+// Validate checks that the configuration is safe to run with
+func (c *Config) Validate() error {
+	if c.JWTSecret == defaultJWTSecret && !c.IsDevelopment() {
+		return errors.New("JWT_SECRET must be set outside development (APP_ENV=development)")
+	}
+	if len(c.JWTSecret) < 32 && !c.IsDevelopment() {
+		return errors.New("JWT_SECRET must be at least 32 characters")
+	}
+	if c.JWTTTL <= 0 {
+		return errors.New("JWT_TTL must be a positive duration such as 15m or 24h")
+	}
+	if c.JWTRefreshTTL <= c.JWTTTL {
+		return errors.New("JWT_REFRESH_TTL must be a duration longer than JWT_TTL")
+	}
+	for _, provider := range c.OIDCProviders {
+		prefix := oidcEnvPrefix(provider.Name)
+		if strings.Trim(provider.Name, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return fmt.Errorf("OIDC provider name %q may only contain lowercase letters, digits and dashes", provider.Name)
+		}
+		if provider.IssuerURL == "" || provider.ClientID == "" {
+			return fmt.Errorf("%sISSUER and %sCLIENT_ID must be set", prefix, prefix)
+		}
+		if !strings.HasPrefix(provider.IssuerURL, "https://") && !c.IsDevelopment() {
+			return fmt.Errorf("%sISSUER must be an https URL", prefix)
+		}
+	}
+	return nil
+}
+
+// JWT returns the token settings used by the handlers
+func (c *Config) JWT() handlers.JWTConfig {
+	return handlers.JWTConfig{
+		Secret:     []byte(c.JWTSecret),
+		Issuer:     c.JWTIssuer,
+		AccessTTL:  c.JWTTTL,
+		RefreshTTL: c.JWTRefreshTTL,
 	}
 }
 
@@ -43,6 +130,11 @@ func NewApplication() *Application {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
+	config := NewConfig()
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Initialize database connection
 	dbConfig := database.NewConfig()
 	db, err := database.Open(dbConfig)
@@ -57,23 +149,76 @@ func NewApplication() *Application {
 
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile)
 
+	m, err := mailer.New(mailer.NewConfig(), logger)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+
 	// Initialize repositories
 	bandRepo := database.NewBandRepository(db)
 	userRepo := database.NewUserRepository(db)
 	playlistRepo := database.NewBandPlaylistRepository(db)
+	entryRepo := database.NewPlaylistEntryRepository(db)
+	bandUserRepo := database.NewBandUserRepository(db)
+	invitationRepo := database.NewBandInvitationRepository(db)
+	sessionRepo := database.NewSessionRepository(db)
+	tokenRepo := database.NewUserTokenRepository(db)
+	mfaRepo := database.NewMFARepository(db)
+	patRepo := database.NewPersonalAccessTokenRepository(db)
+	identityRepo := database.NewUserIdentityRepository(db)
+	songRepo := database.NewBandSongRepository(db)
+	gigRepo := database.NewGigRepository(db)
+	feedRepo := database.NewCalendarFeedRepository(db)
+	rehearsalRepo := database.NewRehearsalRepository(db)
+	readinessRepo := database.NewReadinessRepository(db)
+
+	// Live setlist sessions are kept in memory
+	liveHub := live.NewHub()
+
+	// Identity providers are discovered on first use
+	var oidcProviders []*oidc.Provider
+	for _, providerConfig := range config.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(providerConfig, nil))
+	}
 
 	// Initialize handlers
 	bandHandler := handlers.NewBandHandler(bandRepo, logger)
-	authHandler := handlers.NewAuthHandler(userRepo, logger)
-	playlistHandler := handlers.NewBandPlaylistHandler(playlistRepo, logger)
+	accountHandler := handlers.NewAccountHandler(userRepo, tokenRepo, sessionRepo, mfaRepo, m, logger, config.AppURL)
+	authHandler := handlers.NewAuthHandler(userRepo, sessionRepo, invitationRepo, mfaRepo, patRepo, accountHandler, config.JWT(), logger)
+	playlistHandler := handlers.NewBandPlaylistHandler(playlistRepo, bandRepo, logger)
+	entryHandler := handlers.NewPlaylistHandler(entryRepo, logger)
+	bandUserHandler := handlers.NewBandUserHandler(bandUserRepo, logger)
+	invitationHandler := handlers.NewInvitationHandler(invitationRepo, m, config.JWT(), logger, config.AppURL)
+	mfaHandler := handlers.NewMFAHandler(userRepo, mfaRepo, logger)
+	oidcHandler := handlers.NewOIDCHandler(oidcProviders, identityRepo, authHandler, config.JWT(), logger, config.AppURL, strings.HasPrefix(config.APIURL, "https://"))
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(patRepo, logger)
+	songHandler := handlers.NewBandSongHandler(songRepo, logger)
+	gigHandler := handlers.NewGigHandler(gigRepo, logger)
+	calendarHandler := handlers.NewCalendarHandler(feedRepo, logger, config.APIURL)
+	rehearsalHandler := handlers.NewRehearsalHandler(rehearsalRepo, logger)
+	readinessHandler := handlers.NewReadinessHandler(readinessRepo, logger)
+	liveHandler := handlers.NewLiveHandler(liveHub, playlistRepo, songRepo, bandUserRepo, sessionRepo, config.JWT(), logger)
 
 	return &Application{
 		Logger:              logger,
-		Config:              NewConfig(),
+		Config:              config,
 		DB:                  db,
 		BandHandler:         bandHandler,
 		AuthHandler:         authHandler,
 		BandPlaylistHandler: playlistHandler,
+		PlaylistHandler:     entryHandler,
+		BandUserHandler:     bandUserHandler,
+		InvitationHandler:   invitationHandler,
+		AccountHandler:      accountHandler,
+		MFAHandler:          mfaHandler,
+		TokenHandler:        tokenHandler,
+		OIDCHandler:         oidcHandler,
+		BandSongHandler:     songHandler,
+		GigHandler:          gigHandler,
+		CalendarHandler:     calendarHandler,
+		RehearsalHandler:    rehearsalHandler,
+		ReadinessHandler:    readinessHandler,
+		LiveHandler:         liveHandler,
 	}
 }
 
@@ -94,3 +239,47 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationEnv gets a duration environment variable or returns a default value.
+// An unparsable value yields 0, which Validate rejects.
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// getOIDCProviders reads the identity providers named in OIDC_PROVIDERS
+// (comma-separated). Each provider is configured with OIDC_<NAME>_ISSUER,
+// OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and optionally
+// OIDC_<NAME>_SCOPES (space-separated).
+func getOIDCProviders(apiURL string) []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := oidcEnvPrefix(name)
+		providers = append(providers, oidc.Config{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  apiURL + "/auth/oidc/" + name + "/callback",
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+	return providers
+}
+
+// oidcEnvPrefix returns the environment variable prefix of a provider, e.g. OIDC_MY_IDP_
+func oidcEnvPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
@@ -2,8 +2,9 @@ package app
 
 import (
 	"testing"
+	"time"
 
-	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/oidc"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -16,26 +17,36 @@ func TestNewConfig(t *testing.T) {
 	if config.Port != "8080" {
 		t.Errorf("Expected Port to be '8080', got '%s'", config.Port)
 	}
-}
-
-func TestNew(t *testing.T) {
-	router := chi.NewRouter()
-	app := New(router)
 
-	if app == nil {
-		t.Error("Expected New() to return a non-nil Application")
-	}
-
-	if app.config == nil {
-		t.Error("Expected Application to have a config")
+	if config.JWTTTL != 15*time.Minute {
+		t.Errorf("Expected JWTTTL to be 15m, got '%s'", config.JWTTTL)
 	}
+}
 
-	if app.router == nil {
-		t.Error("Expected Application to have a router")
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"default secret in production", Config{Env: "production", JWTSecret: defaultJWTSecret, JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour}, true},
+		{"default secret in development", Config{Env: "development", JWTSecret: defaultJWTSecret, JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour}, false},
+		{"short secret in production", Config{Env: "production", JWTSecret: "too-short", JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour}, true},
+		{"strong secret in production", Config{Env: "production", JWTSecret: "0123456789abcdef0123456789abcdef", JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour}, false},
+		{"invalid TTL", Config{Env: "development", JWTSecret: defaultJWTSecret, JWTTTL: 0, JWTRefreshTTL: 24 * time.Hour}, true},
+		{"refresh TTL shorter than access TTL", Config{Env: "development", JWTSecret: defaultJWTSecret, JWTTTL: time.Hour, JWTRefreshTTL: time.Minute}, true},
+		{"OIDC provider without client ID", Config{Env: "development", JWTSecret: defaultJWTSecret, JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour, OIDCProviders: []oidc.Config{{Name: "idp", IssuerURL: "https://idp.example.com"}}}, true},
+		{"OIDC provider over http in production", Config{Env: "production", JWTSecret: "0123456789abcdef0123456789abcdef", JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour, OIDCProviders: []oidc.Config{{Name: "idp", IssuerURL: "http://idp.example.com", ClientID: "client"}}}, true},
+		{"OIDC provider", Config{Env: "production", JWTSecret: "0123456789abcdef0123456789abcdef", JWTTTL: time.Hour, JWTRefreshTTL: 24 * time.Hour, OIDCProviders: []oidc.Config{{Name: "idp", IssuerURL: "https://idp.example.com", ClientID: "client"}}}, false},
 	}
 
-	if app.server != nil {
-		t.Error("Expected server to be nil before initialization")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
@@ -46,3 +57,42 @@ func TestGetEnv(t *testing.T) {
 		t.Errorf("Expected 'default_value', got '%s'", result)
 	}
 }
+
+func TestGetDurationEnv(t *testing.T) {
+	t.Setenv("TEST_DURATION", "15m")
+	if d := getDurationEnv("TEST_DURATION", time.Hour); d != 15*time.Minute {
+		t.Errorf("Expected 15m, got '%s'", d)
+	}
+
+	t.Setenv("TEST_DURATION", "soon")
+	if d := getDurationEnv("TEST_DURATION", time.Hour); d != 0 {
+		t.Errorf("Expected 0 for an invalid duration, got '%s'", d)
+	}
+
+	if d := getDurationEnv("NON_EXISTENT_VAR", time.Hour); d != time.Hour {
+		t.Errorf("Expected the default value, got '%s'", d)
+	}
+}
+
+func TestGetOIDCProviders(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "Google, my-idp,")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "google-client")
+	t.Setenv("OIDC_MY_IDP_ISSUER", "https://idp.example.com")
+	t.Setenv("OIDC_MY_IDP_SCOPES", "openid email")
+
+	providers := getOIDCProviders("https://api.example.com")
+	if len(providers) != 2 {
+		t.Fatalf("Expected 2 providers, got %d", len(providers))
+	}
+
+	if providers[0].Name != "google" || providers[0].ClientID != "google-client" {
+		t.Errorf("Unexpected first provider %+v", providers[0])
+	}
+	if providers[1].RedirectURL != "https://api.example.com/auth/oidc/my-idp/callback" {
+		t.Errorf("Unexpected redirect URL %s", providers[1].RedirectURL)
+	}
+	if len(providers[1].Scopes) != 2 || providers[1].IssuerURL != "https://idp.example.com" {
+		t.Errorf("Unexpected second provider %+v", providers[1])
+	}
+}
@@ -0,0 +1,74 @@
+// Package availability works out when a band can meet from the answers its
+// members give to a poll of candidate times.
+package availability
+
+// Answer is a member's answer to whether they can make a candidate time
+type Answer string
+
+const (
+	Yes   Answer = "yes"
+	Maybe Answer = "maybe"
+	No    Answer = "no"
+)
+
+// Valid reports whether the answer is yes, maybe or no
+func (a Answer) Valid() bool {
+	return a == Yes || a == Maybe || a == No
+}
+
+// Tally counts the answers given for a candidate time
+type Tally struct {
+	Yes   int `json:"yes"`
+	Maybe int `json:"maybe"`
+	No    int `json:"no"`
+	// Unanswered counts the members who have not answered
+	Unanswered int `json:"unanswered"`
+}
+
+// Count tallies the answers given for a candidate time by some of a band's
+// members
+func Count(answers []Answer, members int) Tally {
+	var t Tally
+	for _, answer := range answers {
+		switch answer {
+		case Yes:
+			t.Yes++
+		case Maybe:
+			t.Maybe++
+		case No:
+			t.No++
+		}
+	}
+	if answered := t.Yes + t.Maybe + t.No; answered < members {
+		t.Unanswered = members - answered
+	}
+	return t
+}
+
+// better reports whether a tally beats another: more members can make it,
+// then fewer cannot, then more might
+func (t Tally) better(other Tally) bool {
+	if t.Yes != other.Yes {
+		return t.Yes > other.Yes
+	}
+	if t.No != other.No {
+		return t.No < other.No
+	}
+	return t.Maybe > other.Maybe
+}
+
+// Best returns the index of the best candidate time, the earliest of equally
+// good ones when tallies are in time order. It returns -1 when no member can
+// or might make any of them.
+func Best(tallies []Tally) int {
+	best := -1
+	for i, t := range tallies {
+		if t.Yes == 0 && t.Maybe == 0 {
+			continue
+		}
+		if best < 0 || t.better(tallies[best]) {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,35 @@
+package availability
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	got := Count([]Answer{Yes, No, Maybe, Yes}, 6)
+	want := Tally{Yes: 2, Maybe: 1, No: 1, Unanswered: 2}
+	if got != want {
+		t.Errorf("Count = %+v, want %+v", got, want)
+	}
+}
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		name    string
+		tallies []Tally
+		want    int
+	}{
+		{"no slots", nil, -1},
+		{"nobody can make it", []Tally{{No: 3}, {Unanswered: 3}}, -1},
+		{"most yes wins", []Tally{{Yes: 2, No: 1}, {Yes: 3, No: 2}}, 1},
+		{"fewer no breaks a tie", []Tally{{Yes: 2, No: 1}, {Yes: 2, Unanswered: 1}}, 1},
+		{"more maybe breaks a tie", []Tally{{Yes: 2, Unanswered: 2}, {Yes: 2, Maybe: 1, Unanswered: 1}}, 1},
+		{"earliest of equal slots", []Tally{{Yes: 2, Maybe: 1}, {Yes: 2, Maybe: 1}}, 0},
+		{"maybe is better than nothing", []Tally{{No: 2}, {Maybe: 1, No: 1}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Best(tt.tallies); got != tt.want {
+				t.Errorf("Best = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+// Package chordpro parses ChordPro lyric and chord sheets and renders them
+// as plain text or HTML.
+package chordpro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineKind is the kind of a line of a sheet
+type LineKind string
+
+const (
+	// LineLyrics is a line of lyrics with chords
+	LineLyrics LineKind = "lyrics"
+	// LineComment is a comment directive, shown to the performer
+	LineComment LineKind = "comment"
+	// LineChorus repeats the chorus
+	LineChorus LineKind = "chorus"
+	// LineVerbatim is a line of a tab or grid, kept as written
+	LineVerbatim LineKind = "verbatim"
+	// LineEmpty separates paragraphs
+	LineEmpty LineKind = "empty"
+)
+
+// Sheet is a parsed ChordPro document
+type Sheet struct {
+	Title     string              `json:"title"`
+	Subtitles []string            `json:"subtitles"`
+	Meta      map[string][]string `json:"meta"`
+	Sections  []Section           `json:"sections"`
+}
+
+// Section is a part of a sheet, such as a verse or the chorus. Lines outside
+// any start_of/end_of environment form sections without a kind.
+type Section struct {
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Lines []Line `json:"lines"`
+}
+
+// Line is a line of a section. Lyrics lines have segments; comments, chorus
+// repeats and verbatim lines have text.
+type Line struct {
+	Kind     LineKind  `json:"kind"`
+	Segments []Segment `json:"segments,omitempty"`
+	Text     string    `json:"text,omitempty"`
+}
+
+// Segment is a chord and the lyrics sung from it until the next chord. The
+// first segment of a line may have no chord.
+type Segment struct {
+	Chord  string `json:"chord"`
+	Lyrics string `json:"lyrics"`
+}
+
+// Error is a problem at a line of a ChordPro document
+type Error struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ErrorList holds every problem found in a document, in line order
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	if len(l) == 1 {
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
+
+// directiveAliases maps the short forms of directives to their full names
+var directiveAliases = map[string]string{
+	"t":    "title",
+	"st":   "subtitle",
+	"c":    "comment",
+	"ci":   "comment_italic",
+	"cb":   "comment_box",
+	"soc":  "start_of_chorus",
+	"eoc":  "end_of_chorus",
+	"sov":  "start_of_verse",
+	"eov":  "end_of_verse",
+	"sob":  "start_of_bridge",
+	"eob":  "end_of_bridge",
+	"sot":  "start_of_tab",
+	"eot":  "end_of_tab",
+	"sog":  "start_of_grid",
+	"eog":  "end_of_grid",
+	"np":   "new_page",
+	"npp":  "new_physical_page",
+	"col":  "columns",
+	"colb": "column_break",
+	"ns":   "new_song",
+	"g":    "grid",
+	"ng":   "no_grid",
+}
+
+// metaDirectives are the directives that describe the song
+var metaDirectives = map[string]bool{
+	"artist": true, "composer": true, "lyricist": true, "arranger": true, "copyright": true,
+	"album": true, "year": true, "key": true, "time": true, "tempo": true, "duration": true,
+	"capo": true, "sorttitle": true,
+}
+
+// commentDirectives are the directives that print a comment
+var commentDirectives = map[string]bool{
+	"comment": true, "comment_italic": true, "comment_box": true, "highlight": true,
+}
+
+// layoutDirectives only change how a sheet is printed, so they are accepted
+// and ignored
+var layoutDirectives = map[string]bool{
+	"new_page": true, "new_physical_page": true, "column_break": true, "columns": true,
+	"pagetype": true, "grid": true, "no_grid": true, "titles": true, "define": true,
+	"chord": true, "image": true, "diagrams": true,
+	"textfont": true, "textsize": true, "textcolour": true, "chordfont": true, "chordsize": true,
+	"chordcolour": true, "tabfont": true, "tabsize": true, "tabcolour": true, "titlefont": true,
+	"titlesize": true, "titlecolour": true, "footerfont": true, "footersize": true, "footercolour": true,
+	"tocfont": true, "tocsize": true, "toccolour": true,
+}
+
+// verbatimSections keep their lines as written, without reading chords
+var verbatimSections = map[string]bool{"tab": true, "grid": true}
+
+// parser holds the state of a document being parsed
+type parser struct {
+	sheet   *Sheet
+	errors  ErrorList
+	section *Section
+	// open is the environment being read and opened the line it started at
+	open   string
+	opened int
+}
+
+// Parse reads a ChordPro document. It reports every problem it finds as an
+// ErrorList.
+func Parse(src string) (*Sheet, error) {
+	p := &parser{sheet: &Sheet{Subtitles: []string{}, Meta: map[string][]string{}, Sections: []Section{}}}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i, text := range lines {
+		p.line(i+1, text)
+	}
+	if p.open != "" {
+		p.errorf(p.opened, "start_of_%s is never ended", p.open)
+	}
+	p.flush()
+
+	if len(p.errors) > 0 {
+		return nil, p.errors
+	}
+	return p.sheet, nil
+}
+
+func (p *parser) errorf(line int, format string, args ...any) {
+	p.errors = append(p.errors, &Error{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// flush ends the current section, dropping trailing empty lines
+func (p *parser) flush() {
+	if p.section == nil {
+		return
+	}
+	lines := p.section.Lines
+	for len(lines) > 0 && lines[len(lines)-1].Kind == LineEmpty {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 || p.section.Kind != "" {
+		p.section.Lines = lines
+		p.sheet.Sections = append(p.sheet.Sections, *p.section)
+	}
+	p.section = nil
+}
+
+// add appends a line to the current section, starting one when needed.
+// Empty lines are only kept between other lines.
+func (p *parser) add(line Line) {
+	if p.section == nil {
+		if line.Kind == LineEmpty {
+			return
+		}
+		p.section = &Section{}
+	}
+	if line.Kind == LineEmpty && len(p.section.Lines) == 0 {
+		return
+	}
+	if line.Kind == LineEmpty && p.section.Lines[len(p.section.Lines)-1].Kind == LineEmpty {
+		return
+	}
+	p.section.Lines = append(p.section.Lines, line)
+}
+
+func (p *parser) line(number int, text string) {
+	trimmed := strings.TrimSpace(text)
+	if verbatimSections[p.open] && !strings.HasPrefix(trimmed, "{") {
+		p.add(Line{Kind: LineVerbatim, Text: strings.TrimRight(text, " \t")})
+		return
+	}
+
+	switch {
+	case trimmed == "":
+		p.add(Line{Kind: LineEmpty})
+	case strings.HasPrefix(trimmed, "#"):
+		// Comments for whoever edits the file
+	case strings.HasPrefix(trimmed, "{"):
+		if !strings.HasSuffix(trimmed, "}") {
+			p.errorf(number, "unterminated directive")
+			return
+		}
+		p.directive(number, trimmed[1:len(trimmed)-1])
+	default:
+		segments, err := parseLyrics(strings.TrimRight(text, " \t"))
+		if err != "" {
+			p.errorf(number, "%s", err)
+			return
+		}
+		p.add(Line{Kind: LineLyrics, Segments: segments})
+	}
+}
+
+// splitDirective returns the full name and the value of a directive, given
+// without its braces
+func splitDirective(body string) (string, string) {
+	name, value, _ := strings.Cut(body, ":")
+	if strings.ContainsAny(name, " \t") && !strings.Contains(body, ":") {
+		name, value, _ = strings.Cut(strings.TrimSpace(body), " ")
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := directiveAliases[name]; ok {
+		name = alias
+	}
+	return name, strings.TrimSpace(value)
+}
+
+func (p *parser) directive(number int, body string) {
+	name, value := splitDirective(body)
+
+	switch {
+	case name == "":
+		p.errorf(number, "empty directive")
+	case name == "title" || name == "subtitle" || metaDirectives[name] || name == "meta":
+		if value == "" {
+			p.errorf(number, "%s needs a value", name)
+			return
+		}
+		switch name {
+		case "title":
+			p.sheet.Title = value
+		case "subtitle":
+			p.sheet.Subtitles = append(p.sheet.Subtitles, value)
+		case "meta":
+			key, metaValue, _ := strings.Cut(value, " ")
+			p.sheet.Meta[strings.ToLower(key)] = append(p.sheet.Meta[strings.ToLower(key)], strings.TrimSpace(metaValue))
+		default:
+			p.sheet.Meta[name] = append(p.sheet.Meta[name], value)
+		}
+	case commentDirectives[name]:
+		p.add(Line{Kind: LineComment, Text: value})
+	case name == "chorus":
+		p.add(Line{Kind: LineChorus, Text: sectionLabel(value)})
+	case strings.HasPrefix(name, "start_of_"):
+		kind := strings.TrimPrefix(name, "start_of_")
+		if p.open != "" {
+			p.errorf(number, "start_of_%s inside start_of_%s from line %d", kind, p.open, p.opened)
+			return
+		}
+		p.flush()
+		p.open, p.opened = kind, number
+		p.section = &Section{Kind: kind, Label: sectionLabel(value), Lines: []Line{}}
+	case strings.HasPrefix(name, "end_of_"):
+		kind := strings.TrimPrefix(name, "end_of_")
+		if p.open != kind {
+			p.errorf(number, "end_of_%s without start_of_%s", kind, kind)
+			return
+		}
+		p.flush()
+		p.open = ""
+	case layoutDirectives[name] || strings.HasPrefix(name, "x_"):
+		// Printing hints and custom extensions
+	default:
+		p.errorf(number, "unknown directive %q", name)
+	}
+}
+
+// sectionLabel reads the label of a section, given as its value or as
+// label="..."
+func sectionLabel(value string) string {
+	if rest, ok := strings.CutPrefix(value, "label="); ok {
+		return strings.Trim(rest, `"'`)
+	}
+	return value
+}
+
+// parseLyrics splits a lyrics line into chords and the lyrics that follow
+// them. It returns a message for an invalid line.
+func parseLyrics(text string) ([]Segment, string) {
+	var segments []Segment
+	current := Segment{}
+	// start is where the lyrics of the current segment begin
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '[':
+			end := strings.IndexByte(text[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Sprintf("unclosed chord at column %d", i+1)
+			}
+			chord := strings.TrimSpace(text[i+1 : i+1+end])
+			if chord == "" {
+				return nil, fmt.Sprintf("empty chord at column %d", i+1)
+			}
+			if strings.ContainsRune(chord, '[') {
+				return nil, fmt.Sprintf("unclosed chord at column %d", i+1)
+			}
+			current.Lyrics = text[start:i]
+			if current.Chord != "" || current.Lyrics != "" {
+				segments = append(segments, current)
+			}
+			current = Segment{Chord: chord}
+			i += end + 1
+			start = i + 1
+		case ']':
+			return nil, fmt.Sprintf("unexpected ] at column %d", i+1)
+		}
+	}
+	current.Lyrics = text[start:]
+	if current.Chord != "" || current.Lyrics != "" || len(segments) == 0 {
+		segments = append(segments, current)
+	}
+	return segments, ""
+}
@@ -0,0 +1,203 @@
+package chordpro
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSheet = `{title: Let It Be}
+{st: Beatles cover}
+{artist: The Beatles}
+{key: C}
+{tempo: 72}
+# Slower than the record
+
+{start_of_verse: Verse 1}
+When I [C]find myself in [G]times of trouble
+[Am]Mother Mary [F]comes to me
+{end_of_verse}
+
+{soc}
+Let it [Am]be, let it [G]be
+{eoc}
+
+{c: Guitar solo}
+{start_of_tab}
+e|--0--1--|
+{end_of_tab}
+{chorus}
+`
+
+func TestParse(t *testing.T) {
+	sheet, err := Parse(testSheet)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if sheet.Title != "Let It Be" || !reflect.DeepEqual(sheet.Subtitles, []string{"Beatles cover"}) {
+		t.Errorf("title = %q, subtitles = %q", sheet.Title, sheet.Subtitles)
+	}
+	if !reflect.DeepEqual(sheet.Meta["key"], []string{"C"}) || !reflect.DeepEqual(sheet.Meta["artist"], []string{"The Beatles"}) {
+		t.Errorf("meta = %v", sheet.Meta)
+	}
+
+	kinds := []string{}
+	for _, section := range sheet.Sections {
+		kinds = append(kinds, section.Kind)
+	}
+	if want := []string{"verse", "chorus", "", "tab", ""}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("section kinds = %q, want %q", kinds, want)
+	}
+
+	verse := sheet.Sections[0]
+	if verse.Label != "Verse 1" {
+		t.Errorf("verse label = %q", verse.Label)
+	}
+	want := []Segment{{Lyrics: "When I "}, {Chord: "C", Lyrics: "find myself in "}, {Chord: "G", Lyrics: "times of trouble"}}
+	if !reflect.DeepEqual(verse.Lines[0].Segments, want) {
+		t.Errorf("first line = %+v, want %+v", verse.Lines[0].Segments, want)
+	}
+	if !reflect.DeepEqual(sheet.Sections[2].Lines[0], Line{Kind: LineComment, Text: "Guitar solo"}) {
+		t.Errorf("comment = %+v", sheet.Sections[2].Lines[0])
+	}
+	if !reflect.DeepEqual(sheet.Sections[3].Lines[0], Line{Kind: LineVerbatim, Text: "e|--0--1--|"}) {
+		t.Errorf("tab = %+v", sheet.Sections[3].Lines[0])
+	}
+	if sheet.Sections[4].Lines[0].Kind != LineChorus {
+		t.Errorf("chorus repeat = %+v", sheet.Sections[4].Lines[0])
+	}
+}
+
+func TestParseKeepsNonASCIILyrics(t *testing.T) {
+	sheet, err := Parse("Canción de [Am]cuna, mañana")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	want := []Segment{{Lyrics: "Canción de "}, {Chord: "Am", Lyrics: "cuna, mañana"}}
+	if got := sheet.Sections[0].Lines[0].Segments; !reflect.DeepEqual(got, want) {
+		t.Errorf("segments = %+v, want %+v", got, want)
+	}
+	if got := sheet.Text(); !strings.Contains(got, "           Am\nCanción de cuna, mañana") {
+		t.Errorf("text = %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	src := "{title: Broken}\n" +
+		"{start_of_verse}\n" +
+		"Some [Am lyrics\n" +
+		"More [] lyrics\n" +
+		"{end_of_chorus}\n" +
+		"{colour: red}\n" +
+		"{comment: unterminated\n"
+
+	_, err := Parse(src)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Parse should return an ErrorList, got %v", err)
+	}
+
+	got := map[int]string{}
+	for _, e := range list {
+		got[e.Line] = e.Message
+	}
+	want := map[int]string{
+		2: "start_of_verse is never ended",
+		3: "unclosed chord at column 6",
+		4: "empty chord at column 6",
+		5: "end_of_chorus without start_of_chorus",
+		6: `unknown directive "colour"`,
+		7: "unterminated directive",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestParseAcceptsCustomAndLayoutDirectives(t *testing.T) {
+	_, err := Parse("{x_band_note: fade out}\n{new_page}\n{textsize: 12}\n")
+	if err != nil {
+		t.Errorf("Parse returned error: %v", err)
+	}
+}
+
+func TestText(t *testing.T) {
+	sheet, err := Parse(testSheet)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	want := "Let It Be\n" +
+		"Beatles cover\n" +
+		"The Beatles · Key C · 72 BPM\n" +
+		"\n" +
+		"Verse 1:\n" +
+		"       C              G\n" +
+		"When I find myself in times of trouble\n" +
+		"Am          F\n" +
+		"Mother Mary comes to me\n" +
+		"\n" +
+		"Chorus:\n" +
+		"       Am         G\n" +
+		"Let it be, let it be\n" +
+		"\n" +
+		"(Guitar solo)\n" +
+		"\n" +
+		"Tab:\n" +
+		"e|--0--1--|\n" +
+		"\n" +
+		"Chorus\n"
+	if got := sheet.Text(); got != want {
+		t.Errorf("unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTextSpacesOutChordsThatWouldTouch(t *testing.T) {
+	chords, lyrics := alignChords([]Segment{{Chord: "Cmaj7", Lyrics: "a"}, {Chord: "G", Lyrics: "b"}})
+	if chords != "Cmaj7 G" || lyrics != "a     b" {
+		t.Errorf("alignChords = %q / %q", chords, lyrics)
+	}
+}
+
+func TestHTMLEscapes(t *testing.T) {
+	sheet, err := Parse("{title: Rock & <Roll>}\n[A<]x & y\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	out := sheet.HTML()
+	for _, want := range []string{
+		`<h1 class="title">Rock &amp; &lt;Roll&gt;</h1>`,
+		`<span class="chord">A&lt;</span><span class="lyrics">x &amp; y</span>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	src := "{title: Song}\n" +
+		"{key: G}\n" +
+		"# [G] stays\n" +
+		"[G]Hello [D/F#]there [Em7]now [N.C.]\n" +
+		"{start_of_tab}\n" +
+		"[G] e|--3--|\n" +
+		"{end_of_tab}\n" +
+		"[Cadd9]end"
+
+	want := "{title: Song}\n" +
+		"{key: Bb}\n" +
+		"# [G] stays\n" +
+		"[Bb]Hello [F/A]there [Gm7]now [N.C.]\n" +
+		"{start_of_tab}\n" +
+		"[G] e|--3--|\n" +
+		"{end_of_tab}\n" +
+		"[Ebadd9]end"
+	if got := Transpose(src, 3, true); got != want {
+		t.Errorf("unexpected transposition:\n%s\nwant:\n%s", got, want)
+	}
+}
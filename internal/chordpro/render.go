@@ -0,0 +1,179 @@
+package chordpro
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// metaOrder is the order song details are printed in under the title
+var metaOrder = []string{"artist", "composer", "lyricist", "album", "year", "key", "capo", "tempo", "time", "duration"}
+
+// details returns the song details printed under the title
+func (s *Sheet) details() []string {
+	var details []string
+	for _, name := range metaOrder {
+		values := s.Meta[name]
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		switch name {
+		case "artist", "album", "year":
+		case "tempo":
+			value += " BPM"
+		default:
+			value = strings.ToUpper(name[:1]) + name[1:] + " " + value
+		}
+		details = append(details, value)
+	}
+	return details
+}
+
+// heading returns the heading of a section, its label or else its kind
+func (s Section) heading() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	if s.Kind == "" {
+		return ""
+	}
+	return strings.ToUpper(s.Kind[:1]) + s.Kind[1:]
+}
+
+// chorusHeading returns the text shown where the chorus is repeated
+func chorusHeading(line Line) string {
+	if line.Text != "" {
+		return line.Text
+	}
+	return "Chorus"
+}
+
+// Text renders the sheet as plain text, with each chord written above the
+// lyrics it is played on
+func (s *Sheet) Text() string {
+	var sb strings.Builder
+	if s.Title != "" {
+		sb.WriteString(s.Title + "\n")
+	}
+	for _, subtitle := range s.Subtitles {
+		sb.WriteString(subtitle + "\n")
+	}
+	if details := s.details(); len(details) > 0 {
+		sb.WriteString(strings.Join(details, " · ") + "\n")
+	}
+
+	for _, section := range s.Sections {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		if heading := section.heading(); heading != "" {
+			sb.WriteString(heading + ":\n")
+		}
+		for _, line := range section.Lines {
+			switch line.Kind {
+			case LineLyrics:
+				chords, lyrics := alignChords(line.Segments)
+				if chords != "" {
+					sb.WriteString(chords + "\n")
+				}
+				sb.WriteString(lyrics + "\n")
+			case LineComment:
+				sb.WriteString("(" + line.Text + ")\n")
+			case LineChorus:
+				sb.WriteString(chorusHeading(line) + "\n")
+			case LineVerbatim:
+				sb.WriteString(line.Text + "\n")
+			case LineEmpty:
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// alignChords lays out a lyrics line as a line of chords above a line of
+// lyrics. Lyrics are spaced out where chords would otherwise run together.
+func alignChords(segments []Segment) (string, string) {
+	chords, lyrics := "", ""
+	for _, segment := range segments {
+		if segment.Chord != "" {
+			if n := utf8.RuneCountInString(chords); n > utf8.RuneCountInString(lyrics) {
+				lyrics = pad(lyrics, n)
+			}
+			chords = pad(chords, utf8.RuneCountInString(lyrics)) + segment.Chord + " "
+		}
+		lyrics += segment.Lyrics
+	}
+	return strings.TrimRight(chords, " "), strings.TrimRight(lyrics, " ")
+}
+
+// pad adds spaces to s up to width runes
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// HTML renders the sheet as an HTML fragment. Chords and lyrics are written
+// as pairs of spans so a stylesheet can stack each chord over its lyrics.
+func (s *Sheet) HTML() string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="chordpro">` + "\n")
+	if s.Title != "" {
+		sb.WriteString(`<h1 class="title">` + html.EscapeString(s.Title) + "</h1>\n")
+	}
+	for _, subtitle := range s.Subtitles {
+		sb.WriteString(`<h2 class="subtitle">` + html.EscapeString(subtitle) + "</h2>\n")
+	}
+	if details := s.details(); len(details) > 0 {
+		sb.WriteString(`<p class="details">` + html.EscapeString(strings.Join(details, " · ")) + "</p>\n")
+	}
+
+	for _, section := range s.Sections {
+		kind := section.Kind
+		if kind == "" {
+			kind = "lyrics"
+		}
+		sb.WriteString(`<section class="` + html.EscapeString(kind) + `">` + "\n")
+		if heading := section.heading(); heading != "" {
+			sb.WriteString(`<h3 class="label">` + html.EscapeString(heading) + "</h3>\n")
+		}
+		if verbatimSections[section.Kind] {
+			sb.WriteString("<pre>")
+		}
+		for _, line := range section.Lines {
+			switch line.Kind {
+			case LineLyrics:
+				sb.WriteString(`<div class="line">`)
+				for _, segment := range line.Segments {
+					sb.WriteString(`<span class="segment">`)
+					if segment.Chord != "" {
+						sb.WriteString(`<span class="chord">` + html.EscapeString(segment.Chord) + "</span>")
+					}
+					sb.WriteString(`<span class="lyrics">` + html.EscapeString(segment.Lyrics) + "</span></span>")
+				}
+				sb.WriteString("</div>\n")
+			case LineComment:
+				sb.WriteString(`<p class="comment">` + html.EscapeString(line.Text) + "</p>\n")
+			case LineChorus:
+				sb.WriteString(`<p class="chorus-repeat">` + html.EscapeString(chorusHeading(line)) + "</p>\n")
+			case LineVerbatim:
+				sb.WriteString(html.EscapeString(line.Text) + "\n")
+			case LineEmpty:
+				if verbatimSections[section.Kind] {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString(`<div class="empty"></div>` + "\n")
+				}
+			}
+		}
+		if verbatimSections[section.Kind] {
+			sb.WriteString("</pre>\n")
+		}
+		sb.WriteString("</section>\n")
+	}
+	sb.WriteString("</div>\n")
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package chordpro
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nahue/playlists/internal/music"
+)
+
+// chordPattern matches a chord in a lyrics line
+var chordPattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// Transpose moves every chord of a ChordPro document and its key directive
+// the given number of semitones, spelling accidentals with flats or sharps.
+// Tabs, grids and chords that cannot be read, such as "N.C.", are kept as
+// written.
+func Transpose(src string, semitones int, flats bool) string {
+	lines := strings.Split(src, "\n")
+	verbatim := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(strings.TrimRight(trimmed, "\r"), "}") {
+			body := strings.TrimSuffix(strings.TrimRight(trimmed, "\r"), "}")[1:]
+			name, value := splitDirective(body)
+			switch {
+			case strings.HasPrefix(name, "start_of_"):
+				verbatim = verbatimSections[strings.TrimPrefix(name, "start_of_")]
+			case strings.HasPrefix(name, "end_of_"):
+				verbatim = false
+			case name == "key":
+				key, err := music.ParseKey(value)
+				if err == nil {
+					lines[i] = "{key: " + key.Transpose(semitones).String() + "}"
+				}
+			}
+			continue
+		}
+		if verbatim || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		lines[i] = chordPattern.ReplaceAllStringFunc(line, func(match string) string {
+			chord, err := music.TransposeChord(strings.TrimSpace(match[1:len(match)-1]), semitones, flats)
+			if err != nil {
+				return match
+			}
+			return "[" + chord + "]"
+		})
+	}
+	return strings.Join(lines, "\n")
+}
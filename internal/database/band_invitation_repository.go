@@ -0,0 +1,339 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// BandInvitation represents an invitation for an email address to join a band
+type BandInvitation struct {
+	ID           int        `db:"id" json:"id"`
+	BandID       int        `db:"band_id" json:"band_id"`
+	BandName     string     `db:"band_name" json:"band_name"`
+	Email        string     `db:"email" json:"email"`
+	Role         BandRole   `db:"role" json:"role"`
+	BandMemberID *int       `db:"band_member_id" json:"band_member_id,omitempty"`
+	TokenID      string     `db:"token_id" json:"-"`
+	Status       string     `db:"status" json:"status"`
+	InvitedBy    *int       `db:"invited_by" json:"invited_by,omitempty"`
+	RespondedBy  *int       `db:"responded_by" json:"responded_by,omitempty"`
+	ExpiresAt    time.Time  `db:"expires_at" json:"expires_at"`
+	RespondedAt  *time.Time `db:"responded_at" json:"responded_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// CreateInvitationRequest represents the request to invite someone to a band
+type CreateInvitationRequest struct {
+	Email        string   `json:"email"`
+	Role         BandRole `json:"role"`
+	BandMemberID *int     `json:"band_member_id,omitempty"`
+}
+
+// invitationColumns selects an invitation joined with its band name. Pending
+// invitations past their expiry are reported with the "expired" status.
+const invitationColumns = `
+	i.id, i.band_id, b.name AS band_name, i.email, i.role, i.band_member_id, i.token_id,
+	CASE WHEN i.status = 'pending' AND i.expires_at < CURRENT_TIMESTAMP THEN 'expired' ELSE i.status END AS status,
+	i.invited_by, i.responded_by, i.expires_at, i.responded_at, i.created_at, i.updated_at
+`
+
+// BandInvitationRepository handles database operations for band invitations
+type BandInvitationRepository struct {
+	db *sqlx.DB
+}
+
+// NewBandInvitationRepository creates a new band invitation repository
+func NewBandInvitationRepository(db *sqlx.DB) *BandInvitationRepository {
+	return &BandInvitationRepository{db: db}
+}
+
+// CreateInvitation creates a pending invitation identified by tokenID,
+// replacing any pending invitation for the same email address. Inviting
+// admins requires the owner role.
+func (r *BandInvitationRepository) CreateInvitation(bandID, userID int, req CreateInvitationRequest, tokenID string, expiresAt time.Time) (*BandInvitation, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	required := BandRoleAdmin
+	if req.Role.Allows(BandRoleAdmin) {
+		required = BandRoleOwner
+	}
+
+	ok, err := authorizeBand(tx, bandID, userID, required)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if req.BandMemberID != nil {
+		var memberIDCheck int
+		err = tx.Get(&memberIDCheck, `SELECT id FROM band_members WHERE id = $1 AND band_id = $2`, *req.BandMemberID, bandID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrBandMemberNotFound
+			}
+			return nil, fmt.Errorf("failed to verify band member: %w", err)
+		}
+	}
+
+	// A new invitation supersedes any pending one for the same address
+	revokeQuery := `
+		UPDATE band_invitations
+		SET status = 'revoked', updated_at = CURRENT_TIMESTAMP
+		WHERE band_id = $1 AND LOWER(email) = LOWER($2) AND status = 'pending'
+	`
+	_, err = tx.Exec(revokeQuery, bandID, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke previous invitations: %w", err)
+	}
+
+	var invitationID int
+	insertQuery := `
+		INSERT INTO band_invitations (band_id, email, role, band_member_id, token_id, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	err = tx.Get(&invitationID, insertQuery, bandID, strings.TrimSpace(req.Email), req.Role, req.BandMemberID, tokenID, userID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	invitation, err := getInvitation(tx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetInvitations returns all invitations of a band (requires admin)
+func (r *BandInvitationRepository) GetInvitations(bandID, userID int) ([]BandInvitation, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM band_invitations i
+		JOIN bands b ON b.id = i.band_id
+		WHERE i.band_id = $1
+		ORDER BY i.created_at DESC, i.id DESC
+	`
+
+	invitations := []BandInvitation{}
+	err = r.db.Select(&invitations, query, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation revokes a pending invitation (requires admin). It reports
+// whether a pending invitation was revoked.
+func (r *BandInvitationRepository) RevokeInvitation(invitationID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	query := `
+		UPDATE band_invitations
+		SET status = 'revoked', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND band_id = $2 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(query, invitationID, bandID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetInvitationByToken returns the invitation a signed token refers to, or
+// nil if the token ID does not match (for example after a resend)
+func (r *BandInvitationRepository) GetInvitationByToken(invitationID int, tokenID string) (*BandInvitation, error) {
+	invitation, err := getInvitation(r.db, invitationID)
+	if err != nil || invitation == nil {
+		return nil, err
+	}
+
+	if invitation.TokenID != tokenID {
+		return nil, nil
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation links the user to the band with the invited role and, if
+// the invitation names a band member, connects that member to the account.
+// The user's email must match the invited address.
+func (r *BandInvitationRepository) AcceptInvitation(invitationID int, tokenID string, userID int) (*BandUser, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invitation, err := lockPendingInvitation(tx, invitationID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	var email string
+	err = tx.Get(&email, `SELECT email FROM users WHERE id = $1`, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !strings.EqualFold(email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	// Users that already belong to the band keep their current role
+	_, err = tx.Exec(`
+		INSERT INTO band_users (band_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (band_id, user_id) DO NOTHING
+	`, invitation.BandID, userID, invitation.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add band user: %w", err)
+	}
+
+	if invitation.BandMemberID != nil {
+		_, err = tx.Exec(`
+			UPDATE band_members
+			SET user_id = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2 AND band_id = $3 AND user_id IS NULL
+		`, userID, *invitation.BandMemberID, invitation.BandID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link band member: %w", err)
+		}
+	}
+
+	err = respondToInvitation(tx, invitationID, InvitationAccepted, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bandUser, err := getBandUser(tx, invitation.BandID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return bandUser, nil
+}
+
+// DeclineInvitation marks a pending invitation as declined
+func (r *BandInvitationRepository) DeclineInvitation(invitationID int, tokenID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPendingInvitation(tx, invitationID, tokenID); err != nil {
+		return err
+	}
+
+	if err := respondToInvitation(tx, invitationID, InvitationDeclined, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getInvitation returns an invitation by ID
+func getInvitation(q sqlx.Queryer, invitationID int) (*BandInvitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM band_invitations i
+		JOIN bands b ON b.id = i.band_id
+		WHERE i.id = $1
+	`
+
+	var invitation BandInvitation
+	err := sqlx.Get(q, &invitation, query, invitationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// lockPendingInvitation locks the invitation row and returns ErrInvitationUnavailable
+// unless it matches the token and is still pending and unexpired
+func lockPendingInvitation(tx *sqlx.Tx, invitationID int, tokenID string) (*BandInvitation, error) {
+	var lockedID int
+	err := tx.Get(&lockedID, `SELECT id FROM band_invitations WHERE id = $1 AND token_id = $2 FOR UPDATE`, invitationID, tokenID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvitationUnavailable
+		}
+		return nil, fmt.Errorf("failed to lock invitation: %w", err)
+	}
+
+	invitation, err := getInvitation(tx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation == nil || invitation.Status != InvitationPending {
+		return nil, ErrInvitationUnavailable
+	}
+
+	return invitation, nil
+}
+
+// respondToInvitation records the final status of an invitation
+func respondToInvitation(tx *sqlx.Tx, invitationID int, status string, userID *int) error {
+	query := `
+		UPDATE band_invitations
+		SET status = $1, responded_by = $2, responded_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+
+	_, err := tx.Exec(query, status, userID, invitationID)
+	if err != nil {
+		return fmt.Errorf("failed to update invitation: %w", err)
+	}
+
+	return nil
+}
@@ -3,68 +3,233 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nahue/playlists/internal/setlist"
 )
 
-// BandPlaylist represents a playlist for a specific band
+// BandPlaylist represents a playlist for a specific band. ChangeoverDuration
+// is the gap between songs and SetTargetDuration the length each set should
+// fit in unless its section sets another, both in seconds. Templates are
+// playlists new playlists can start from.
 type BandPlaylist struct {
-	ID          int       `db:"id" json:"id"`
-	BandID      int       `db:"band_id" json:"band_id"`
-	Name        string    `db:"name" json:"name"`
-	Description string    `db:"description" json:"description"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID                 int       `db:"id" json:"id"`
+	BandID             int       `db:"band_id" json:"band_id"`
+	Name               string    `db:"name" json:"name"`
+	Description        string    `db:"description" json:"description"`
+	IsTemplate         bool      `db:"is_template" json:"is_template"`
+	ChangeoverDuration int       `db:"changeover_duration" json:"changeover_duration"`
+	SetTargetDuration  *int      `db:"set_target_duration" json:"set_target_duration"`
+	CreatedAt          time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
 }
 
-// BandPlaylistSong represents a song in a band playlist
+// BandPlaylistSection is a named part of a playlist, such as a set or the
+// encore. Songs without a section form a set before the first section.
+type BandPlaylistSection struct {
+	ID             int       `db:"id" json:"id"`
+	PlaylistID     int       `db:"playlist_id" json:"playlist_id"`
+	Name           string    `db:"name" json:"name"`
+	Position       int       `db:"position" json:"position"`
+	TargetDuration *int      `db:"target_duration" json:"target_duration"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// BandPlaylistSong represents a song in a band playlist. It references a
+// catalog song; Notes and the metadata hold the effective values, which are
+// the playlist's overrides where set and the catalog's otherwise.
 type BandPlaylistSong struct {
-	ID         int       `db:"id" json:"id"`
-	PlaylistID int       `db:"playlist_id" json:"playlist_id"`
-	Artist     string    `db:"artist" json:"artist"`
-	Song       string    `db:"song" json:"song"`
-	Notes      string    `db:"notes" json:"notes"`
-	Position   int       `db:"position" json:"position"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+	ID         int    `db:"id" json:"id"`
+	PlaylistID int    `db:"playlist_id" json:"playlist_id"`
+	BandSongID int    `db:"band_song_id" json:"band_song_id"`
+	SectionID  *int   `db:"section_id" json:"section_id"`
+	Artist     string `db:"artist" json:"artist"`
+	Song       string `db:"song" json:"song"`
+	Notes      string `db:"notes" json:"notes"`
+	SongMetadata
+	Overrides SongOverrides `db:"overrides" json:"overrides"`
+	Position  int           `db:"position" json:"position"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+// SongOverrides holds the details a playlist sets for a song instead of the
+// catalog values. Nil fields follow the catalog.
+type SongOverrides struct {
+	Notes         *string `db:"notes" json:"notes"`
+	SongKey       *string `db:"song_key" json:"song_key"`
+	Tempo         *int    `db:"tempo" json:"tempo"`
+	TimeSignature *string `db:"time_signature" json:"time_signature"`
+	Duration      *int    `db:"duration" json:"duration"`
+	Capo          *int    `db:"capo" json:"capo"`
+	Tuning        *string `db:"tuning" json:"tuning"`
 }
 
-// BandPlaylistWithSongs represents a playlist with its songs
+// PlaylistSongChart is the chord sheet of a playlist song's catalog song,
+// with the playlist song whose key it is played in
+type PlaylistSongChart struct {
+	Song  BandPlaylistSong
+	Chart BandSongChart
+}
+
+// BandPlaylistWithSongs represents a playlist with its songs, sections and
+// how long its sets run
 type BandPlaylistWithSongs struct {
 	BandPlaylist
-	Songs     []BandPlaylistSong `json:"songs"`
-	SongCount int                `json:"song_count"`
+	Songs     []BandPlaylistSong    `json:"songs"`
+	SongCount int                   `json:"song_count"`
+	Sections  []BandPlaylistSection `json:"sections"`
+	Timing    PlaylistTiming        `json:"timing"`
+}
+
+// PlaylistSetTiming is the computed length of one set of a playlist. The
+// set of the songs without a section has no section ID.
+type PlaylistSetTiming struct {
+	SectionID *int   `json:"section_id"`
+	Name      string `json:"name"`
+	setlist.SetTiming
 }
 
-// CreatePlaylistRequest represents the request to create a new playlist
+// PlaylistTiming is the computed length of each set of a playlist and of the whole playlist
+type PlaylistTiming struct {
+	Sets  []PlaylistSetTiming `json:"sets"`
+	Total setlist.SetTiming   `json:"total"`
+}
+
+// CreatePlaylistRequest represents the request to create a new playlist.
+// With TemplateID the playlist starts with the sections and songs of that
+// template, and takes its changeover and set target durations unless the
+// request sets them.
 type CreatePlaylistRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	ChangeoverDuration int    `json:"changeover_duration"`
+	SetTargetDuration  *int   `json:"set_target_duration"`
+	IsTemplate         bool   `json:"is_template"`
+	TemplateID         *int   `json:"template_id"`
 }
 
 // UpdatePlaylistRequest represents the request to update a playlist
 type UpdatePlaylistRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	ChangeoverDuration int    `json:"changeover_duration"`
+	SetTargetDuration  *int   `json:"set_target_duration"`
+	IsTemplate         bool   `json:"is_template"`
+}
+
+// DuplicatePlaylistRequest represents the request to copy a playlist with
+// its sections and songs. Without a name the copy is named after the
+// original.
+type DuplicatePlaylistRequest struct {
+	Name       string `json:"name"`
+	IsTemplate bool   `json:"is_template"`
+}
+
+// CreateSectionRequest represents the request to add a section to a playlist
+type CreateSectionRequest struct {
+	Name           string `json:"name"`
+	Position       int    `json:"position"`
+	TargetDuration *int   `json:"target_duration"`
+}
+
+// UpdateSectionRequest represents the request to update a playlist section
+type UpdateSectionRequest struct {
+	Name           string `json:"name"`
+	Position       int    `json:"position"`
+	TargetDuration *int   `json:"target_duration"`
 }
 
-// AddSongRequest represents the request to add a new song
+// AddSongRequest represents the request to add a song to a playlist. The
+// song is the catalog song BandSongID, or else the catalog song matching
+// Artist and Song, which is created when the band does not have it yet.
+// Notes and the metadata override the catalog values for this playlist;
+// a song created by the request takes them as its catalog values instead.
+// Position is the 0-based index to insert the song at; without it the song
+// is appended.
 type AddSongRequest struct {
-	Artist   string `json:"artist"`
-	Song     string `json:"song"`
-	Notes    string `json:"notes"`
-	Position int    `json:"position"`
+	BandSongID *int   `json:"band_song_id"`
+	Artist     string `json:"artist"`
+	Song       string `json:"song"`
+	Notes      string `json:"notes"`
+	SongMetadata
+	SectionID *int `json:"section_id"`
+	Position  *int `json:"position"`
 }
 
-// UpdateSongRequest represents the request to update a song
+// UpdateSongRequest represents the request to update a song in a playlist.
+// The catalog song is chosen like in AddSongRequest. Empty overrides, or
+// overrides equal to the catalog values, follow the catalog. The song is
+// moved to the 0-based Position.
 type UpdateSongRequest struct {
-	Artist   string `json:"artist"`
-	Song     string `json:"song"`
-	Notes    string `json:"notes"`
-	Position int    `json:"position"`
+	BandSongID *int   `json:"band_song_id"`
+	Artist     string `json:"artist"`
+	Song       string `json:"song"`
+	Notes      string `json:"notes"`
+	SongMetadata
+	SectionID *int `json:"section_id"`
+	Position  int  `json:"position"`
 }
 
+// ReorderSongsRequest represents the request to reorder the songs of a
+// playlist. SongIDs lists every song of the playlist in the new order.
+type ReorderSongsRequest struct {
+	SongIDs []int `json:"song_ids"`
+}
+
+// MoveSongRequest represents the request to move or copy a song to a
+// playlist of the same band. Position is the 0-based index to put the song
+// at; without it the song is appended. The song joins the section of the
+// song it is put in front of, or of the last song when appended.
+type MoveSongRequest struct {
+	PlaylistID int  `json:"playlist_id"`
+	Position   *int `json:"position"`
+	Copy       bool `json:"copy"`
+}
+
+// ImportSongsRequest represents songs imported from a file. Sections are
+// matched by name with the sections of the playlist and created when it has
+// none by that name; songs name their section in Section, which need not
+// be listed in Sections.
+type ImportSongsRequest struct {
+	Sections []CreateSectionRequest
+	Songs    []ImportSongRequest
+}
+
+// ImportSongRequest represents a song imported from a file
+type ImportSongRequest struct {
+	AddSongRequest
+	Section string
+}
+
+// playlistSongColumns selects a playlist song joined with its catalog song
+// as s and bs
+const playlistSongColumns = `
+	s.id, s.playlist_id, s.band_song_id, s.section_id, bs.artist, bs.title AS song,
+	COALESCE(s.notes, bs.notes) AS notes,
+	COALESCE(s.song_key, bs.song_key) AS song_key,
+	COALESCE(s.tempo, bs.tempo) AS tempo,
+	COALESCE(s.time_signature, bs.time_signature) AS time_signature,
+	COALESCE(s.duration, bs.duration) AS duration,
+	COALESCE(s.capo, bs.capo) AS capo,
+	COALESCE(s.tuning, bs.tuning) AS tuning,
+	s.notes AS "overrides.notes",
+	s.song_key AS "overrides.song_key",
+	s.tempo AS "overrides.tempo",
+	s.time_signature AS "overrides.time_signature",
+	s.duration AS "overrides.duration",
+	s.capo AS "overrides.capo",
+	s.tuning AS "overrides.tuning",
+	s.position, s.created_at, s.updated_at`
+
+const playlistColumns = `id, band_id, name, description, is_template, changeover_duration, set_target_duration, created_at, updated_at`
+
+const sectionColumns = `id, playlist_id, name, position, target_duration, created_at, updated_at`
+
 // BandPlaylistRepository handles database operations for band playlists
 type BandPlaylistRepository struct {
 	db *sqlx.DB
@@ -77,19 +242,13 @@ func NewBandPlaylistRepository(db *sqlx.DB) *BandPlaylistRepository {
 
 // GetPlaylistsByBandID returns all playlists for a specific band
 func (r *BandPlaylistRepository) GetPlaylistsByBandID(bandID, userID int) ([]BandPlaylistWithSongs, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	query := `
-		SELECT id, band_id, name, description, created_at, updated_at
+		SELECT ` + playlistColumns + `
 		FROM band_playlists
 		WHERE band_id = $1
 		ORDER BY created_at DESC
@@ -104,37 +263,57 @@ func (r *BandPlaylistRepository) GetPlaylistsByBandID(bandID, userID int) ([]Ban
 	// Get songs for each playlist
 	var playlistsWithSongs []BandPlaylistWithSongs
 	for _, playlist := range playlists {
-		songs, err := r.GetPlaylistSongs(playlist.ID, bandID, userID)
+		playlistWithSongs, err := r.withSongs(playlist)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get songs for playlist %d: %w", playlist.ID, err)
+			return nil, err
 		}
-
-		playlistWithSongs := BandPlaylistWithSongs{
-			BandPlaylist: playlist,
-			Songs:        songs,
-			SongCount:    len(songs),
-		}
-		playlistsWithSongs = append(playlistsWithSongs, playlistWithSongs)
+		playlistsWithSongs = append(playlistsWithSongs, *playlistWithSongs)
 	}
 
 	return playlistsWithSongs, nil
 }
 
-// GetPlaylistByID returns a specific playlist by ID (only if owned by the user)
-func (r *BandPlaylistRepository) GetPlaylistByID(playlistID, bandID, userID int) (*BandPlaylistWithSongs, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
+// GetTemplates returns the template playlists of a band
+func (r *BandPlaylistRepository) GetTemplates(bandID, userID int) ([]BandPlaylistWithSongs, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + playlistColumns + `
+		FROM band_playlists
+		WHERE band_id = $1 AND is_template
+		ORDER BY name ASC
+	`
+
+	var playlists []BandPlaylist
+	err = r.db.Select(&playlists, query, bandID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+
+	templates := []BandPlaylistWithSongs{}
+	for _, playlist := range playlists {
+		template, err := r.withSongs(playlist)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+		templates = append(templates, *template)
+	}
+
+	return templates, nil
+}
+
+// GetPlaylistByID returns a specific playlist by ID (only if the user has access to the band)
+func (r *BandPlaylistRepository) GetPlaylistByID(playlistID, bandID, userID int) (*BandPlaylistWithSongs, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	query := `
-		SELECT id, band_id, name, description, created_at, updated_at
+		SELECT ` + playlistColumns + `
 		FROM band_playlists
 		WHERE id = $1 AND band_id = $2
 	`
@@ -148,77 +327,273 @@ func (r *BandPlaylistRepository) GetPlaylistByID(playlistID, bandID, userID int)
 		return nil, fmt.Errorf("failed to get playlist: %w", err)
 	}
 
-	// Get songs for this playlist
-	songs, err := r.GetPlaylistSongs(playlist.ID, bandID, userID)
+	return r.withSongs(playlist)
+}
+
+// withSongs loads the songs and sections of a playlist and computes its timing
+func (r *BandPlaylistRepository) withSongs(playlist BandPlaylist) (*BandPlaylistWithSongs, error) {
+	songs, err := r.getPlaylistSongs(playlist.ID, playlist.BandID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get songs for playlist %d: %w", playlist.ID, err)
 	}
 
+	sections, err := getPlaylistSections(r.db, playlist.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sections for playlist %d: %w", playlist.ID, err)
+	}
+
 	playlistWithSongs := &BandPlaylistWithSongs{
 		BandPlaylist: playlist,
 		Songs:        songs,
 		SongCount:    len(songs),
+		Sections:     sections,
+		Timing:       playlistTiming(playlist, sections, songs),
 	}
 
 	return playlistWithSongs, nil
 }
 
-// CreatePlaylist creates a new playlist for a band
+// playlistTiming splits the songs into sets by section and computes how long
+// each set runs. Songs without a section form the first set, which is left
+// out when it is empty and the playlist has sections.
+func playlistTiming(playlist BandPlaylist, sections []BandPlaylistSection, songs []BandPlaylistSong) PlaylistTiming {
+	durations := make(map[int][]*int)
+	var unsectioned []*int
+	for _, song := range songs {
+		if song.SectionID == nil {
+			unsectioned = append(unsectioned, song.Duration)
+			continue
+		}
+		durations[*song.SectionID] = append(durations[*song.SectionID], song.Duration)
+	}
+
+	var sets []setlist.Set
+	var named []PlaylistSetTiming
+	if len(unsectioned) > 0 || len(sections) == 0 {
+		sets = append(sets, setlist.Set{Durations: unsectioned, Target: playlist.SetTargetDuration})
+		named = append(named, PlaylistSetTiming{})
+	}
+	for _, section := range sections {
+		target := section.TargetDuration
+		if target == nil {
+			target = playlist.SetTargetDuration
+		}
+		sets = append(sets, setlist.Set{Durations: durations[section.ID], Target: target})
+		id := section.ID
+		named = append(named, PlaylistSetTiming{SectionID: &id, Name: section.Name})
+	}
+
+	timing := setlist.Compute(sets, playlist.ChangeoverDuration)
+	for i := range named {
+		named[i].SetTiming = timing.Sets[i]
+	}
+
+	return PlaylistTiming{Sets: named, Total: timing.Total}
+}
+
+// CreatePlaylist creates a new playlist for a band, from a template when
+// the request names one (ErrTemplateNotFound unless it is a template of the band)
 func (r *BandPlaylistRepository) CreatePlaylist(bandID, userID int, req CreatePlaylistRequest) (*BandPlaylistWithSongs, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if req.TemplateID == nil {
+		query := `
+			INSERT INTO band_playlists (band_id, name, description, is_template, changeover_duration, set_target_duration)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING ` + playlistColumns
+
+		var playlist BandPlaylist
+		err = r.db.Get(&playlist, query, bandID, req.Name, req.Description, req.IsTemplate, req.ChangeoverDuration, req.SetTargetDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create playlist: %w", err)
+		}
+
+		playlistWithSongs := &BandPlaylistWithSongs{
+			BandPlaylist: playlist,
+			Songs:        []BandPlaylistSong{},
+			SongCount:    0,
+			Sections:     []BandPlaylistSection{},
+			Timing:       playlistTiming(playlist, nil, nil),
 		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+
+		return playlistWithSongs, nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	template, err := getPlaylist(tx, *req.TemplateID, bandID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil || !template.IsTemplate {
+		return nil, ErrTemplateNotFound
+	}
+
+	playlist := BandPlaylist{
+		Name:               req.Name,
+		Description:        req.Description,
+		IsTemplate:         req.IsTemplate,
+		ChangeoverDuration: req.ChangeoverDuration,
+		SetTargetDuration:  req.SetTargetDuration,
+	}
+	if playlist.ChangeoverDuration == 0 {
+		playlist.ChangeoverDuration = template.ChangeoverDuration
+	}
+	if playlist.SetTargetDuration == nil {
+		playlist.SetTargetDuration = template.SetTargetDuration
+	}
+
+	created, err := copyPlaylist(tx, template.ID, bandID, playlist)
+	if err != nil {
+		return nil, err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.withSongs(*created)
+}
+
+// DuplicatePlaylist copies a playlist with its sections and songs, including
+// the songs' overrides
+func (r *BandPlaylistRepository) DuplicatePlaylist(playlistID, bandID, userID int, req DuplicatePlaylistRequest) (*BandPlaylistWithSongs, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	source, err := getPlaylist(tx, playlistID, bandID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, nil // Playlist not found
+	}
+
+	playlist := *source
+	playlist.IsTemplate = req.IsTemplate
+	playlist.Name = req.Name
+	if playlist.Name == "" {
+		playlist.Name = source.Name + " (copy)"
+	}
+
+	created, err := copyPlaylist(tx, source.ID, bandID, playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.withSongs(*created)
+}
+
+// getPlaylist returns a playlist of the band, locking it against changes
+// while it is copied
+func getPlaylist(tx *sqlx.Tx, playlistID, bandID int) (*BandPlaylist, error) {
 	query := `
-		INSERT INTO band_playlists (band_id, name, description)
-		VALUES ($1, $2, $3)
-		RETURNING id, band_id, name, description, created_at, updated_at
+		SELECT ` + playlistColumns + `
+		FROM band_playlists
+		WHERE id = $1 AND band_id = $2
+		FOR SHARE
 	`
 
 	var playlist BandPlaylist
-	err = r.db.Get(&playlist, query, bandID, req.Name, req.Description)
+	err := tx.Get(&playlist, query, playlistID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Playlist not found
+		}
+		return nil, fmt.Errorf("failed to get playlist: %w", err)
+	}
+
+	return &playlist, nil
+}
+
+// copyPlaylist creates a playlist in the band with the settings of playlist
+// and copies the sections and songs of the source playlist into it
+func copyPlaylist(tx *sqlx.Tx, sourceID, bandID int, playlist BandPlaylist) (*BandPlaylist, error) {
+	query := `
+		INSERT INTO band_playlists (band_id, name, description, is_template, changeover_duration, set_target_duration)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + playlistColumns
+
+	var created BandPlaylist
+	err := tx.Get(&created, query, bandID, playlist.Name, playlist.Description, playlist.IsTemplate, playlist.ChangeoverDuration, playlist.SetTargetDuration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create playlist: %w", err)
 	}
 
-	playlistWithSongs := &BandPlaylistWithSongs{
-		BandPlaylist: playlist,
-		Songs:        []BandPlaylistSong{},
-		SongCount:    0,
+	sections, err := getPlaylistSections(tx, sourceID)
+	if err != nil {
+		return nil, err
 	}
 
-	return playlistWithSongs, nil
+	// Map the source sections to their copies so songs stay in their sets
+	oldIDs := make(pq.Int64Array, len(sections))
+	newIDs := make(pq.Int64Array, len(sections))
+	for i, section := range sections {
+		var id int
+		err = tx.Get(&id, `
+			INSERT INTO band_playlist_sections (playlist_id, name, position, target_duration)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, created.ID, section.Name, section.Position, section.TargetDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy section: %w", err)
+		}
+		oldIDs[i] = int64(section.ID)
+		newIDs[i] = int64(id)
+	}
+
+	query = `
+		INSERT INTO band_playlist_songs (playlist_id, band_song_id, section_id, notes, song_key, tempo, time_signature, duration, capo, tuning, position)
+		SELECT $1, s.band_song_id, m.new_id, s.notes, s.song_key, s.tempo, s.time_signature, s.duration, s.capo, s.tuning, s.position
+		FROM band_playlist_songs s
+		LEFT JOIN unnest($3::int[], $4::int[]) AS m(old_id, new_id) ON m.old_id = s.section_id
+		WHERE s.playlist_id = $2
+		ORDER BY s.position ASC, s.created_at ASC, s.id ASC
+	`
+	_, err = tx.Exec(query, created.ID, sourceID, oldIDs, newIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy songs: %w", err)
+	}
+
+	return &created, nil
 }
 
 // UpdatePlaylist updates a specific playlist
 func (r *BandPlaylistRepository) UpdatePlaylist(playlistID, bandID, userID int, req UpdatePlaylistRequest) (*BandPlaylist, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	query := `
 		UPDATE band_playlists
-		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $3 AND band_id = $4
-		RETURNING id, band_id, name, description, created_at, updated_at
-	`
+		SET name = $1, description = $2, changeover_duration = $3, set_target_duration = $4, is_template = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND band_id = $7
+		RETURNING ` + playlistColumns
 
 	var playlist BandPlaylist
-	err = r.db.Get(&playlist, query, req.Name, req.Description, playlistID, bandID)
+	err = r.db.Get(&playlist, query, req.Name, req.Description, req.ChangeoverDuration, req.SetTargetDuration, req.IsTemplate, playlistID, bandID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Playlist not found
@@ -231,15 +606,9 @@ func (r *BandPlaylistRepository) UpdatePlaylist(playlistID, bandID, userID int,
 
 // DeletePlaylist deletes a specific playlist and all its songs
 func (r *BandPlaylistRepository) DeletePlaylist(playlistID, bandID, userID int) error {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil // Band not found
-		}
-		return fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return err
 	}
 
 	// Delete the playlist (songs will be deleted automatically due to CASCADE)
@@ -263,27 +632,28 @@ func (r *BandPlaylistRepository) DeletePlaylist(playlistID, bandID, userID int)
 
 // GetPlaylistSongs returns all songs for a specific playlist
 func (r *BandPlaylistRepository) GetPlaylistSongs(playlistID, bandID, userID int) ([]BandPlaylistSong, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
 	}
 
+	return r.getPlaylistSongs(playlistID, bandID)
+}
+
+// getPlaylistSongs returns the songs of a playlist without checking band access
+func (r *BandPlaylistRepository) getPlaylistSongs(playlistID, bandID int) ([]BandPlaylistSong, error) {
 	query := `
-		SELECT s.id, s.playlist_id, s.artist, s.song, s.notes, s.position, s.created_at, s.updated_at
+		SELECT ` + playlistSongColumns + `
 		FROM band_playlist_songs s
+		JOIN band_songs bs ON bs.id = s.band_song_id
 		JOIN band_playlists p ON s.playlist_id = p.id
+		LEFT JOIN band_playlist_sections sec ON sec.id = s.section_id
 		WHERE s.playlist_id = $1 AND p.band_id = $2
-		ORDER BY s.position ASC, s.created_at ASC
+		ORDER BY sec.position ASC NULLS FIRST, sec.id ASC NULLS FIRST, s.position ASC, s.created_at ASC
 	`
 
-	var songs []BandPlaylistSong
-	err = r.db.Select(&songs, query, playlistID, bandID)
+	songs := []BandPlaylistSong{}
+	err := r.db.Select(&songs, query, playlistID, bandID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get playlist songs: %w", err)
 	}
@@ -291,70 +661,320 @@ func (r *BandPlaylistRepository) GetPlaylistSongs(playlistID, bandID, userID int
 	return songs, nil
 }
 
+// getPlaylistSong returns a single playlist song joined with its catalog song
+func getPlaylistSong(q sqlx.Queryer, songID int) (*BandPlaylistSong, error) {
+	query := `
+		SELECT ` + playlistSongColumns + `
+		FROM band_playlist_songs s
+		JOIN band_songs bs ON bs.id = s.band_song_id
+		WHERE s.id = $1
+	`
+
+	var song BandPlaylistSong
+	err := sqlx.Get(q, &song, query, songID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist song: %w", err)
+	}
+
+	return &song, nil
+}
+
+// resolvePlaylistSong finds the catalog song a playlist song request refers
+// to, creating it when needed, and works out the overrides to store
+func resolvePlaylistSong(q sqlx.Queryer, bandID int, bandSongID *int, seed CreateBandSongRequest) (int, SongOverrides, error) {
+	var song *BandSong
+	var created bool
+	var err error
+	if bandSongID != nil {
+		song, err = getBandSong(q, *bandSongID, bandID)
+		if err != nil {
+			return 0, SongOverrides{}, err
+		}
+		if song == nil {
+			return 0, SongOverrides{}, ErrBandSongNotFound
+		}
+	} else {
+		song, created, err = findOrCreateBandSong(q, bandID, seed)
+		if err != nil {
+			return 0, SongOverrides{}, err
+		}
+	}
+
+	if created {
+		// The request details became the catalog values
+		return song.ID, SongOverrides{}, nil
+	}
+
+	overrides := SongOverrides{
+		Notes:         overrideString(seed.Notes, song.Notes),
+		SongKey:       overrideString(seed.SongKey, song.SongKey),
+		Tempo:         overrideInt(seed.Tempo, song.Tempo),
+		TimeSignature: overrideString(seed.TimeSignature, song.TimeSignature),
+		Duration:      overrideInt(seed.Duration, song.Duration),
+		Capo:          overrideInt(seed.Capo, song.Capo),
+		Tuning:        overrideString(seed.Tuning, song.Tuning),
+	}
+
+	return song.ID, overrides, nil
+}
+
+// overrideString returns the value to store as an override, or nil when it
+// is empty or matches the catalog value
+func overrideString(value, catalog string) *string {
+	if value == "" || value == catalog {
+		return nil
+	}
+	return &value
+}
+
+// overrideInt returns the value to store as an override, or nil when it is
+// unset or matches the catalog value
+func overrideInt(value, catalog *int) *int {
+	if value == nil || (catalog != nil && *value == *catalog) {
+		return nil
+	}
+	return value
+}
+
+// songSeed turns the details of a playlist song request into a catalog song
+func songSeed(artist, song, notes string, metadata SongMetadata) CreateBandSongRequest {
+	return CreateBandSongRequest{
+		Artist:       artist,
+		Title:        song,
+		SongMetadata: metadata,
+		Notes:        notes,
+	}
+}
+
 // AddSong adds a new song to a playlist
 func (r *BandPlaylistRepository) AddSong(playlistID, bandID, userID int, req AddSongRequest) (*BandPlaylistSong, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Verify that the playlist belongs to the band, locking it while positions change
+	found, err := lockPlaylists(tx, bandID, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil // Playlist not found
+	}
+
+	songID, err := insertPlaylistSong(tx, playlistID, bandID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := getPlaylistSong(tx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return song, nil
+}
+
+// ImportSongs adds songs to the end of a playlist, all of them or none. With
+// dryRun the songs are added and rolled back, so the result previews the
+// import, including the catalog songs and sections it would create.
+func (r *BandPlaylistRepository) ImportSongs(playlistID, bandID, userID int, req ImportSongsRequest, dryRun bool) ([]BandPlaylistSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockPlaylists(tx, bandID, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil // Playlist not found
+	}
+
+	sectionIDs, err := importSections(tx, playlistID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := []BandPlaylistSong{}
+	for _, song := range req.Songs {
+		add := song.AddSongRequest
+		if song.Section != "" {
+			id := sectionIDs[strings.ToLower(song.Section)]
+			add.SectionID = &id
 		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+
+		var songID int
+		songID, err = insertPlaylistSong(tx, playlistID, bandID, add)
+		if err != nil {
+			return nil, err
+		}
+
+		var song *BandPlaylistSong
+		song, err = getPlaylistSong(tx, songID)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, *song)
+	}
+
+	if dryRun {
+		return imported, nil
 	}
 
-	// Verify that the playlist belongs to the band
-	playlistQuery := `SELECT id FROM band_playlists WHERE id = $1 AND band_id = $2`
-	var playlistIDCheck int
-	err = r.db.Get(&playlistIDCheck, playlistQuery, playlistID, bandID)
+	err = tx.Commit()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Playlist not found
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return imported, nil
+}
+
+// importSections finds or creates the sections an import lists or its songs
+// name, and returns their IDs by lower-cased name. New sections go after the
+// playlist's sections in the order they are first named.
+func importSections(tx *sqlx.Tx, playlistID int, req ImportSongsRequest) (map[string]int, error) {
+	existing, err := getPlaylistSections(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int)
+	position := 0
+	for _, section := range existing {
+		name := strings.ToLower(section.Name)
+		if _, ok := ids[name]; !ok {
+			ids[name] = section.ID
+		}
+		position = section.Position + 1
+	}
+
+	wanted := append([]CreateSectionRequest{}, req.Sections...)
+	for _, song := range req.Songs {
+		if song.Section != "" {
+			wanted = append(wanted, CreateSectionRequest{Name: song.Section})
+		}
+	}
+
+	for _, section := range wanted {
+		name := strings.ToLower(section.Name)
+		if _, ok := ids[name]; ok {
+			continue
 		}
-		return nil, fmt.Errorf("failed to verify playlist ownership: %w", err)
+
+		var id int
+		err = tx.Get(&id, `
+			INSERT INTO band_playlist_sections (playlist_id, name, position, target_duration)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, playlistID, section.Name, position, section.TargetDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create section: %w", err)
+		}
+		ids[name] = id
+		position++
+	}
+
+	return ids, nil
+}
+
+// insertPlaylistSong adds a song to a playlist locked by the transaction and
+// puts it at the requested position
+func insertPlaylistSong(tx *sqlx.Tx, playlistID, bandID int, req AddSongRequest) (int, error) {
+	err := checkSection(tx, req.SectionID, playlistID)
+	if err != nil {
+		return 0, err
+	}
+
+	bandSongID, overrides, err := resolvePlaylistSong(tx, bandID, req.BandSongID, songSeed(req.Artist, req.Song, req.Notes, req.SongMetadata))
+	if err != nil {
+		return 0, err
 	}
 
 	query := `
-		INSERT INTO band_playlist_songs (playlist_id, artist, song, notes, position)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, playlist_id, artist, song, notes, position, created_at, updated_at
+		INSERT INTO band_playlist_songs (playlist_id, band_song_id, section_id, notes, song_key, tempo, time_signature, duration, capo, tuning, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 0)
+		RETURNING id
 	`
 
-	var song BandPlaylistSong
-	err = r.db.Get(&song, query, playlistID, req.Artist, req.Song, req.Notes, req.Position)
+	var songID int
+	err = tx.Get(&songID, query, playlistID, bandSongID, req.SectionID, overrides.Notes, overrides.SongKey, overrides.Tempo,
+		overrides.TimeSignature, overrides.Duration, overrides.Capo, overrides.Tuning)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add song: %w", err)
+		return 0, fmt.Errorf("failed to add song: %w", err)
 	}
 
-	return &song, nil
+	err = placeSong(tx, playlistID, songID, req.Position)
+	if err != nil {
+		return 0, err
+	}
+
+	return songID, nil
 }
 
 // UpdateSong updates a specific song in a playlist
 func (r *BandPlaylistRepository) UpdateSong(songID, playlistID, bandID, userID int, req UpdateSongRequest) (*BandPlaylistSong, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockPlaylists(tx, bandID, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil // Playlist not found
+	}
+
+	err = checkSection(tx, req.SectionID, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	bandSongID, overrides, err := resolvePlaylistSong(tx, bandID, req.BandSongID, songSeed(req.Artist, req.Song, req.Notes, req.SongMetadata))
+	if err != nil {
+		return nil, err
 	}
 
 	// Verify that the song belongs to the playlist and the playlist belongs to the band
 	query := `
 		UPDATE band_playlist_songs
-		SET artist = $1, song = $2, notes = $3, position = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $5 AND playlist_id = $6 AND playlist_id IN (
-			SELECT id FROM band_playlists WHERE band_id = $7
+		SET band_song_id = $1, section_id = $2, notes = $3, song_key = $4, tempo = $5, time_signature = $6,
+			duration = $7, capo = $8, tuning = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND playlist_id = $11 AND playlist_id IN (
+			SELECT id FROM band_playlists WHERE band_id = $12
 		)
-		RETURNING id, playlist_id, artist, song, notes, position, created_at, updated_at
+		RETURNING id
 	`
 
-	var song BandPlaylistSong
-	err = r.db.Get(&song, query, req.Artist, req.Song, req.Notes, req.Position, songID, playlistID, bandID)
+	var updatedID int
+	err = tx.Get(&updatedID, query, bandSongID, req.SectionID, overrides.Notes, overrides.SongKey, overrides.Tempo,
+		overrides.TimeSignature, overrides.Duration, overrides.Capo, overrides.Tuning, songID, playlistID, bandID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Song not found
@@ -362,20 +982,93 @@ func (r *BandPlaylistRepository) UpdateSong(songID, playlistID, bandID, userID i
 		return nil, fmt.Errorf("failed to update song: %w", err)
 	}
 
-	return &song, nil
+	err = placeSong(tx, playlistID, updatedID, &req.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := getPlaylistSong(tx, updatedID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return song, nil
 }
 
-// DeleteSong deletes a specific song from a playlist
-func (r *BandPlaylistRepository) DeleteSong(songID, playlistID, bandID, userID int) error {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
+// GetSongChart returns a playlist song with the chord sheet of its catalog song
+func (r *BandPlaylistRepository) GetSongChart(songID, playlistID, bandID, userID int) (*PlaylistSongChart, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + playlistSongColumns + `
+		FROM band_playlist_songs s
+		JOIN band_songs bs ON bs.id = s.band_song_id
+		JOIN band_playlists p ON p.id = s.playlist_id
+		WHERE s.id = $1 AND s.playlist_id = $2 AND p.band_id = $3
+	`
+
+	var song BandPlaylistSong
+	err = r.db.Get(&song, query, songID, playlistID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to get playlist song: %w", err)
+	}
+
+	chart, err := getBandSongChart(r.db, song.BandSongID, bandID)
+	if err != nil {
+		return nil, err
+	}
+	if chart == nil {
+		return nil, nil // Song not found
+	}
+
+	return &PlaylistSongChart{Song: song, Chart: *chart}, nil
+}
+
+// SetSongKey sets the key a playlist plays a song in. A key equal to the
+// catalog key clears the override.
+func (r *BandPlaylistRepository) SetSongKey(songID, playlistID, bandID, userID int, key string) (*BandPlaylistSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		UPDATE band_playlist_songs s
+		SET song_key = NULLIF($1, bs.song_key), updated_at = CURRENT_TIMESTAMP
+		FROM band_songs bs, band_playlists p
+		WHERE bs.id = s.band_song_id AND p.id = s.playlist_id
+			AND s.id = $2 AND s.playlist_id = $3 AND p.band_id = $4
+		RETURNING s.id
+	`
+
+	var updatedID int
+	err = r.db.Get(&updatedID, query, key, songID, playlistID, bandID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil // Band not found
+			return nil, nil // Song not found
 		}
-		return fmt.Errorf("failed to verify band ownership: %w", err)
+		return nil, fmt.Errorf("failed to update song key: %w", err)
+	}
+
+	return getPlaylistSong(r.db, updatedID)
+}
+
+// DeleteSong deletes a specific song from a playlist
+func (r *BandPlaylistRepository) DeleteSong(songID, playlistID, bandID, userID int) error {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return err
 	}
 
 	query := `
@@ -401,3 +1094,389 @@ func (r *BandPlaylistRepository) DeleteSong(songID, playlistID, bandID, userID i
 
 	return nil
 }
+
+// checkSection returns ErrSectionNotFound unless sectionID is nil or a section of the playlist
+func checkSection(q sqlx.Queryer, sectionID *int, playlistID int) error {
+	if sectionID == nil {
+		return nil
+	}
+
+	var id int
+	err := sqlx.Get(q, &id, `SELECT id FROM band_playlist_sections WHERE id = $1 AND playlist_id = $2`, *sectionID, playlistID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrSectionNotFound
+		}
+		return fmt.Errorf("failed to verify section: %w", err)
+	}
+
+	return nil
+}
+
+// getPlaylistSections returns the sections of a playlist in order
+func getPlaylistSections(q sqlx.Queryer, playlistID int) ([]BandPlaylistSection, error) {
+	query := `
+		SELECT ` + sectionColumns + `
+		FROM band_playlist_sections
+		WHERE playlist_id = $1
+		ORDER BY position ASC, id ASC
+	`
+
+	sections := []BandPlaylistSection{}
+	err := sqlx.Select(q, &sections, query, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist sections: %w", err)
+	}
+
+	return sections, nil
+}
+
+// CreateSection adds a section to a playlist
+func (r *BandPlaylistRepository) CreateSection(playlistID, bandID, userID int, req CreateSectionRequest) (*BandPlaylistSection, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO band_playlist_sections (playlist_id, name, position, target_duration)
+		SELECT id, $3, $4, $5 FROM band_playlists WHERE id = $1 AND band_id = $2
+		RETURNING ` + sectionColumns
+
+	var section BandPlaylistSection
+	err = r.db.Get(&section, query, playlistID, bandID, req.Name, req.Position, req.TargetDuration)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Playlist not found
+		}
+		return nil, fmt.Errorf("failed to create section: %w", err)
+	}
+
+	return &section, nil
+}
+
+// UpdateSection updates a section of a playlist
+func (r *BandPlaylistRepository) UpdateSection(sectionID, playlistID, bandID, userID int, req UpdateSectionRequest) (*BandPlaylistSection, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		UPDATE band_playlist_sections
+		SET name = $1, position = $2, target_duration = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND playlist_id = $5 AND playlist_id IN (
+			SELECT id FROM band_playlists WHERE band_id = $6
+		)
+		RETURNING ` + sectionColumns
+
+	var section BandPlaylistSection
+	err = r.db.Get(&section, query, req.Name, req.Position, req.TargetDuration, sectionID, playlistID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Section not found
+		}
+		return nil, fmt.Errorf("failed to update section: %w", err)
+	}
+
+	return &section, nil
+}
+
+// DeleteSection removes a section from a playlist. Its songs stay in the
+// playlist without a section.
+func (r *BandPlaylistRepository) DeleteSection(sectionID, playlistID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	query := `
+		DELETE FROM band_playlist_sections
+		WHERE id = $1 AND playlist_id = $2 AND playlist_id IN (
+			SELECT id FROM band_playlists WHERE band_id = $3
+		)
+	`
+
+	result, err := r.db.Exec(query, sectionID, playlistID, bandID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete section: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReorderSongs rewrites the positions of all songs of a playlist in one
+// transaction. It returns ErrInvalidSongOrder unless songIDs lists every
+// song of the playlist exactly once. Songs keep their sections.
+func (r *BandPlaylistRepository) ReorderSongs(playlistID, bandID, userID int, songIDs []int) ([]BandPlaylistSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockPlaylists(tx, bandID, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil // Playlist not found
+	}
+
+	current, err := orderedSongIDs(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(songIDs) != len(current) {
+		return nil, ErrInvalidSongOrder
+	}
+	remaining := make(map[int]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range songIDs {
+		if !remaining[id] {
+			return nil, ErrInvalidSongOrder
+		}
+		delete(remaining, id)
+	}
+
+	err = writePositions(tx, songIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.getPlaylistSongs(playlistID, bandID)
+}
+
+// MoveSong moves a song to a playlist of the same band, or copies it there
+// with its overrides. It returns ErrPlaylistNotFound when the target
+// playlist is not one of the band's.
+func (r *BandPlaylistRepository) MoveSong(songID, playlistID, bandID, userID int, req MoveSongRequest) (*BandPlaylistSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockPlaylists(tx, bandID, playlistID, req.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		var sourceFound bool
+		sourceFound, err = lockPlaylists(tx, bandID, playlistID)
+		if err != nil {
+			return nil, err
+		}
+		if !sourceFound {
+			return nil, nil // Playlist not found
+		}
+		return nil, ErrPlaylistNotFound
+	}
+
+	var exists bool
+	err = tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM band_playlist_songs WHERE id = $1 AND playlist_id = $2)`, songID, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get song: %w", err)
+	}
+	if !exists {
+		return nil, nil // Song not found
+	}
+
+	// The song joins the section of its new neighbour in the target playlist
+	target, err := orderedSongIDs(tx, req.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+	target = removeID(target, songID)
+	var neighbourID *int
+	switch {
+	case req.Position != nil && *req.Position < len(target):
+		neighbourID = &target[*req.Position]
+	case len(target) > 0:
+		neighbourID = &target[len(target)-1]
+	}
+	var sectionID *int
+	if neighbourID != nil {
+		err = tx.Get(&sectionID, `SELECT section_id FROM band_playlist_songs WHERE id = $1`, *neighbourID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get section: %w", err)
+		}
+	}
+
+	movedID := songID
+	if req.Copy {
+		query := `
+			INSERT INTO band_playlist_songs (playlist_id, band_song_id, section_id, notes, song_key, tempo, time_signature, duration, capo, tuning, position)
+			SELECT $1, band_song_id, $2, notes, song_key, tempo, time_signature, duration, capo, tuning, 0
+			FROM band_playlist_songs
+			WHERE id = $3
+			RETURNING id
+		`
+		err = tx.Get(&movedID, query, req.PlaylistID, sectionID, songID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy song: %w", err)
+		}
+	} else {
+		query := `
+			UPDATE band_playlist_songs
+			SET playlist_id = $1, section_id = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3
+		`
+		_, err = tx.Exec(query, req.PlaylistID, sectionID, songID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move song: %w", err)
+		}
+
+		// Close the gap the song left behind
+		if req.PlaylistID != playlistID {
+			var source []int
+			source, err = orderedSongIDs(tx, playlistID)
+			if err != nil {
+				return nil, err
+			}
+			err = writePositions(tx, source)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = placeSong(tx, req.PlaylistID, movedID, req.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := getPlaylistSong(tx, movedID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return song, nil
+}
+
+// lockPlaylists locks the given playlists of the band so their song
+// positions can be rewritten, and reports whether all of them exist
+func lockPlaylists(tx *sqlx.Tx, bandID int, playlistIDs ...int) (bool, error) {
+	ids := make(pq.Int64Array, 0, len(playlistIDs))
+	for _, id := range playlistIDs {
+		ids = append(ids, int64(id))
+	}
+
+	// Lock in a fixed order so concurrent moves between two playlists cannot deadlock
+	query := `
+		SELECT id FROM band_playlists
+		WHERE id = ANY($1) AND band_id = $2
+		ORDER BY id
+		FOR UPDATE
+	`
+
+	var locked []int
+	err := tx.Select(&locked, query, ids, bandID)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock playlists: %w", err)
+	}
+
+	wanted := make(map[int]bool)
+	for _, id := range playlistIDs {
+		wanted[id] = true
+	}
+	return len(locked) == len(wanted), nil
+}
+
+// orderedSongIDs returns the IDs of a playlist's songs in playlist order
+func orderedSongIDs(q sqlx.Queryer, playlistID int) ([]int, error) {
+	query := `
+		SELECT s.id
+		FROM band_playlist_songs s
+		LEFT JOIN band_playlist_sections sec ON sec.id = s.section_id
+		WHERE s.playlist_id = $1
+		ORDER BY sec.position ASC NULLS FIRST, sec.id ASC NULLS FIRST, s.position ASC, s.created_at ASC, s.id ASC
+	`
+
+	var ids []int
+	err := sqlx.Select(q, &ids, query, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist order: %w", err)
+	}
+
+	return ids, nil
+}
+
+// placeSong puts a song of the playlist at the 0-based position, or at the
+// end when position is nil or past the end, and renumbers the playlist
+func placeSong(tx *sqlx.Tx, playlistID, songID int, position *int) error {
+	ids, err := orderedSongIDs(tx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	ids = removeID(ids, songID)
+	index := len(ids)
+	if position != nil && *position < index {
+		index = *position
+	}
+	ids = append(ids[:index], append([]int{songID}, ids[index:]...)...)
+
+	return writePositions(tx, ids)
+}
+
+// writePositions numbers the songs from 0 in the given order
+func writePositions(tx *sqlx.Tx, songIDs []int) error {
+	ids := make(pq.Int64Array, len(songIDs))
+	for i, id := range songIDs {
+		ids[i] = int64(id)
+	}
+
+	query := `
+		UPDATE band_playlist_songs s
+		SET position = o.ord - 1, updated_at = CURRENT_TIMESTAMP
+		FROM unnest($1::int[]) WITH ORDINALITY AS o(id, ord)
+		WHERE s.id = o.id AND s.position IS DISTINCT FROM o.ord - 1
+	`
+
+	_, err := tx.Exec(query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to write song positions: %w", err)
+	}
+
+	return nil
+}
+
+// removeID returns ids without id
+func removeID(ids []int, id int) []int {
+	result := make([]int, 0, len(ids))
+	for _, v := range ids {
+		if v != id {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -26,6 +26,7 @@ type BandMember struct {
 	Role      string    `db:"role" json:"role"`
 	Email     string    `db:"email" json:"email,omitempty"`
 	Phone     string    `db:"phone" json:"phone,omitempty"`
+	UserID    *int      `db:"user_id" json:"user_id,omitempty"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -35,6 +36,13 @@ type BandWithMembers struct {
 	Band
 	Members     []BandMember `json:"members,omitempty"`
 	MemberCount int          `json:"member_count"`
+	Role        BandRole     `json:"role,omitempty"`
+}
+
+// bandWithRole is a band row joined with the requesting user's role
+type bandWithRole struct {
+	Band
+	Role BandRole `db:"role"`
 }
 
 // CreateBandRequest represents the request to create a new band
@@ -76,16 +84,17 @@ func NewBandRepository(db *sqlx.DB) *BandRepository {
 	return &BandRepository{db: db}
 }
 
-// GetBandsByUserID returns all bands for a specific user
+// GetBandsByUserID returns all bands the user has access to
 func (r *BandRepository) GetBandsByUserID(userID int) ([]BandWithMembers, error) {
 	query := `
-		SELECT b.id, b.name, b.description, b.user_id, b.created_at, b.updated_at
+		SELECT b.id, b.name, b.description, b.user_id, b.created_at, b.updated_at, bu.role
 		FROM bands b
-		WHERE b.user_id = $1
+		JOIN band_users bu ON bu.band_id = b.id
+		WHERE bu.user_id = $1
 		ORDER BY b.created_at DESC
 	`
 
-	var bands []Band
+	var bands []bandWithRole
 	err := r.db.Select(&bands, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bands: %w", err)
@@ -100,9 +109,10 @@ func (r *BandRepository) GetBandsByUserID(userID int) ([]BandWithMembers, error)
 		}
 
 		bandWithMembers := BandWithMembers{
-			Band:        band,
+			Band:        band.Band,
 			Members:     members,
 			MemberCount: len(members),
+			Role:        band.Role,
 		}
 		bandsWithMembers = append(bandsWithMembers, bandWithMembers)
 	}
@@ -110,16 +120,24 @@ func (r *BandRepository) GetBandsByUserID(userID int) ([]BandWithMembers, error)
 	return bandsWithMembers, nil
 }
 
-// GetBandByID returns a specific band by ID (only if owned by the user)
+// GetBandByID returns a specific band by ID (only if the user has access to it)
 func (r *BandRepository) GetBandByID(bandID, userID int) (*BandWithMembers, error) {
+	role, err := getBandRole(r.db, bandID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, nil // Band not found
+	}
+
 	query := `
 		SELECT id, name, description, user_id, created_at, updated_at
 		FROM bands
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1
 	`
 
 	var band Band
-	err := r.db.Get(&band, query, bandID, userID)
+	err = r.db.Get(&band, query, bandID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Band not found
@@ -137,6 +155,7 @@ func (r *BandRepository) GetBandByID(bandID, userID int) (*BandWithMembers, erro
 		Band:        band,
 		Members:     members,
 		MemberCount: len(members),
+		Role:        role,
 	}
 
 	return bandWithMembers, nil
@@ -163,13 +182,19 @@ func (r *BandRepository) CreateBand(userID int, req CreateBandRequest) (*BandWit
 		return nil, err
 	}
 
+	// The creator owns the band
+	_, err = tx.Exec(`INSERT INTO band_users (band_id, user_id, role) VALUES ($1, $2, $3)`, band.ID, userID, BandRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add members if provided
 	var members []BandMember
 	if len(req.Members) > 0 {
 		memberQuery := `
 			INSERT INTO band_members (band_id, name, role, email, phone)
 			VALUES ($1, $2, $3, $4, $5)
-			RETURNING id, band_id, name, role, email, phone, created_at, updated_at
+			RETURNING id, band_id, name, role, email, phone, user_id, created_at, updated_at
 		`
 
 		for _, memberReq := range req.Members {
@@ -190,22 +215,28 @@ func (r *BandRepository) CreateBand(userID int, req CreateBandRequest) (*BandWit
 		Band:        band,
 		Members:     members,
 		MemberCount: len(members),
+		Role:        BandRoleOwner,
 	}
 
 	return bandWithMembers, nil
 }
 
-// UpdateBand updates a specific band
+// UpdateBand updates a specific band (requires admin)
 func (r *BandRepository) UpdateBand(bandID, userID int, req UpdateBandRequest) (*Band, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return nil, err
+	}
+
 	query := `
 		UPDATE bands
 		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $3 AND user_id = $4
+		WHERE id = $3
 		RETURNING id, name, description, user_id, created_at, updated_at
 	`
 
 	var band Band
-	err := r.db.Get(&band, query, req.Name, req.Description, bandID, userID)
+	err = r.db.Get(&band, query, req.Name, req.Description, bandID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Band not found
@@ -216,14 +247,19 @@ func (r *BandRepository) UpdateBand(bandID, userID int, req UpdateBandRequest) (
 	return &band, nil
 }
 
-// DeleteBand deletes a specific band and all its members
+// DeleteBand deletes a specific band and all its members (requires owner)
 func (r *BandRepository) DeleteBand(bandID, userID int) error {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleOwner)
+	if err != nil || !ok {
+		return err
+	}
+
 	query := `
 		DELETE FROM bands
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(query, bandID, userID)
+	result, err := r.db.Exec(query, bandID)
 	if err != nil {
 		return fmt.Errorf("failed to delete band: %w", err)
 	}
@@ -243,7 +279,7 @@ func (r *BandRepository) DeleteBand(bandID, userID int) error {
 // GetBandMembers returns all members of a specific band
 func (r *BandRepository) GetBandMembers(bandID int) ([]BandMember, error) {
 	query := `
-		SELECT id, band_id, name, role, email, phone, created_at, updated_at
+		SELECT id, band_id, name, role, email, phone, user_id, created_at, updated_at
 		FROM band_members
 		WHERE band_id = $1
 		ORDER BY created_at ASC
@@ -260,22 +296,16 @@ func (r *BandRepository) GetBandMembers(bandID int) ([]BandMember, error) {
 
 // AddBandMember adds a new member to a band
 func (r *BandRepository) AddBandMember(bandID, userID int, req AddMemberRequest) (*BandMember, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	// Add the member
 	memberQuery := `
 		INSERT INTO band_members (band_id, name, role, email, phone)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, band_id, name, role, email, phone, created_at, updated_at
+		RETURNING id, band_id, name, role, email, phone, user_id, created_at, updated_at
 	`
 
 	var member BandMember
@@ -289,15 +319,9 @@ func (r *BandRepository) AddBandMember(bandID, userID int, req AddMemberRequest)
 
 // UpdateBandMember updates a specific band member
 func (r *BandRepository) UpdateBandMember(memberID, bandID, userID int, req UpdateMemberRequest) (*BandMember, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	// Update the member
@@ -305,7 +329,7 @@ func (r *BandRepository) UpdateBandMember(memberID, bandID, userID int, req Upda
 		UPDATE band_members
 		SET name = $1, role = $2, email = $3, phone = $4, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $5 AND band_id = $6
-		RETURNING id, band_id, name, role, email, phone, created_at, updated_at
+		RETURNING id, band_id, name, role, email, phone, user_id, created_at, updated_at
 	`
 
 	var member BandMember
@@ -322,15 +346,9 @@ func (r *BandRepository) UpdateBandMember(memberID, bandID, userID int, req Upda
 
 // DeleteBandMember deletes a specific band member
 func (r *BandRepository) DeleteBandMember(memberID, bandID, userID int) error {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil // Band not found
-		}
-		return fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return err
 	}
 
 	// Delete the member
@@ -354,20 +372,14 @@ func (r *BandRepository) DeleteBandMember(memberID, bandID, userID int) error {
 
 // GetBandMemberByID returns a specific band member by ID
 func (r *BandRepository) GetBandMemberByID(memberID, bandID, userID int) (*BandMember, error) {
-	// First verify that the band belongs to the user
-	bandQuery := `SELECT id FROM bands WHERE id = $1 AND user_id = $2`
-	var bandIDCheck int
-	err := r.db.Get(&bandIDCheck, bandQuery, bandID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Band not found
-		}
-		return nil, fmt.Errorf("failed to verify band ownership: %w", err)
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	// Get the member
 	memberQuery := `
-		SELECT id, band_id, name, role, email, phone, created_at, updated_at
+		SELECT id, band_id, name, role, email, phone, user_id, created_at, updated_at
 		FROM band_members
 		WHERE id = $1 AND band_id = $2
 	`
@@ -0,0 +1,373 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nahue/playlists/internal/music"
+)
+
+// Limits of the numeric song metadata
+const (
+	MaxTempo    = 400
+	MaxDuration = 60 * 60
+	MaxCapo     = 12
+	maxTuning   = 50
+)
+
+// SongMetadata holds the musical details of a song. Empty strings and nil
+// values mean the detail is not known.
+type SongMetadata struct {
+	SongKey       string `db:"song_key" json:"song_key"`
+	Tempo         *int   `db:"tempo" json:"tempo"`
+	TimeSignature string `db:"time_signature" json:"time_signature"`
+	Duration      *int   `db:"duration" json:"duration"` // seconds
+	Capo          *int   `db:"capo" json:"capo"`
+	Tuning        string `db:"tuning" json:"tuning"`
+}
+
+// Normalize validates the metadata and rewrites the key and time signature
+// in canonical form
+func (m *SongMetadata) Normalize() error {
+	if m.SongKey != "" {
+		key, err := music.ParseKey(m.SongKey)
+		if err != nil {
+			return err
+		}
+		m.SongKey = key.String()
+	}
+
+	if m.TimeSignature != "" {
+		signature, err := music.ParseTimeSignature(m.TimeSignature)
+		if err != nil {
+			return err
+		}
+		m.TimeSignature = signature
+	}
+
+	if m.Tempo != nil && (*m.Tempo <= 0 || *m.Tempo > MaxTempo) {
+		return fmt.Errorf("tempo must be between 1 and %d BPM", MaxTempo)
+	}
+	if m.Duration != nil && (*m.Duration <= 0 || *m.Duration > MaxDuration) {
+		return fmt.Errorf("duration must be between 1 and %d seconds", MaxDuration)
+	}
+	if m.Capo != nil && (*m.Capo < 0 || *m.Capo > MaxCapo) {
+		return fmt.Errorf("capo must be between 0 and %d", MaxCapo)
+	}
+
+	m.Tuning = strings.TrimSpace(m.Tuning)
+	if len(m.Tuning) > maxTuning {
+		return fmt.Errorf("tuning must be at most %d characters", maxTuning)
+	}
+
+	return nil
+}
+
+// BandSong represents a song in a band's catalog. Playlist songs reference
+// catalog songs, so the same tune shares one set of details across setlists.
+type BandSong struct {
+	ID     int    `db:"id" json:"id"`
+	BandID int    `db:"band_id" json:"band_id"`
+	Artist string `db:"artist" json:"artist"`
+	Title  string `db:"title" json:"title"`
+	SongMetadata
+	Notes     string    `db:"notes" json:"notes"`
+	HasChart  bool      `db:"has_chart" json:"has_chart"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// BandSongChart is the ChordPro chord sheet of a catalog song, with the
+// catalog key of the song
+type BandSongChart struct {
+	SongID    int       `db:"id" json:"song_id"`
+	ChordPro  string    `db:"chordpro" json:"chordpro"`
+	SongKey   string    `db:"song_key" json:"song_key"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateBandSongRequest represents the request to add a song to a band's catalog
+type CreateBandSongRequest struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	SongMetadata
+	Notes string `json:"notes"`
+}
+
+// UpdateBandSongRequest represents the request to update a catalog song
+type UpdateBandSongRequest struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	SongMetadata
+	Notes string `json:"notes"`
+}
+
+const bandSongColumns = `id, band_id, artist, title, song_key, tempo, time_signature, duration, capo, tuning, notes, chordpro <> '' AS has_chart, created_at, updated_at`
+
+// BandSongRepository handles database operations for band song catalogs
+type BandSongRepository struct {
+	db *sqlx.DB
+}
+
+// NewBandSongRepository creates a new band song repository
+func NewBandSongRepository(db *sqlx.DB) *BandSongRepository {
+	return &BandSongRepository{db: db}
+}
+
+// GetSongs returns the catalog of a band ordered by artist and title
+func (r *BandSongRepository) GetSongs(bandID, userID int) ([]BandSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + bandSongColumns + `
+		FROM band_songs
+		WHERE band_id = $1
+		ORDER BY LOWER(artist), LOWER(title)
+	`
+
+	songs := []BandSong{}
+	err = r.db.Select(&songs, query, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get band songs: %w", err)
+	}
+
+	return songs, nil
+}
+
+// GetSong returns a catalog song of the band
+func (r *BandSongRepository) GetSong(songID, bandID, userID int) (*BandSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return getBandSong(r.db, songID, bandID)
+}
+
+// CreateSong adds a song to the band's catalog. It returns ErrBandSongExists
+// when the band already has a song with the same artist and title.
+func (r *BandSongRepository) CreateSong(bandID, userID int, req CreateBandSongRequest) (*BandSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO band_songs (band_id, artist, title, song_key, tempo, time_signature, duration, capo, tuning, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (band_id, LOWER(artist), LOWER(title)) DO NOTHING
+		RETURNING ` + bandSongColumns
+
+	var song BandSong
+	err = r.db.Get(&song, query, bandID, strings.TrimSpace(req.Artist), strings.TrimSpace(req.Title),
+		req.SongKey, req.Tempo, req.TimeSignature, req.Duration, req.Capo, req.Tuning, req.Notes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBandSongExists
+		}
+		return nil, fmt.Errorf("failed to create band song: %w", err)
+	}
+
+	return &song, nil
+}
+
+// UpdateSong updates a catalog song. Every playlist that references the song
+// sees the change, except for the details a playlist overrides.
+func (r *BandSongRepository) UpdateSong(songID, bandID, userID int, req UpdateBandSongRequest) (*BandSong, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	// Renaming onto another catalog song would break the one-song-per-title rule
+	var existingID int
+	err = r.db.Get(&existingID, `
+		SELECT id FROM band_songs
+		WHERE band_id = $1 AND LOWER(artist) = LOWER($2) AND LOWER(title) = LOWER($3) AND id <> $4
+	`, bandID, strings.TrimSpace(req.Artist), strings.TrimSpace(req.Title), songID)
+	if err == nil {
+		return nil, ErrBandSongExists
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check band song: %w", err)
+	}
+
+	query := `
+		UPDATE band_songs
+		SET artist = $1, title = $2, song_key = $3, tempo = $4, time_signature = $5, duration = $6,
+			capo = $7, tuning = $8, notes = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND band_id = $11
+		RETURNING ` + bandSongColumns
+
+	var song BandSong
+	err = r.db.Get(&song, query, strings.TrimSpace(req.Artist), strings.TrimSpace(req.Title),
+		req.SongKey, req.Tempo, req.TimeSignature, req.Duration, req.Capo, req.Tuning, req.Notes, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to update band song: %w", err)
+	}
+
+	return &song, nil
+}
+
+// DeleteSong removes a song from the band's catalog. It returns
+// ErrBandSongInUse while playlists still reference the song.
+func (r *BandSongRepository) DeleteSong(songID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the song so no playlist can start referencing it meanwhile
+	var id int
+	err = tx.Get(&id, `SELECT id FROM band_songs WHERE id = $1 AND band_id = $2 FOR UPDATE`, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil // Song not found
+		}
+		return false, fmt.Errorf("failed to get band song: %w", err)
+	}
+
+	var inUse bool
+	err = tx.Get(&inUse, `SELECT EXISTS (SELECT 1 FROM band_playlist_songs WHERE band_song_id = $1)`, songID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check band song usage: %w", err)
+	}
+	if inUse {
+		return false, ErrBandSongInUse
+	}
+
+	_, err = tx.Exec(`DELETE FROM band_songs WHERE id = $1`, songID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete band song: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetChart returns the chord sheet of a catalog song. Songs without a chart
+// have an empty one.
+func (r *BandSongRepository) GetChart(songID, bandID, userID int) (*BandSongChart, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return getBandSongChart(r.db, songID, bandID)
+}
+
+// SetChart replaces the chord sheet of a catalog song. An empty sheet
+// removes the chart.
+func (r *BandSongRepository) SetChart(songID, bandID, userID int, chordPro string) (*BandSongChart, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		UPDATE band_songs
+		SET chordpro = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND band_id = $3
+		RETURNING id, chordpro, song_key, updated_at
+	`
+
+	var chart BandSongChart
+	err = r.db.Get(&chart, query, chordPro, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to update band song chart: %w", err)
+	}
+
+	return &chart, nil
+}
+
+// getBandSong returns a catalog song of the band without checking band access
+func getBandSong(q sqlx.Queryer, songID, bandID int) (*BandSong, error) {
+	query := `SELECT ` + bandSongColumns + ` FROM band_songs WHERE id = $1 AND band_id = $2`
+
+	var song BandSong
+	err := sqlx.Get(q, &song, query, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to get band song: %w", err)
+	}
+
+	return &song, nil
+}
+
+// getBandSongChart returns the chord sheet of a catalog song of the band
+// without checking band access
+func getBandSongChart(q sqlx.Queryer, songID, bandID int) (*BandSongChart, error) {
+	query := `SELECT id, chordpro, song_key, updated_at FROM band_songs WHERE id = $1 AND band_id = $2`
+
+	var chart BandSongChart
+	err := sqlx.Get(q, &chart, query, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to get band song chart: %w", err)
+	}
+
+	return &chart, nil
+}
+
+// findOrCreateBandSong returns the catalog song of the band with the given
+// artist and title, matched case-insensitively, and whether it was created.
+// A missing song is created with the seed details.
+func findOrCreateBandSong(q sqlx.Queryer, bandID int, seed CreateBandSongRequest) (*BandSong, bool, error) {
+	artist := strings.TrimSpace(seed.Artist)
+	title := strings.TrimSpace(seed.Title)
+
+	query := `
+		INSERT INTO band_songs (band_id, artist, title, song_key, tempo, time_signature, duration, capo, tuning, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (band_id, LOWER(artist), LOWER(title)) DO NOTHING
+		RETURNING ` + bandSongColumns
+
+	var song BandSong
+	err := sqlx.Get(q, &song, query, bandID, artist, title,
+		seed.SongKey, seed.Tempo, seed.TimeSignature, seed.Duration, seed.Capo, seed.Tuning, seed.Notes)
+	if err == nil {
+		return &song, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to create band song: %w", err)
+	}
+
+	query = `
+		SELECT ` + bandSongColumns + `
+		FROM band_songs
+		WHERE band_id = $1 AND LOWER(artist) = LOWER($2) AND LOWER(title) = LOWER($3)
+	`
+
+	err = sqlx.Get(q, &song, query, bandID, artist, title)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find band song: %w", err)
+	}
+
+	return &song, false, nil
+}
@@ -0,0 +1,374 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BandRole is a user's role within a band
+type BandRole string
+
+const (
+	// BandRoleOwner can do everything, including deleting the band and managing owners
+	BandRoleOwner BandRole = "owner"
+	// BandRoleAdmin can manage band details, contacts and membership
+	BandRoleAdmin BandRole = "admin"
+	// BandRoleEditor can change playlists and songs
+	BandRoleEditor BandRole = "editor"
+	// BandRoleViewer has read-only access
+	BandRoleViewer BandRole = "viewer"
+)
+
+var bandRoleRanks = map[BandRole]int{
+	BandRoleViewer: 1,
+	BandRoleEditor: 2,
+	BandRoleAdmin:  3,
+	BandRoleOwner:  4,
+}
+
+// Valid reports whether the role is one of the known band roles
+func (r BandRole) Valid() bool {
+	_, ok := bandRoleRanks[r]
+	return ok
+}
+
+// Allows reports whether the role grants at least the permissions of required
+func (r BandRole) Allows(required BandRole) bool {
+	return r.Valid() && bandRoleRanks[r] >= bandRoleRanks[required]
+}
+
+// BandUser represents a user account linked to a band
+type BandUser struct {
+	BandID    int       `db:"band_id" json:"band_id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Role      BandRole  `db:"role" json:"role"`
+	FirstName string    `db:"first_name" json:"first_name"`
+	LastName  string    `db:"last_name" json:"last_name"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// AddBandUserRequest represents the request to give a user access to a band
+type AddBandUserRequest struct {
+	Email string   `json:"email"`
+	Role  BandRole `json:"role"`
+}
+
+// UpdateBandUserRequest represents the request to change a user's band role
+type UpdateBandUserRequest struct {
+	Role BandRole `json:"role"`
+}
+
+// authorizeBand is the single access check for band-scoped operations.
+// It returns false if the user does not belong to the band, which callers
+// treat as "band not found", and ErrForbidden if the user's role is lower
+// than required.
+func authorizeBand(q sqlx.Queryer, bandID, userID int, required BandRole) (bool, error) {
+	role, err := getBandRole(q, bandID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if role == "" {
+		return false, nil
+	}
+
+	if !role.Allows(required) {
+		return true, ErrForbidden
+	}
+
+	return true, nil
+}
+
+// authorizeMember returns the band member a user acts as: the member linked
+// to their account, or the given member. Acting as another member takes the
+// editor role. It returns ErrBandMemberNotFound when the user has no linked
+// member or the member is not the band's.
+func authorizeMember(q sqlx.Queryer, bandID, userID int, memberID *int) (int, error) {
+	var member struct {
+		ID     int  `db:"id"`
+		UserID *int `db:"user_id"`
+	}
+	var err error
+	if memberID == nil {
+		err = sqlx.Get(q, &member, `SELECT id, user_id FROM band_members WHERE band_id = $1 AND user_id = $2 ORDER BY id LIMIT 1`, bandID, userID)
+	} else {
+		err = sqlx.Get(q, &member, `SELECT id, user_id FROM band_members WHERE id = $1 AND band_id = $2`, *memberID, bandID)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrBandMemberNotFound
+		}
+		return 0, fmt.Errorf("failed to get band member: %w", err)
+	}
+
+	if member.UserID == nil || *member.UserID != userID {
+		_, err = authorizeBand(q, bandID, userID, BandRoleEditor)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return member.ID, nil
+}
+
+// getBandRole returns the user's role in the band, or an empty role if they are not a member
+func getBandRole(q sqlx.Queryer, bandID, userID int) (BandRole, error) {
+	query := `SELECT role FROM band_users WHERE band_id = $1 AND user_id = $2`
+
+	var role BandRole
+	err := sqlx.Get(q, &role, query, bandID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to verify band access: %w", err)
+	}
+
+	return role, nil
+}
+
+// getBandUser returns a single band user joined with their account details
+func getBandUser(q sqlx.Queryer, bandID, userID int) (*BandUser, error) {
+	query := `
+		SELECT bu.band_id, bu.user_id, bu.role, u.first_name, u.last_name, u.email, bu.created_at, bu.updated_at
+		FROM band_users bu
+		JOIN users u ON u.id = bu.user_id
+		WHERE bu.band_id = $1 AND bu.user_id = $2
+	`
+
+	var bandUser BandUser
+	err := sqlx.Get(q, &bandUser, query, bandID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get band user: %w", err)
+	}
+
+	return &bandUser, nil
+}
+
+// countOtherOwners locks the band's owner rows and counts the owners other than userID
+func countOtherOwners(tx *sqlx.Tx, bandID, userID int) (int, error) {
+	query := `SELECT user_id FROM band_users WHERE band_id = $1 AND role = 'owner' FOR UPDATE`
+
+	var owners []int
+	err := tx.Select(&owners, query, bandID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get band owners: %w", err)
+	}
+
+	count := 0
+	for _, owner := range owners {
+		if owner != userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// BandUserRepository handles database operations for band membership and roles
+type BandUserRepository struct {
+	db *sqlx.DB
+}
+
+// NewBandUserRepository creates a new band user repository
+func NewBandUserRepository(db *sqlx.DB) *BandUserRepository {
+	return &BandUserRepository{db: db}
+}
+
+// GetRole returns the user's role in the band, or an empty role if they are not a member
+func (r *BandUserRepository) GetRole(bandID, userID int) (BandRole, error) {
+	return getBandRole(r.db, bandID, userID)
+}
+
+// GetBandUsers returns all users with access to a band
+func (r *BandUserRepository) GetBandUsers(bandID, userID int) ([]BandUser, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT bu.band_id, bu.user_id, bu.role, u.first_name, u.last_name, u.email, bu.created_at, bu.updated_at
+		FROM band_users bu
+		JOIN users u ON u.id = bu.user_id
+		WHERE bu.band_id = $1
+		ORDER BY bu.created_at ASC
+	`
+
+	bandUsers := []BandUser{}
+	err = r.db.Select(&bandUsers, query, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get band users: %w", err)
+	}
+
+	return bandUsers, nil
+}
+
+// AddBandUser gives an existing user access to a band. Admins can add
+// editors and viewers; only owners can add other admins or owners.
+func (r *BandUserRepository) AddBandUser(bandID, userID int, req AddBandUserRequest) (*BandUser, error) {
+	required := BandRoleAdmin
+	if req.Role.Allows(BandRoleAdmin) {
+		required = BandRoleOwner
+	}
+
+	ok, err := authorizeBand(r.db, bandID, userID, required)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var targetID int
+	err = r.db.Get(&targetID, `SELECT id FROM users WHERE email = $1`, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return addBandUser(r.db, bandID, targetID, req.Role)
+}
+
+// addBandUser links a user to a band with the given role
+func addBandUser(q sqlx.Ext, bandID, userID int, role BandRole) (*BandUser, error) {
+	query := `
+		INSERT INTO band_users (band_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (band_id, user_id) DO NOTHING
+	`
+
+	result, err := q.Exec(query, bandID, userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add band user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	return getBandUser(q, bandID, userID)
+}
+
+// UpdateBandUserRole changes a user's role in a band. Only owners can
+// grant or change the admin and owner roles, and the last owner cannot be
+// demoted.
+func (r *BandUserRepository) UpdateBandUserRole(bandID, targetUserID, userID int, req UpdateBandUserRequest) (*BandUser, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ok, err := authorizeBand(tx, bandID, userID, BandRoleAdmin)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	current, err := getBandRole(tx, bandID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return nil, nil // Band user not found
+	}
+
+	if current.Allows(BandRoleAdmin) || req.Role.Allows(BandRoleAdmin) {
+		if _, err := authorizeBand(tx, bandID, userID, BandRoleOwner); err != nil {
+			return nil, err
+		}
+	}
+
+	if current == BandRoleOwner && req.Role != BandRoleOwner {
+		others, err := countOtherOwners(tx, bandID, targetUserID)
+		if err != nil {
+			return nil, err
+		}
+		if others == 0 {
+			return nil, ErrLastOwner
+		}
+	}
+
+	query := `UPDATE band_users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE band_id = $2 AND user_id = $3`
+	_, err = tx.Exec(query, req.Role, bandID, targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update band user: %w", err)
+	}
+
+	bandUser, err := getBandUser(tx, bandID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return bandUser, nil
+}
+
+// RemoveBandUser revokes a user's access to a band. Any member can remove
+// themselves; removing someone else requires admin, or owner to remove an
+// admin or owner. It reports whether a user was removed.
+func (r *BandUserRepository) RemoveBandUser(bandID, targetUserID, userID int) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current, err := getBandRole(tx, bandID, targetUserID)
+	if err != nil {
+		return false, err
+	}
+
+	required := BandRoleViewer
+	if targetUserID != userID {
+		required = BandRoleAdmin
+		if current.Allows(BandRoleAdmin) {
+			required = BandRoleOwner
+		}
+	}
+
+	ok, err := authorizeBand(tx, bandID, userID, required)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if current == "" {
+		return false, nil // Band user not found
+	}
+
+	if current == BandRoleOwner {
+		others, err := countOtherOwners(tx, bandID, targetUserID)
+		if err != nil {
+			return false, err
+		}
+		if others == 0 {
+			return false, ErrLastOwner
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM band_users WHERE band_id = $1 AND user_id = $2`, bandID, targetUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove band user: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CalendarFeed is a secret iCalendar subscription URL of a user. It covers
+// one band, or every band the user belongs to when BandID is nil.
+type CalendarFeed struct {
+	ID          int        `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"user_id"`
+	BandID      *int       `db:"band_id" json:"band_id"`
+	BandName    string     `db:"band_name" json:"band_name,omitempty"`
+	TokenPrefix string     `db:"token_prefix" json:"token_prefix"`
+	LastUsedAt  *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateCalendarFeedRequest represents the request to create a calendar
+// feed, of one band or of all the user's bands when BandID is nil
+type CreateCalendarFeedRequest struct {
+	BandID *int `json:"band_id"`
+}
+
+// CalendarGig is a gig in a calendar feed, with the name of its band
+type CalendarGig struct {
+	Gig
+	BandName string `db:"band_name" json:"band_name"`
+}
+
+// CalendarRehearsal is a scheduled or cancelled rehearsal in a calendar
+// feed, with the name of its band
+type CalendarRehearsal struct {
+	Rehearsal
+	BandName string `db:"band_name" json:"band_name"`
+}
+
+// calendarHistory is how far back calendar feeds list past events
+const calendarHistory = "1 year"
+
+const calendarFeedColumns = `
+	f.id, f.user_id, f.band_id, COALESCE(b.name, '') AS band_name, f.token_prefix, f.last_used_at, f.created_at`
+
+// CalendarFeedRepository handles database operations for calendar feeds.
+// Only hashes of the feed tokens are stored.
+type CalendarFeedRepository struct {
+	db *sqlx.DB
+}
+
+// NewCalendarFeedRepository creates a new calendar feed repository
+func NewCalendarFeedRepository(db *sqlx.DB) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db: db}
+}
+
+// CreateFeed stores a new feed for the user, replacing the feed of the same
+// band so its old URL stops working. prefix is the part of the token shown
+// when listing feeds. It returns nil if the user is not a member of the band.
+func (r *CalendarFeedRepository) CreateFeed(userID int, req CreateCalendarFeedRequest, token, prefix string) (*CalendarFeed, error) {
+	if req.BandID != nil {
+		ok, err := authorizeBand(r.db, *req.BandID, userID, BandRoleViewer)
+		if err != nil || !ok {
+			return nil, err
+		}
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM calendar_feeds WHERE user_id = $1 AND band_id IS NOT DISTINCT FROM $2`, userID, req.BandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace calendar feed: %w", err)
+	}
+
+	query := `
+		INSERT INTO calendar_feeds (user_id, band_id, token_prefix, token_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	var feedID int
+	err = tx.Get(&feedID, query, userID, req.BandID, prefix, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar feed: %w", err)
+	}
+
+	var feed CalendarFeed
+	err = tx.Get(&feed, `SELECT `+calendarFeedColumns+` FROM calendar_feeds f LEFT JOIN bands b ON b.id = f.band_id WHERE f.id = $1`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &feed, nil
+}
+
+// GetFeeds returns the user's calendar feeds, newest first
+func (r *CalendarFeedRepository) GetFeeds(userID int) ([]CalendarFeed, error) {
+	query := `
+		SELECT ` + calendarFeedColumns + `
+		FROM calendar_feeds f
+		LEFT JOIN bands b ON b.id = f.band_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC, f.id DESC
+	`
+
+	feeds := []CalendarFeed{}
+	err := r.db.Select(&feeds, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feeds: %w", err)
+	}
+
+	return feeds, nil
+}
+
+// DeleteFeed revokes one of the user's feeds. It reports whether a feed was deleted.
+func (r *CalendarFeedRepository) DeleteFeed(feedID, userID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM calendar_feeds WHERE id = $1 AND user_id = $2`, feedID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete calendar feed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// AuthenticateFeed returns the feed matching the token and records that it
+// was used. It returns nil if there is no such feed.
+func (r *CalendarFeedRepository) AuthenticateFeed(token string) (*CalendarFeed, error) {
+	query := `
+		SELECT ` + calendarFeedColumns + `
+		FROM calendar_feeds f
+		LEFT JOIN bands b ON b.id = f.band_id
+		WHERE f.token_hash = $1
+	`
+
+	var feed CalendarFeed
+	err := r.db.Get(&feed, query, hashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Feed not found or revoked
+		}
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+
+	now := time.Now()
+	if feed.LastUsedAt == nil || now.Sub(*feed.LastUsedAt) > lastUsedResolution {
+		_, err = r.db.Exec(`UPDATE calendar_feeds SET last_used_at = $1 WHERE id = $2`, now, feed.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record calendar feed use: %w", err)
+		}
+		feed.LastUsedAt = &now
+	}
+
+	return &feed, nil
+}
+
+// GetFeedGigs returns the gigs in a feed: those of the bands it covers that
+// the user still belongs to, from a year ago on, soonest first
+func (r *CalendarFeedRepository) GetFeedGigs(feed *CalendarFeed) ([]CalendarGig, error) {
+	query := `
+		SELECT ` + gigColumns + `, b.name AS band_name
+		FROM gigs g
+		JOIN bands b ON b.id = g.band_id
+		JOIN band_users bu ON bu.band_id = g.band_id AND bu.user_id = $1
+		WHERE ($2::int IS NULL OR g.band_id = $2)
+			AND g.starts_at > CURRENT_TIMESTAMP - INTERVAL '` + calendarHistory + `'
+		ORDER BY g.starts_at ASC, g.id ASC
+	`
+
+	gigs := []CalendarGig{}
+	err := r.db.Select(&gigs, query, feed.UserID, feed.BandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar gigs: %w", err)
+	}
+
+	for i := range gigs {
+		gigs[i].inTimezone()
+	}
+	return gigs, nil
+}
+
+// GetFeedRehearsals returns the rehearsals in a feed: those of the bands it
+// covers that the user still belongs to that have been given a time, from a
+// year ago on, soonest first
+func (r *CalendarFeedRepository) GetFeedRehearsals(feed *CalendarFeed) ([]CalendarRehearsal, error) {
+	query := `
+		SELECT ` + rehearsalColumns + `, b.name AS band_name
+		FROM rehearsals r
+		JOIN bands b ON b.id = r.band_id
+		JOIN band_users bu ON bu.band_id = r.band_id AND bu.user_id = $1
+		WHERE ($2::int IS NULL OR r.band_id = $2)
+			AND r.starts_at > CURRENT_TIMESTAMP - INTERVAL '` + calendarHistory + `'
+		ORDER BY r.starts_at ASC, r.id ASC
+	`
+
+	rehearsals := []CalendarRehearsal{}
+	err := r.db.Select(&rehearsals, query, feed.UserID, feed.BandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar rehearsals: %w", err)
+	}
+
+	for i := range rehearsals {
+		rehearsals[i].inTimezone()
+	}
+	return rehearsals, nil
+}
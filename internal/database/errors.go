@@ -0,0 +1,91 @@
+package database
+
+import "errors"
+
+var (
+	// ErrForbidden is returned when a user belongs to a band but their role
+	// does not allow the requested operation
+	ErrForbidden = errors.New("insufficient band permissions")
+
+	// ErrUserNotFound is returned when an operation references a user that does not exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrAlreadyMember is returned when adding a user that already belongs to the band
+	ErrAlreadyMember = errors.New("user is already a band member")
+
+	// ErrLastOwner is returned when an operation would leave a band without an owner
+	ErrLastOwner = errors.New("band must keep at least one owner")
+
+	// ErrBandMemberNotFound is returned when an operation references a member outside the band
+	ErrBandMemberNotFound = errors.New("band member not found")
+
+	// ErrInvitationUnavailable is returned when an invitation was already
+	// answered, revoked, superseded or has expired
+	ErrInvitationUnavailable = errors.New("invitation is no longer valid")
+
+	// ErrInvitationEmailMismatch is returned when a user accepts an invitation sent to another address
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again; the session it belongs to has been revoked
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+	// ErrBandPolicyRequired is returned when deleting an account that is the
+	// only owner of bands without saying what should happen to them
+	ErrBandPolicyRequired = errors.New("account is the only owner of bands; choose to transfer or delete them")
+
+	// ErrNoTransferTarget is returned when a band cannot be transferred because
+	// it has no other admin to become its owner
+	ErrNoTransferTarget = errors.New("band has no other admin to transfer ownership to")
+
+	// ErrMFAAlreadyEnabled is returned when enrolling a second factor that is already enabled
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrIdentityEmailUnverified is returned when an external identity cannot be
+	// linked because the provider did not verify its email address
+	ErrIdentityEmailUnverified = errors.New("identity provider did not verify the email address")
+
+	// ErrIdentityConflict is returned when an account already links a different
+	// identity of the same provider
+	ErrIdentityConflict = errors.New("account is already linked to another identity of this provider")
+
+	// ErrBandSongExists is returned when a band's catalog already has a song
+	// with the same artist and title
+	ErrBandSongExists = errors.New("band already has a song with this artist and title")
+
+	// ErrBandSongInUse is returned when deleting a catalog song that playlists still reference
+	ErrBandSongInUse = errors.New("song is still used in playlists")
+
+	// ErrBandSongNotFound is returned when a playlist song references a song outside the band's catalog
+	ErrBandSongNotFound = errors.New("band song not found")
+
+	// ErrSectionNotFound is returned when a playlist song references a section outside its playlist
+	ErrSectionNotFound = errors.New("playlist section not found")
+
+	// ErrInvalidSongOrder is returned when a new song order does not list
+	// every song of the playlist exactly once
+	ErrInvalidSongOrder = errors.New("song order must list every song of the playlist once")
+
+	// ErrPlaylistNotFound is returned when an operation targets a playlist outside the band
+	ErrPlaylistNotFound = errors.New("playlist not found")
+
+	// ErrTemplateNotFound is returned when a playlist is created from a template the band does not have
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrRehearsalClosed is returned when changing the poll of a rehearsal
+	// that is already scheduled or cancelled, or scheduling a cancelled one
+	ErrRehearsalClosed = errors.New("rehearsal is no longer open")
+
+	// ErrTooManySlots is returned when adding a slot to a poll that already has MaxRehearsalSlots
+	ErrTooManySlots = errors.New("rehearsal has too many slots")
+
+	// ErrSlotNotFound is returned when an operation references a slot outside the rehearsal's poll
+	ErrSlotNotFound = errors.New("rehearsal slot not found")
+
+	// ErrNoAvailableSlot is returned when scheduling a rehearsal at its best
+	// slot while no member can make any slot
+	ErrNoAvailableSlot = errors.New("no member can make any of the rehearsal slots")
+
+	// ErrPlaylistSongNotFound is returned when an operation references a song outside the band's playlists
+	ErrPlaylistSongNotFound = errors.New("playlist song not found")
+)
@@ -0,0 +1,395 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Gig statuses
+const (
+	GigTentative = "tentative"
+	GigConfirmed = "confirmed"
+	GigCancelled = "cancelled"
+)
+
+// EventPeriod selects gigs and rehearsals by whether they are still to come
+type EventPeriod string
+
+const (
+	// EventsUpcoming are the events on or after today, in the timezone of each event
+	EventsUpcoming EventPeriod = "upcoming"
+	// EventsPast are the events before today
+	EventsPast EventPeriod = "past"
+)
+
+// GigDetails holds what a band knows about a gig. Times are instants, shown
+// in Timezone, the IANA zone of the venue. Fee is in cents of Currency.
+type GigDetails struct {
+	Name         string     `db:"name" json:"name"`
+	StartsAt     time.Time  `db:"starts_at" json:"starts_at"`
+	Timezone     string     `db:"timezone" json:"timezone"`
+	VenueName    string     `db:"venue_name" json:"venue_name"`
+	VenueAddress string     `db:"venue_address" json:"venue_address"`
+	LoadInAt     *time.Time `db:"load_in_at" json:"load_in_at"`
+	SoundcheckAt *time.Time `db:"soundcheck_at" json:"soundcheck_at"`
+	SetAt        *time.Time `db:"set_at" json:"set_at"`
+	Fee          *int       `db:"fee" json:"fee"`
+	Currency     string     `db:"currency" json:"currency"`
+	Status       string     `db:"status" json:"status"`
+	Notes        string     `db:"notes" json:"notes"`
+}
+
+// Normalize validates the details, filling in the tentative status when
+// none is given, and shows the times in the gig's timezone
+func (d *GigDetails) Normalize() error {
+	if d.StartsAt.IsZero() {
+		return fmt.Errorf("start time is required")
+	}
+	location, err := loadTimezone(d.Timezone)
+	if err != nil {
+		return err
+	}
+
+	d.Name = strings.TrimSpace(d.Name)
+	d.VenueName = strings.TrimSpace(d.VenueName)
+	d.VenueAddress = strings.TrimSpace(d.VenueAddress)
+
+	switch d.Status {
+	case "":
+		d.Status = GigTentative
+	case GigTentative, GigConfirmed, GigCancelled:
+	default:
+		return fmt.Errorf("status must be tentative, confirmed or cancelled")
+	}
+
+	if d.Fee != nil && *d.Fee < 0 {
+		return fmt.Errorf("fee cannot be negative")
+	}
+	d.Currency = strings.ToUpper(strings.TrimSpace(d.Currency))
+	if d.Currency != "" && (len(d.Currency) != 3 || strings.Trim(d.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "") {
+		return fmt.Errorf("currency must be a three-letter code such as EUR")
+	}
+
+	d.localize(location)
+	return nil
+}
+
+// loadTimezone returns the location of an IANA timezone name given for an event
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, fmt.Errorf("timezone is required")
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("invalid timezone %q", name)
+	}
+	return location, nil
+}
+
+// localize shows the times of the gig in the given location
+func (d *GigDetails) localize(location *time.Location) {
+	d.StartsAt = d.StartsAt.In(location)
+	for _, t := range []*time.Time{d.LoadInAt, d.SoundcheckAt, d.SetAt} {
+		if t != nil {
+			*t = t.In(location)
+		}
+	}
+}
+
+// Gig is a show a band plays, with the playlists played at it in order
+type Gig struct {
+	ID     int `db:"id" json:"id"`
+	BandID int `db:"band_id" json:"band_id"`
+	GigDetails
+	PlaylistIDs pq.Int64Array `db:"playlist_ids" json:"playlist_ids"`
+	// Sequence counts the changes made to the gig since it was added
+	Sequence  int       `db:"sequence" json:"sequence"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// GigWithPlaylists is a gig with the playlists played at it
+type GigWithPlaylists struct {
+	Gig
+	Playlists []BandPlaylist `json:"playlists"`
+}
+
+// CreateGigRequest represents the request to add a gig. PlaylistIDs are the
+// band playlists played at it, in order.
+type CreateGigRequest struct {
+	GigDetails
+	PlaylistIDs []int `json:"playlist_ids"`
+}
+
+// UpdateGigRequest represents the request to update a gig, replacing its playlists
+type UpdateGigRequest struct {
+	GigDetails
+	PlaylistIDs []int `json:"playlist_ids"`
+}
+
+const gigColumns = `
+	g.id, g.band_id, g.name, g.starts_at, g.timezone, g.venue_name, g.venue_address,
+	g.load_in_at, g.soundcheck_at, g.set_at, g.fee, g.currency, g.status, g.notes,
+	ARRAY(SELECT gp.playlist_id FROM gig_playlists gp WHERE gp.gig_id = g.id ORDER BY gp.position) AS playlist_ids,
+	g.sequence, g.created_at, g.updated_at`
+
+// GigRepository handles database operations for band gigs
+type GigRepository struct {
+	db *sqlx.DB
+}
+
+// NewGigRepository creates a new gig repository
+func NewGigRepository(db *sqlx.DB) *GigRepository {
+	return &GigRepository{db: db}
+}
+
+// GetGigs returns the gigs of a band. Upcoming gigs come soonest first, past
+// gigs most recent first, and all gigs in date order.
+func (r *GigRepository) GetGigs(bandID, userID int, period EventPeriod) ([]Gig, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	// A gig stays upcoming until the end of its day where it is played
+	filter, order := "", "g.starts_at ASC"
+	switch period {
+	case EventsUpcoming:
+		filter = `AND (g.starts_at AT TIME ZONE g.timezone)::date >= (CURRENT_TIMESTAMP AT TIME ZONE g.timezone)::date`
+	case EventsPast:
+		filter = `AND (g.starts_at AT TIME ZONE g.timezone)::date < (CURRENT_TIMESTAMP AT TIME ZONE g.timezone)::date`
+		order = "g.starts_at DESC"
+	}
+
+	query := `
+		SELECT ` + gigColumns + `
+		FROM gigs g
+		WHERE g.band_id = $1 ` + filter + `
+		ORDER BY ` + order + `, g.id ASC
+	`
+
+	gigs := []Gig{}
+	err = r.db.Select(&gigs, query, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gigs: %w", err)
+	}
+
+	for i := range gigs {
+		gigs[i].inTimezone()
+	}
+	return gigs, nil
+}
+
+// GetGig returns a gig of the band with its playlists
+func (r *GigRepository) GetGig(gigID, bandID, userID int) (*GigWithPlaylists, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	gig, err := getGig(r.db, gigID, bandID)
+	if err != nil || gig == nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + playlistColumns + `
+		FROM gig_playlists gp
+		JOIN band_playlists ON band_playlists.id = gp.playlist_id
+		WHERE gp.gig_id = $1
+		ORDER BY gp.position
+	`
+
+	playlists := []BandPlaylist{}
+	err = r.db.Select(&playlists, query, gigID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gig playlists: %w", err)
+	}
+
+	return &GigWithPlaylists{Gig: *gig, Playlists: playlists}, nil
+}
+
+// CreateGig adds a gig to the band. It returns ErrPlaylistNotFound when a
+// playlist is not one of the band's.
+func (r *GigRepository) CreateGig(bandID, userID int, req CreateGigRequest) (*Gig, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gigs (band_id, name, starts_at, timezone, venue_name, venue_address,
+			load_in_at, soundcheck_at, set_at, fee, currency, status, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`
+
+	var gigID int
+	err = tx.Get(&gigID, query, bandID, req.Name, req.StartsAt, req.Timezone, req.VenueName, req.VenueAddress,
+		req.LoadInAt, req.SoundcheckAt, req.SetAt, req.Fee, req.Currency, req.Status, req.Notes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gig: %w", err)
+	}
+
+	err = setGigPlaylists(tx, gigID, bandID, req.PlaylistIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	gig, err := getGig(tx, gigID, bandID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return gig, nil
+}
+
+// UpdateGig updates a gig and replaces its playlists. It returns
+// ErrPlaylistNotFound when a playlist is not one of the band's.
+func (r *GigRepository) UpdateGig(gigID, bandID, userID int, req UpdateGigRequest) (*Gig, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE gigs
+		SET name = $1, starts_at = $2, timezone = $3, venue_name = $4, venue_address = $5, load_in_at = $6,
+			soundcheck_at = $7, set_at = $8, fee = $9, currency = $10, status = $11, notes = $12,
+			sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $13 AND band_id = $14
+		RETURNING id
+	`
+
+	var updatedID int
+	err = tx.Get(&updatedID, query, req.Name, req.StartsAt, req.Timezone, req.VenueName, req.VenueAddress,
+		req.LoadInAt, req.SoundcheckAt, req.SetAt, req.Fee, req.Currency, req.Status, req.Notes, gigID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Gig not found
+		}
+		return nil, fmt.Errorf("failed to update gig: %w", err)
+	}
+
+	err = setGigPlaylists(tx, gigID, bandID, req.PlaylistIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	gig, err := getGig(tx, gigID, bandID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return gig, nil
+}
+
+// DeleteGig removes a gig of the band. Its playlists are kept.
+func (r *GigRepository) DeleteGig(gigID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM gigs WHERE id = $1 AND band_id = $2`, gigID, bandID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete gig: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// getGig returns a gig of the band without checking band access
+func getGig(q sqlx.Queryer, gigID, bandID int) (*Gig, error) {
+	query := `SELECT ` + gigColumns + ` FROM gigs g WHERE g.id = $1 AND g.band_id = $2`
+
+	var gig Gig
+	err := sqlx.Get(q, &gig, query, gigID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Gig not found
+		}
+		return nil, fmt.Errorf("failed to get gig: %w", err)
+	}
+
+	gig.inTimezone()
+	return &gig, nil
+}
+
+// inTimezone shows the times of a gig read from the database in its timezone
+func (g *Gig) inTimezone() {
+	location, err := time.LoadLocation(g.Timezone)
+	if err == nil {
+		g.localize(location)
+	}
+}
+
+// setGigPlaylists replaces the playlists of a gig, in the given order
+func setGigPlaylists(tx *sqlx.Tx, gigID, bandID int, playlistIDs []int) error {
+	ids := make(pq.Int64Array, 0, len(playlistIDs))
+	seen := make(map[int]bool)
+	for _, id := range playlistIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, int64(id))
+		}
+	}
+
+	var found int
+	err := tx.Get(&found, `SELECT COUNT(*) FROM band_playlists WHERE id = ANY($1) AND band_id = $2`, ids, bandID)
+	if err != nil {
+		return fmt.Errorf("failed to check playlists: %w", err)
+	}
+	if found != len(ids) {
+		return ErrPlaylistNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM gig_playlists WHERE gig_id = $1`, gigID)
+	if err != nil {
+		return fmt.Errorf("failed to clear gig playlists: %w", err)
+	}
+
+	query := `
+		INSERT INTO gig_playlists (gig_id, playlist_id, position)
+		SELECT $1, playlist_id, position - 1
+		FROM unnest($2::int[]) WITH ORDINALITY AS t(playlist_id, position)
+	`
+	_, err = tx.Exec(query, gigID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to set gig playlists: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UserTOTP is a user's TOTP second factor. Enrollment is pending until
+// EnabledAt is set.
+type UserTOTP struct {
+	UserID         int        `db:"user_id" json:"user_id"`
+	Secret         string     `db:"secret" json:"-"`
+	EnabledAt      *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep   *int64     `db:"last_used_step" json:"-"`
+	FailedAttempts int        `db:"failed_attempts" json:"-"`
+	LockedUntil    *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Enabled reports whether enrollment was confirmed, so login requires a code
+func (t *UserTOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// Locked reports whether verification is locked after too many failed codes
+func (t *UserTOTP) Locked(now time.Time) bool {
+	return t != nil && t.LockedUntil != nil && t.LockedUntil.After(now)
+}
+
+// MFACodeRequest carries a second factor: a TOTP code or, if the
+// authenticator is lost, a recovery code
+type MFACodeRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+const totpColumns = `user_id, secret, enabled_at, last_used_step, failed_attempts, locked_until, created_at, updated_at`
+
+// MFARepository handles database operations for TOTP secrets and recovery
+// codes. Recovery codes are stored as hashes.
+type MFARepository struct {
+	db *sqlx.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// GetTOTP returns the user's TOTP settings, or nil if they never enrolled
+func (r *MFARepository) GetTOTP(userID int) (*UserTOTP, error) {
+	query := `SELECT ` + totpColumns + ` FROM user_totp WHERE user_id = $1`
+
+	var userTOTP UserTOTP
+	err := r.db.Get(&userTOTP, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // TOTP not enrolled
+		}
+		return nil, fmt.Errorf("failed to get TOTP settings: %w", err)
+	}
+
+	return &userTOTP, nil
+}
+
+// BeginTOTPEnrollment stores a new secret pending confirmation, replacing any
+// earlier unconfirmed one. It returns ErrMFAAlreadyEnabled if TOTP is enabled.
+func (r *MFARepository) BeginTOTPEnrollment(userID int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = NULL, failed_attempts = 0, locked_until = NULL,
+			created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to begin TOTP enrollment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTPEnrollment enables a pending enrollment whose first code matched
+// the given time step and stores the user's recovery codes. It reports
+// whether a pending enrollment was enabled.
+func (r *MFARepository) ConfirmTOTPEnrollment(userID int, step int64, recoveryCodes []string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp
+		SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+
+	result, err := tx.Exec(query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err = replaceRecoveryCodes(tx, userID, recoveryCodes); err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DisableTOTP removes the user's TOTP secret and recovery codes. It reports
+// whether TOTP was enrolled.
+func (r *MFARepository) DisableTOTP(userID int) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable TOTP: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UseTOTPStep records a successful code for the given time step and clears
+// failed attempts. It reports false if a code of that step or a later one
+// was already used, so every code works only once.
+func (r *MFARepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use TOTP code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UseRecoveryCode redeems one of the user's unused recovery codes and clears
+// failed attempts. It reports whether the code was valid.
+func (r *MFARepository) UseRecoveryCode(userID int, code string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)
+	`
+
+	result, err := tx.Exec(query, userID, hashToken(code))
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`UPDATE user_totp SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset failed attempts: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RecordFailedAttempt counts a wrong code. After maxAttempts consecutive
+// failures verification is locked for the lockout duration.
+func (r *MFARepository) RecordFailedAttempt(userID, maxAttempts int, lockout time.Duration) error {
+	query := `
+		UPDATE user_totp
+		SET failed_attempts = failed_attempts + 1,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+		WHERE user_id = $1
+	`
+
+	_, err := r.db.Exec(query, userID, maxAttempts, time.Now().Add(lockout))
+	if err != nil {
+		return fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores new ones
+func (r *MFARepository) ReplaceRecoveryCodes(userID int, recoveryCodes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = replaceRecoveryCodes(tx, userID, recoveryCodes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *MFARepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores hashes of the new ones
+func replaceRecoveryCodes(tx *sqlx.Tx, userID int, recoveryCodes []string) error {
+	_, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, code := range recoveryCodes {
+		_, err = tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hashToken(code))
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// TokenScope is a permission granted to a personal access token
+type TokenScope string
+
+const (
+	// ScopeRead allows read-only requests
+	ScopeRead TokenScope = "read"
+	// ScopeBandsWrite also allows creating, changing and deleting bands, members and playlists
+	ScopeBandsWrite TokenScope = "bands:write"
+)
+
+// Valid reports whether the scope is one of the known token scopes
+func (s TokenScope) Valid() bool {
+	return s == ScopeRead || s == ScopeBandsWrite
+}
+
+// lastUsedResolution is how stale last_used_at may get before a request updates it,
+// so busy tokens do not write on every request
+const lastUsedResolution = time.Minute
+
+// PersonalAccessToken represents a long-lived API token created by a user
+type PersonalAccessToken struct {
+	ID          int            `db:"id" json:"id"`
+	UserID      int            `db:"user_id" json:"user_id"`
+	Name        string         `db:"name" json:"name"`
+	TokenPrefix string         `db:"token_prefix" json:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt   *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if TokenScope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatePersonalAccessTokenRequest represents the request to create a personal access token
+type CreatePersonalAccessTokenRequest struct {
+	Name      string       `json:"name"`
+	Scopes    []TokenScope `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
+
+const personalAccessTokenColumns = `id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at`
+
+// PersonalAccessTokenRepository handles database operations for personal
+// access tokens. Only hashes of the tokens are stored.
+type PersonalAccessTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewPersonalAccessTokenRepository creates a new personal access token repository
+func NewPersonalAccessTokenRepository(db *sqlx.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+// CreateToken stores a new token for the user. prefix is the part of the
+// token shown when listing tokens.
+func (r *PersonalAccessTokenRepository) CreateToken(userID int, req CreatePersonalAccessTokenRequest, token, prefix string) (*PersonalAccessToken, error) {
+	scopes := make(pq.StringArray, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	query := `
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + personalAccessTokenColumns
+
+	var pat PersonalAccessToken
+	err := r.db.Get(&pat, query, userID, req.Name, prefix, hashToken(token), scopes, req.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create personal access token: %w", err)
+	}
+
+	return &pat, nil
+}
+
+// GetTokens returns the user's tokens, newest first
+func (r *PersonalAccessTokenRepository) GetTokens(userID int) ([]PersonalAccessToken, error) {
+	query := `
+		SELECT ` + personalAccessTokenColumns + `
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	tokens := []PersonalAccessToken{}
+	err := r.db.Select(&tokens, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get personal access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteToken revokes one of the user's tokens. It reports whether a token was deleted.
+func (r *PersonalAccessTokenRepository) DeleteToken(tokenID, userID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, tokenID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete personal access token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// AuthenticateToken returns the unexpired token matching the secret and
+// records that it was used. It returns nil if there is no such token.
+func (r *PersonalAccessTokenRepository) AuthenticateToken(token string) (*PersonalAccessToken, error) {
+	query := `
+		SELECT ` + personalAccessTokenColumns + `
+		FROM personal_access_tokens
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`
+
+	var pat PersonalAccessToken
+	err := r.db.Get(&pat, query, hashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Token not found or expired
+		}
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+
+	now := time.Now()
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > lastUsedResolution {
+		_, err = r.db.Exec(`UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2`, now, pat.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record token use: %w", err)
+		}
+		pat.LastUsedAt = &now
+	}
+
+	return &pat, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlaylistEntry represents a song request in the shared playlist queue
+type PlaylistEntry struct {
+	ID        int       `db:"id" json:"id"`
+	Artist    string    `db:"artist" json:"artist"`
+	Song      string    `db:"song" json:"song"`
+	UserName  string    `db:"user_name" json:"user_name"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreatePlaylistEntryRequest represents the request to add a song to the queue
+type CreatePlaylistEntryRequest struct {
+	Artist   string `json:"artist"`
+	Song     string `json:"song"`
+	UserName string `json:"user_name"`
+}
+
+// UpdatePlaylistEntryRequest represents the request to update a queued song
+type UpdatePlaylistEntryRequest struct {
+	Artist   string `json:"artist"`
+	Song     string `json:"song"`
+	UserName string `json:"user_name"`
+}
+
+// PlaylistEntryRepository handles database operations for the song request queue
+type PlaylistEntryRepository struct {
+	db *sqlx.DB
+}
+
+// NewPlaylistEntryRepository creates a new playlist entry repository
+func NewPlaylistEntryRepository(db *sqlx.DB) *PlaylistEntryRepository {
+	return &PlaylistEntryRepository{db: db}
+}
+
+// GetEntries returns all entries in the queue, oldest first
+func (r *PlaylistEntryRepository) GetEntries() ([]PlaylistEntry, error) {
+	query := `
+		SELECT id, artist, song, user_name, user_id, created_at, updated_at
+		FROM playlist_entries
+		ORDER BY created_at ASC, id ASC
+	`
+
+	entries := []PlaylistEntry{}
+	err := r.db.Select(&entries, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetEntryByID returns a specific entry by ID
+func (r *PlaylistEntryRepository) GetEntryByID(entryID int) (*PlaylistEntry, error) {
+	query := `
+		SELECT id, artist, song, user_name, user_id, created_at, updated_at
+		FROM playlist_entries
+		WHERE id = $1
+	`
+
+	var entry PlaylistEntry
+	err := r.db.Get(&entry, query, entryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Entry not found
+		}
+		return nil, fmt.Errorf("failed to get playlist entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// CreateEntry adds a new entry to the queue on behalf of the given user
+func (r *PlaylistEntryRepository) CreateEntry(userID int, req CreatePlaylistEntryRequest) (*PlaylistEntry, error) {
+	query := `
+		INSERT INTO playlist_entries (artist, song, user_name, user_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, artist, song, user_name, user_id, created_at, updated_at
+	`
+
+	var entry PlaylistEntry
+	err := r.db.Get(&entry, query, req.Artist, req.Song, req.UserName, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playlist entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// UpdateEntry updates a specific entry
+func (r *PlaylistEntryRepository) UpdateEntry(entryID int, req UpdatePlaylistEntryRequest) (*PlaylistEntry, error) {
+	query := `
+		UPDATE playlist_entries
+		SET artist = $1, song = $2, user_name = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING id, artist, song, user_name, user_id, created_at, updated_at
+	`
+
+	var entry PlaylistEntry
+	err := r.db.Get(&entry, query, req.Artist, req.Song, req.UserName, entryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Entry not found
+		}
+		return nil, fmt.Errorf("failed to update playlist entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// DeleteEntry deletes a specific entry. It reports whether an entry was deleted.
+func (r *PlaylistEntryRepository) DeleteEntry(entryID int) (bool, error) {
+	query := `DELETE FROM playlist_entries WHERE id = $1`
+
+	result, err := r.db.Exec(query, entryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete playlist entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetArtists returns distinct artists matching the search term, for autocomplete
+func (r *PlaylistEntryRepository) GetArtists(search string, limit int) ([]string, error) {
+	query := `
+		SELECT artist
+		FROM playlist_entries
+		WHERE artist ILIKE '%' || $1 || '%'
+		GROUP BY artist
+		ORDER BY MIN(created_at) ASC
+		LIMIT $2
+	`
+
+	artists := []string{}
+	err := r.db.Select(&artists, query, escapeLike(search), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artists: %w", err)
+	}
+
+	return artists, nil
+}
+
+// GetUserNames returns distinct requester names matching the search term, for autocomplete
+func (r *PlaylistEntryRepository) GetUserNames(search string, limit int) ([]string, error) {
+	query := `
+		SELECT user_name
+		FROM playlist_entries
+		WHERE user_name ILIKE '%' || $1 || '%'
+		GROUP BY user_name
+		ORDER BY MIN(created_at) ASC
+		LIMIT $2
+	`
+
+	userNames := []string{}
+	err := r.db.Select(&userNames, query, escapeLike(search), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user names: %w", err)
+	}
+
+	return userNames, nil
+}
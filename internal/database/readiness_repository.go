@@ -0,0 +1,352 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nahue/playlists/internal/readiness"
+)
+
+// MemberReadiness is how ready a band member is to play a song. Status is
+// empty when the member has not said.
+type MemberReadiness struct {
+	BandSongID      int              `db:"band_song_id" json:"-"`
+	MemberID        int              `db:"band_member_id" json:"member_id"`
+	MemberName      string           `db:"member_name" json:"member_name"`
+	Status          readiness.Status `db:"status" json:"status"`
+	LastRehearsedAt *time.Time       `db:"last_rehearsed_at" json:"last_rehearsed_at"`
+	Notes           string           `db:"notes" json:"notes"`
+	UpdatedAt       *time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// SongReadiness is how ready a band is to play a catalog song. The song was
+// last rehearsed when a member last rehearsed it, or when a past rehearsal
+// had it on its agenda, whichever is later.
+type SongReadiness struct {
+	BandSongID        int               `db:"band_song_id" json:"band_song_id"`
+	Artist            string            `db:"artist" json:"artist"`
+	Title             string            `db:"title" json:"title"`
+	Members           []MemberReadiness `db:"-" json:"members"`
+	readiness.Summary `db:"-"`
+}
+
+// SetlistSongReadiness is a song of a playlist with how ready the band is to play it
+type SetlistSongReadiness struct {
+	PlaylistSongID int `db:"playlist_song_id" json:"playlist_song_id"`
+	PlaylistID     int `db:"playlist_id" json:"playlist_id"`
+	SongReadiness
+}
+
+// PlaylistReadiness is how ready a band is to play the songs of a playlist.
+// AtRisk counts the songs at risk.
+type PlaylistReadiness struct {
+	PlaylistID int                    `json:"playlist_id"`
+	Name       string                 `json:"name"`
+	Songs      []SetlistSongReadiness `json:"songs"`
+	AtRisk     int                    `json:"at_risk"`
+}
+
+// GigReadiness is how ready a band is to play the playlists of a gig, by
+// the time the gig starts. AtRisk counts the songs at risk.
+type GigReadiness struct {
+	Gig
+	Playlists []PlaylistReadiness `json:"playlists"`
+	AtRisk    int                 `json:"at_risk"`
+}
+
+// SetReadinessRequest represents the request to say how ready a band member
+// is to play a song. The member linked to the user's account is used when
+// MemberID is nil. LastRehearsedAt is kept when nil.
+type SetReadinessRequest struct {
+	MemberID        *int             `json:"member_id"`
+	Status          readiness.Status `json:"status"`
+	LastRehearsedAt *time.Time       `json:"last_rehearsed_at"`
+	Notes           string           `json:"notes"`
+}
+
+// ReadinessRepository handles database operations for how ready band
+// members are to play the band's songs
+type ReadinessRepository struct {
+	db *sqlx.DB
+}
+
+// NewReadinessRepository creates a new readiness repository
+func NewReadinessRepository(db *sqlx.DB) *ReadinessRepository {
+	return &ReadinessRepository{db: db}
+}
+
+// GetSongReadiness returns how ready each member of the band is to play a
+// catalog song
+func (r *ReadinessRepository) GetSongReadiness(songID, bandID, userID int) (*SongReadiness, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return getSongReadiness(r.db, songID, bandID)
+}
+
+// SetReadiness records how ready a band member is to play a catalog song.
+// Members answer for themselves; editors may answer for any member. It
+// returns ErrBandMemberNotFound when the user has no linked member or the
+// member is not the band's.
+func (r *ReadinessRepository) SetReadiness(songID, bandID, userID int, req SetReadinessRequest) (*SongReadiness, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	song, err := getBandSong(tx, songID, bandID)
+	if err != nil || song == nil {
+		return nil, err
+	}
+
+	memberID, err := authorizeMember(tx, bandID, userID, req.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO song_readiness (band_song_id, band_member_id, status, last_rehearsed_at, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (band_song_id, band_member_id)
+		DO UPDATE SET status = EXCLUDED.status,
+			last_rehearsed_at = COALESCE(EXCLUDED.last_rehearsed_at, song_readiness.last_rehearsed_at),
+			notes = EXCLUDED.notes, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err = tx.Exec(query, songID, memberID, req.Status, req.LastRehearsedAt, req.Notes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set song readiness: %w", err)
+	}
+
+	result, err := getSongReadiness(tx, songID, bandID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetPlaylistReadiness returns how ready the band is to play the songs of a
+// playlist now
+func (r *ReadinessRepository) GetPlaylistReadiness(playlistID, bandID, userID int) (*PlaylistReadiness, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var playlist BandPlaylist
+	err = r.db.Get(&playlist, `SELECT `+playlistColumns+` FROM band_playlists WHERE id = $1 AND band_id = $2`, playlistID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Playlist not found
+		}
+		return nil, fmt.Errorf("failed to get playlist: %w", err)
+	}
+
+	return getPlaylistReadiness(r.db, playlist, time.Now())
+}
+
+// GetReadinessReport returns how ready the band is to play the playlists of
+// each upcoming gig that is not cancelled, soonest first. Songs are assessed
+// for the time each gig starts.
+func (r *ReadinessRepository) GetReadinessReport(bandID, userID int) ([]GigReadiness, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + gigColumns + `
+		FROM gigs g
+		WHERE g.band_id = $1 AND g.status <> $2
+			AND (g.starts_at AT TIME ZONE g.timezone)::date >= (CURRENT_TIMESTAMP AT TIME ZONE g.timezone)::date
+		ORDER BY g.starts_at ASC, g.id ASC
+	`
+
+	gigs := []Gig{}
+	err = r.db.Select(&gigs, query, bandID, GigCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gigs: %w", err)
+	}
+
+	playlistQuery := `
+		SELECT ` + playlistColumns + `
+		FROM gig_playlists gp
+		JOIN band_playlists ON band_playlists.id = gp.playlist_id
+		WHERE gp.gig_id = $1
+		ORDER BY gp.position
+	`
+
+	report := make([]GigReadiness, 0, len(gigs))
+	for _, gig := range gigs {
+		gig.inTimezone()
+
+		playlists := []BandPlaylist{}
+		err = r.db.Select(&playlists, playlistQuery, gig.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gig playlists: %w", err)
+		}
+
+		entry := GigReadiness{Gig: gig, Playlists: []PlaylistReadiness{}}
+		for _, playlist := range playlists {
+			playlistReadiness, err := getPlaylistReadiness(r.db, playlist, gig.StartsAt)
+			if err != nil {
+				return nil, err
+			}
+			entry.Playlists = append(entry.Playlists, *playlistReadiness)
+			entry.AtRisk += playlistReadiness.AtRisk
+		}
+		report = append(report, entry)
+	}
+
+	return report, nil
+}
+
+// getSongReadiness returns how ready the band is to play a catalog song now,
+// without checking band access
+func getSongReadiness(q sqlx.Queryer, songID, bandID int) (*SongReadiness, error) {
+	var song SongReadiness
+	err := sqlx.Get(q, &song, `SELECT id AS band_song_id, artist, title FROM band_songs WHERE id = $1 AND band_id = $2`, songID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Song not found
+		}
+		return nil, fmt.Errorf("failed to get band song: %w", err)
+	}
+
+	err = assessSongs(q, []*SongReadiness{&song}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+// getPlaylistReadiness returns how ready the band is to play the songs of a
+// playlist at a given time, in playlist order
+func getPlaylistReadiness(q sqlx.Queryer, playlist BandPlaylist, at time.Time) (*PlaylistReadiness, error) {
+	query := `
+		SELECT s.id AS playlist_song_id, s.playlist_id, s.band_song_id, bs.artist, bs.title
+		FROM band_playlist_songs s
+		JOIN band_songs bs ON bs.id = s.band_song_id
+		LEFT JOIN band_playlist_sections sec ON sec.id = s.section_id
+		WHERE s.playlist_id = $1
+		ORDER BY sec.position ASC NULLS FIRST, sec.id ASC NULLS FIRST, s.position ASC, s.created_at ASC
+	`
+
+	songs := []SetlistSongReadiness{}
+	err := sqlx.Select(q, &songs, query, playlist.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist songs: %w", err)
+	}
+
+	assessed := make([]*SongReadiness, len(songs))
+	for i := range songs {
+		assessed[i] = &songs[i].SongReadiness
+	}
+	err = assessSongs(q, assessed, at)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlaylistReadiness{PlaylistID: playlist.ID, Name: playlist.Name, Songs: songs}
+	for _, song := range songs {
+		if song.AtRisk {
+			result.AtRisk++
+		}
+	}
+	return result, nil
+}
+
+// assessSongs fills in the members' statuses of catalog songs and how ready
+// the band is to play them at a given time
+func assessSongs(q sqlx.Queryer, songs []*SongReadiness, at time.Time) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	ids := make(pq.Int64Array, len(songs))
+	for i, song := range songs {
+		ids[i] = int64(song.BandSongID)
+	}
+
+	// Every member of the band is listed, with an empty status until they say
+	query := `
+		SELECT bs.id AS band_song_id, m.id AS band_member_id, m.name AS member_name,
+			COALESCE(sr.status, '') AS status, sr.last_rehearsed_at, COALESCE(sr.notes, '') AS notes, sr.updated_at
+		FROM band_songs bs
+		JOIN band_members m ON m.band_id = bs.band_id
+		LEFT JOIN song_readiness sr ON sr.band_song_id = bs.id AND sr.band_member_id = m.id
+		WHERE bs.id = ANY($1)
+		ORDER BY m.name, m.id
+	`
+	members := []MemberReadiness{}
+	err := sqlx.Select(q, &members, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get song readiness: %w", err)
+	}
+
+	query = `
+		SELECT s.band_song_id, MAX(r.starts_at) AS last_rehearsed_at
+		FROM rehearsal_agenda_items a
+		JOIN rehearsals r ON r.id = a.rehearsal_id
+		JOIN band_playlist_songs s ON s.id = a.playlist_song_id
+		WHERE s.band_song_id = ANY($1) AND r.status = $2 AND r.starts_at <= CURRENT_TIMESTAMP
+		GROUP BY s.band_song_id
+	`
+	var rehearsed []struct {
+		BandSongID      int       `db:"band_song_id"`
+		LastRehearsedAt time.Time `db:"last_rehearsed_at"`
+	}
+	err = sqlx.Select(q, &rehearsed, query, ids, RehearsalScheduled)
+	if err != nil {
+		return fmt.Errorf("failed to get song rehearsals: %w", err)
+	}
+
+	for _, song := range songs {
+		song.Members = []MemberReadiness{}
+		var statuses []readiness.Status
+		var lastRehearsed *time.Time
+		for _, member := range members {
+			if member.BandSongID != song.BandSongID {
+				continue
+			}
+			song.Members = append(song.Members, member)
+			if member.Status != "" {
+				statuses = append(statuses, member.Status)
+			}
+			lastRehearsed = later(lastRehearsed, member.LastRehearsedAt)
+		}
+		for _, band := range rehearsed {
+			if band.BandSongID == song.BandSongID {
+				lastRehearsed = later(lastRehearsed, &band.LastRehearsedAt)
+			}
+		}
+		song.Summary = readiness.Assess(statuses, len(song.Members), lastRehearsed, at)
+	}
+
+	return nil
+}
+
+// later returns the later of two optional times
+func later(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
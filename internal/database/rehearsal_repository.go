@@ -0,0 +1,782 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nahue/playlists/internal/availability"
+)
+
+// Rehearsal statuses
+const (
+	RehearsalPolling   = "polling"
+	RehearsalScheduled = "scheduled"
+	RehearsalCancelled = "cancelled"
+)
+
+// MaxRehearsalSlots limits how many candidate times a rehearsal is polled on
+const MaxRehearsalSlots = 50
+
+// defaultRehearsalDuration is how long a rehearsal lasts when no duration is given, in seconds
+const defaultRehearsalDuration = 2 * 60 * 60
+
+// RehearsalDetails holds what a band plans for a rehearsal. Duration is in
+// seconds and Timezone is the IANA zone its times are shown in.
+type RehearsalDetails struct {
+	Title    string `db:"title" json:"title"`
+	Location string `db:"location" json:"location"`
+	Timezone string `db:"timezone" json:"timezone"`
+	Duration int    `db:"duration" json:"duration"`
+	Notes    string `db:"notes" json:"notes"`
+}
+
+// Normalize validates the details, filling in a two hour duration when none
+// is given
+func (d *RehearsalDetails) Normalize() error {
+	_, err := loadTimezone(d.Timezone)
+	if err != nil {
+		return err
+	}
+
+	d.Title = strings.TrimSpace(d.Title)
+	d.Location = strings.TrimSpace(d.Location)
+
+	if d.Duration == 0 {
+		d.Duration = defaultRehearsalDuration
+	}
+	if d.Duration < 0 {
+		return fmt.Errorf("duration cannot be negative")
+	}
+	return nil
+}
+
+// Rehearsal is a rehearsal of a band. It is polled over candidate slots
+// until it is scheduled, which sets StartsAt and EndsAt.
+type Rehearsal struct {
+	ID     int `db:"id" json:"id"`
+	BandID int `db:"band_id" json:"band_id"`
+	RehearsalDetails
+	Status   string     `db:"status" json:"status"`
+	StartsAt *time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt   *time.Time `db:"-" json:"ends_at"`
+	// Sequence counts the changes made to the rehearsal since it was added
+	Sequence  int       `db:"sequence" json:"sequence"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// RehearsalSlot is a candidate start time of a rehearsal with the answers
+// band members gave for it
+type RehearsalSlot struct {
+	ID          int                `db:"id" json:"id"`
+	RehearsalID int                `db:"rehearsal_id" json:"rehearsal_id"`
+	StartsAt    time.Time          `db:"starts_at" json:"starts_at"`
+	Tally       availability.Tally `db:"-" json:"tally"`
+	Answers     []SlotAnswer       `db:"-" json:"answers"`
+}
+
+// SlotAnswer is a band member's answer for a slot
+type SlotAnswer struct {
+	SlotID     int                 `db:"slot_id" json:"-"`
+	MemberID   int                 `db:"band_member_id" json:"member_id"`
+	MemberName string              `db:"member_name" json:"member_name"`
+	Answer     availability.Answer `db:"answer" json:"answer"`
+}
+
+// AgendaItem is an item of a rehearsal's agenda: a song of one of the band's
+// playlists, or anything else named by Title. Duration is in seconds.
+type AgendaItem struct {
+	ID             int    `db:"id" json:"id"`
+	Position       int    `db:"position" json:"position"`
+	PlaylistSongID *int   `db:"playlist_song_id" json:"playlist_song_id"`
+	PlaylistID     *int   `db:"playlist_id" json:"playlist_id"`
+	Artist         string `db:"artist" json:"artist,omitempty"`
+	Song           string `db:"song" json:"song,omitempty"`
+	SongKey        string `db:"song_key" json:"song_key,omitempty"`
+	Title          string `db:"title" json:"title"`
+	Notes          string `db:"notes" json:"notes"`
+	Duration       *int   `db:"duration" json:"duration"`
+}
+
+// RehearsalWithPoll is a rehearsal with its candidate slots, the best of
+// them so far, and its agenda
+type RehearsalWithPoll struct {
+	Rehearsal
+	Slots      []RehearsalSlot `json:"slots"`
+	BestSlotID *int            `json:"best_slot_id"`
+	Agenda     []AgendaItem    `json:"agenda"`
+}
+
+// CreateRehearsalRequest represents the request to propose a rehearsal at
+// one of several candidate start times
+type CreateRehearsalRequest struct {
+	RehearsalDetails
+	Slots []time.Time `json:"slots"`
+}
+
+// UpdateRehearsalRequest represents the request to update a rehearsal
+type UpdateRehearsalRequest struct {
+	RehearsalDetails
+}
+
+// AddSlotRequest represents the request to add a candidate start time to a rehearsal
+type AddSlotRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+}
+
+// AvailabilityRequest represents a band member's answers for the slots of a
+// rehearsal. MemberID defaults to the member linked to the current user.
+type AvailabilityRequest struct {
+	MemberID *int               `json:"member_id"`
+	Answers  []SlotAvailability `json:"answers"`
+}
+
+// SlotAvailability is an answer for one slot
+type SlotAvailability struct {
+	SlotID int                 `json:"slot_id"`
+	Answer availability.Answer `json:"answer"`
+}
+
+// ScheduleRehearsalRequest represents the request to schedule a rehearsal at
+// one of its slots, the best one when SlotID is nil
+type ScheduleRehearsalRequest struct {
+	SlotID *int `json:"slot_id"`
+}
+
+// AgendaItemRequest is an item of a new agenda
+type AgendaItemRequest struct {
+	PlaylistSongID *int   `json:"playlist_song_id"`
+	Title          string `json:"title"`
+	Notes          string `json:"notes"`
+	Duration       *int   `json:"duration"`
+}
+
+// SetAgendaRequest represents the request to replace the agenda of a rehearsal
+type SetAgendaRequest struct {
+	Items []AgendaItemRequest `json:"items"`
+}
+
+const rehearsalColumns = `
+	r.id, r.band_id, r.title, r.location, r.timezone, r.duration, r.notes, r.status, r.starts_at,
+	r.sequence, r.created_at, r.updated_at`
+
+// RehearsalRepository handles database operations for band rehearsals and
+// the availability polls that schedule them
+type RehearsalRepository struct {
+	db *sqlx.DB
+}
+
+// NewRehearsalRepository creates a new rehearsal repository
+func NewRehearsalRepository(db *sqlx.DB) *RehearsalRepository {
+	return &RehearsalRepository{db: db}
+}
+
+// GetRehearsals returns the rehearsals of a band. Rehearsals still being
+// polled come first and count as upcoming; the others come in date order,
+// past ones most recent first.
+func (r *RehearsalRepository) GetRehearsals(bandID, userID int, period EventPeriod) ([]Rehearsal, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	filter, order := "", "r.starts_at ASC NULLS FIRST"
+	switch period {
+	case EventsUpcoming:
+		filter = `AND (r.starts_at IS NULL OR (r.starts_at AT TIME ZONE r.timezone)::date >= (CURRENT_TIMESTAMP AT TIME ZONE r.timezone)::date)`
+	case EventsPast:
+		filter = `AND (r.starts_at AT TIME ZONE r.timezone)::date < (CURRENT_TIMESTAMP AT TIME ZONE r.timezone)::date`
+		order = "r.starts_at DESC"
+	}
+
+	query := `
+		SELECT ` + rehearsalColumns + `
+		FROM rehearsals r
+		WHERE r.band_id = $1 ` + filter + `
+		ORDER BY ` + order + `, r.id ASC
+	`
+
+	rehearsals := []Rehearsal{}
+	err = r.db.Select(&rehearsals, query, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rehearsals: %w", err)
+	}
+
+	for i := range rehearsals {
+		rehearsals[i].inTimezone()
+	}
+	return rehearsals, nil
+}
+
+// GetRehearsal returns a rehearsal of the band with its poll and agenda
+func (r *RehearsalRepository) GetRehearsal(rehearsalID, bandID, userID int) (*RehearsalWithPoll, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	rehearsal, err := getRehearsal(r.db, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return nil, err
+	}
+
+	return getRehearsalPoll(r.db, rehearsal)
+}
+
+// CreateRehearsal proposes a rehearsal to the band at the given candidate
+// start times
+func (r *RehearsalRepository) CreateRehearsal(bandID, userID int, req CreateRehearsalRequest) (*RehearsalWithPoll, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO rehearsals (band_id, title, location, timezone, duration, notes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	var rehearsalID int
+	err = tx.Get(&rehearsalID, query, bandID, req.Title, req.Location, req.Timezone, req.Duration, req.Notes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rehearsal: %w", err)
+	}
+
+	for _, startsAt := range req.Slots {
+		_, err = addSlot(tx, rehearsalID, startsAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rehearsal, err := getRehearsal(tx, rehearsalID, bandID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := getRehearsalPoll(tx, rehearsal)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return poll, nil
+}
+
+// UpdateRehearsal updates the details of a rehearsal
+func (r *RehearsalRepository) UpdateRehearsal(rehearsalID, bandID, userID int, req UpdateRehearsalRequest) (*Rehearsal, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		UPDATE rehearsals r
+		SET title = $1, location = $2, timezone = $3, duration = $4, notes = $5,
+			sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE r.id = $6 AND r.band_id = $7
+		RETURNING ` + rehearsalColumns
+
+	var rehearsal Rehearsal
+	err = r.db.Get(&rehearsal, query, req.Title, req.Location, req.Timezone, req.Duration, req.Notes, rehearsalID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Rehearsal not found
+		}
+		return nil, fmt.Errorf("failed to update rehearsal: %w", err)
+	}
+
+	rehearsal.inTimezone()
+	return &rehearsal, nil
+}
+
+// CancelRehearsal calls off a rehearsal. It stays listed, and in calendars,
+// as cancelled.
+func (r *RehearsalRepository) CancelRehearsal(rehearsalID, bandID, userID int) (*Rehearsal, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	query := `
+		UPDATE rehearsals r
+		SET status = $1, sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE r.id = $2 AND r.band_id = $3
+		RETURNING ` + rehearsalColumns
+
+	var rehearsal Rehearsal
+	err = r.db.Get(&rehearsal, query, RehearsalCancelled, rehearsalID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Rehearsal not found
+		}
+		return nil, fmt.Errorf("failed to cancel rehearsal: %w", err)
+	}
+
+	rehearsal.inTimezone()
+	return &rehearsal, nil
+}
+
+// DeleteRehearsal removes a rehearsal of the band with its poll and agenda
+func (r *RehearsalRepository) DeleteRehearsal(rehearsalID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM rehearsals WHERE id = $1 AND band_id = $2`, rehearsalID, bandID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rehearsal: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// AddSlot adds a candidate start time to a rehearsal that is still being
+// polled. It returns ErrRehearsalClosed otherwise, and ErrTooManySlots when
+// the poll already has MaxRehearsalSlots slots.
+func (r *RehearsalRepository) AddSlot(rehearsalID, bandID, userID int, req AddSlotRequest) (*RehearsalSlot, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the rehearsal so concurrent additions cannot exceed the limit
+	_, err = tx.Exec(`SELECT id FROM rehearsals WHERE id = $1 AND band_id = $2 FOR UPDATE`, rehearsalID, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock rehearsal: %w", err)
+	}
+
+	rehearsal, err := getRehearsal(tx, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return nil, err
+	}
+	if rehearsal.Status != RehearsalPolling {
+		return nil, ErrRehearsalClosed
+	}
+
+	var count int
+	err = tx.Get(&count, `SELECT COUNT(*) FROM rehearsal_slots WHERE rehearsal_id = $1`, rehearsalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rehearsal slots: %w", err)
+	}
+	if count >= MaxRehearsalSlots {
+		return nil, ErrTooManySlots
+	}
+
+	slot, err := addSlot(tx, rehearsalID, req.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slot.localize(rehearsal.Timezone)
+	slot.Answers = []SlotAnswer{}
+	return slot, nil
+}
+
+// DeleteSlot removes a candidate start time, with its answers, from a
+// rehearsal that is still being polled. It returns ErrRehearsalClosed otherwise.
+func (r *RehearsalRepository) DeleteSlot(slotID, rehearsalID, bandID, userID int) (bool, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	rehearsal, err := getRehearsal(r.db, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return false, err
+	}
+	if rehearsal.Status != RehearsalPolling {
+		return false, ErrRehearsalClosed
+	}
+
+	result, err := r.db.Exec(`DELETE FROM rehearsal_slots WHERE id = $1 AND rehearsal_id = $2`, slotID, rehearsalID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rehearsal slot: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// SetAvailability records a band member's answers for slots of a rehearsal
+// that is still being polled. Members answer for themselves; editors may
+// answer for any member. It returns ErrBandMemberNotFound when the user has
+// no linked member or the member is not the band's, ErrSlotNotFound for
+// slots of other rehearsals and ErrRehearsalClosed once the poll is over.
+func (r *RehearsalRepository) SetAvailability(rehearsalID, bandID, userID int, req AvailabilityRequest) (*RehearsalWithPoll, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleViewer)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rehearsal, err := getRehearsal(tx, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return nil, err
+	}
+	if rehearsal.Status != RehearsalPolling {
+		return nil, ErrRehearsalClosed
+	}
+
+	memberID, err := authorizeMember(tx, bandID, userID, req.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO rehearsal_availability (slot_id, band_member_id, answer)
+		SELECT id, $1, $2 FROM rehearsal_slots WHERE id = $3 AND rehearsal_id = $4
+		ON CONFLICT (slot_id, band_member_id)
+		DO UPDATE SET answer = EXCLUDED.answer, updated_at = CURRENT_TIMESTAMP
+	`
+	for _, answer := range req.Answers {
+		result, err := tx.Exec(query, memberID, answer.Answer, answer.SlotID, rehearsalID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set availability: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rows == 0 {
+			return nil, ErrSlotNotFound
+		}
+	}
+
+	poll, err := getRehearsalPoll(tx, rehearsal)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return poll, nil
+}
+
+// ScheduleRehearsal closes the poll of a rehearsal and schedules it at one
+// of its slots, the best one when none is given. A scheduled rehearsal can
+// be moved to another slot the same way. It returns ErrSlotNotFound for
+// slots of other rehearsals, ErrNoAvailableSlot when no member can make any
+// slot and ErrRehearsalClosed when the rehearsal was cancelled.
+func (r *RehearsalRepository) ScheduleRehearsal(rehearsalID, bandID, userID int, req ScheduleRehearsalRequest) (*RehearsalWithPoll, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rehearsal, err := getRehearsal(tx, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return nil, err
+	}
+	if rehearsal.Status == RehearsalCancelled {
+		return nil, ErrRehearsalClosed
+	}
+
+	poll, err := getRehearsalPoll(tx, rehearsal)
+	if err != nil {
+		return nil, err
+	}
+
+	slotID := req.SlotID
+	if slotID == nil {
+		if poll.BestSlotID == nil {
+			return nil, ErrNoAvailableSlot
+		}
+		slotID = poll.BestSlotID
+	}
+
+	var startsAt *time.Time
+	for _, slot := range poll.Slots {
+		if slot.ID == *slotID {
+			startsAt = &slot.StartsAt
+		}
+	}
+	if startsAt == nil {
+		return nil, ErrSlotNotFound
+	}
+
+	query := `
+		UPDATE rehearsals
+		SET status = $1, starts_at = $2, sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+	_, err = tx.Exec(query, RehearsalScheduled, *startsAt, rehearsalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule rehearsal: %w", err)
+	}
+
+	rehearsal, err = getRehearsal(tx, rehearsalID, bandID)
+	if err != nil {
+		return nil, err
+	}
+	poll.Rehearsal = *rehearsal
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return poll, nil
+}
+
+// SetAgenda replaces the agenda of a rehearsal with the given items, in
+// order. It returns ErrPlaylistSongNotFound when a song is not in one of the
+// band's playlists.
+func (r *RehearsalRepository) SetAgenda(rehearsalID, bandID, userID int, req SetAgendaRequest) ([]AgendaItem, error) {
+	ok, err := authorizeBand(r.db, bandID, userID, BandRoleEditor)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rehearsal, err := getRehearsal(tx, rehearsalID, bandID)
+	if err != nil || rehearsal == nil {
+		return nil, err
+	}
+
+	songIDs := pq.Int64Array{}
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.PlaylistSongID != nil && !seen[*item.PlaylistSongID] {
+			seen[*item.PlaylistSongID] = true
+			songIDs = append(songIDs, int64(*item.PlaylistSongID))
+		}
+	}
+
+	query := `
+		SELECT COUNT(*)
+		FROM band_playlist_songs s
+		JOIN band_playlists p ON p.id = s.playlist_id
+		WHERE s.id = ANY($1) AND p.band_id = $2
+	`
+	var found int
+	err = tx.Get(&found, query, songIDs, bandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check playlist songs: %w", err)
+	}
+	if found != len(songIDs) {
+		return nil, ErrPlaylistSongNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM rehearsal_agenda_items WHERE rehearsal_id = $1`, rehearsalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear rehearsal agenda: %w", err)
+	}
+
+	query = `
+		INSERT INTO rehearsal_agenda_items (rehearsal_id, position, playlist_song_id, title, notes, duration)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for i, item := range req.Items {
+		_, err = tx.Exec(query, rehearsalID, i, item.PlaylistSongID, strings.TrimSpace(item.Title), item.Notes, item.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add agenda item: %w", err)
+		}
+	}
+
+	agenda, err := getAgenda(tx, rehearsalID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return agenda, nil
+}
+
+// getRehearsal returns a rehearsal of the band without checking band access
+func getRehearsal(q sqlx.Queryer, rehearsalID, bandID int) (*Rehearsal, error) {
+	query := `SELECT ` + rehearsalColumns + ` FROM rehearsals r WHERE r.id = $1 AND r.band_id = $2`
+
+	var rehearsal Rehearsal
+	err := sqlx.Get(q, &rehearsal, query, rehearsalID, bandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Rehearsal not found
+		}
+		return nil, fmt.Errorf("failed to get rehearsal: %w", err)
+	}
+
+	rehearsal.inTimezone()
+	return &rehearsal, nil
+}
+
+// getRehearsalPoll returns a rehearsal with its slots, the answers band
+// members gave and its agenda
+func getRehearsalPoll(q sqlx.Queryer, rehearsal *Rehearsal) (*RehearsalWithPoll, error) {
+	slots := []RehearsalSlot{}
+	err := sqlx.Select(q, &slots, `SELECT id, rehearsal_id, starts_at FROM rehearsal_slots WHERE rehearsal_id = $1 ORDER BY starts_at, id`, rehearsal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rehearsal slots: %w", err)
+	}
+
+	query := `
+		SELECT a.slot_id, a.band_member_id, m.name AS member_name, a.answer
+		FROM rehearsal_availability a
+		JOIN rehearsal_slots rs ON rs.id = a.slot_id
+		JOIN band_members m ON m.id = a.band_member_id
+		WHERE rs.rehearsal_id = $1
+		ORDER BY m.name, m.id
+	`
+	answers := []SlotAnswer{}
+	err = sqlx.Select(q, &answers, query, rehearsal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rehearsal availability: %w", err)
+	}
+
+	var members int
+	err = sqlx.Get(q, &members, `SELECT COUNT(*) FROM band_members WHERE band_id = $1`, rehearsal.BandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count band members: %w", err)
+	}
+
+	tallies := make([]availability.Tally, len(slots))
+	for i := range slots {
+		slot := &slots[i]
+		slot.localize(rehearsal.Timezone)
+		slot.Answers = []SlotAnswer{}
+		var given []availability.Answer
+		for _, answer := range answers {
+			if answer.SlotID == slot.ID {
+				slot.Answers = append(slot.Answers, answer)
+				given = append(given, answer.Answer)
+			}
+		}
+		slot.Tally = availability.Count(given, members)
+		tallies[i] = slot.Tally
+	}
+
+	agenda, err := getAgenda(q, rehearsal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll := &RehearsalWithPoll{Rehearsal: *rehearsal, Slots: slots, Agenda: agenda}
+	if best := availability.Best(tallies); best >= 0 {
+		poll.BestSlotID = &slots[best].ID
+	}
+	return poll, nil
+}
+
+// getAgenda returns the agenda of a rehearsal, with the details playlists
+// give its songs
+func getAgenda(q sqlx.Queryer, rehearsalID int) ([]AgendaItem, error) {
+	query := `
+		SELECT a.id, a.position, a.playlist_song_id, s.playlist_id,
+			COALESCE(bs.artist, '') AS artist, COALESCE(bs.title, '') AS song,
+			COALESCE(s.song_key, bs.song_key, '') AS song_key,
+			a.title, a.notes, a.duration
+		FROM rehearsal_agenda_items a
+		LEFT JOIN band_playlist_songs s ON s.id = a.playlist_song_id
+		LEFT JOIN band_songs bs ON bs.id = s.band_song_id
+		WHERE a.rehearsal_id = $1
+		ORDER BY a.position, a.id
+	`
+
+	agenda := []AgendaItem{}
+	err := sqlx.Select(q, &agenda, query, rehearsalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rehearsal agenda: %w", err)
+	}
+
+	return agenda, nil
+}
+
+// addSlot adds a candidate start time to a rehearsal, or returns the slot
+// already at that time
+func addSlot(q sqlx.Queryer, rehearsalID int, startsAt time.Time) (*RehearsalSlot, error) {
+	query := `
+		INSERT INTO rehearsal_slots (rehearsal_id, starts_at)
+		VALUES ($1, $2)
+		ON CONFLICT (rehearsal_id, starts_at) DO UPDATE SET starts_at = EXCLUDED.starts_at
+		RETURNING id, rehearsal_id, starts_at
+	`
+
+	var slot RehearsalSlot
+	err := sqlx.Get(q, &slot, query, rehearsalID, startsAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add rehearsal slot: %w", err)
+	}
+
+	return &slot, nil
+}
+
+// inTimezone shows the times of a rehearsal read from the database in its
+// timezone and works out when it ends
+func (r *Rehearsal) inTimezone() {
+	if r.StartsAt == nil {
+		return
+	}
+	location, err := time.LoadLocation(r.Timezone)
+	if err == nil {
+		*r.StartsAt = r.StartsAt.In(location)
+	}
+	endsAt := r.StartsAt.Add(time.Duration(r.Duration) * time.Second)
+	r.EndsAt = &endsAt
+}
+
+// localize shows the start of a slot in the timezone of its rehearsal
+func (s *RehearsalSlot) localize(timezone string) {
+	location, err := time.LoadLocation(timezone)
+	if err == nil {
+		s.StartsAt = s.StartsAt.In(location)
+	}
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Session represents a signed-in device
+type Session struct {
+	ID         int        `db:"id" json:"id"`
+	UserID     int        `db:"user_id" json:"user_id"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	LastUsedAt time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+	Current    bool       `db:"-" json:"current"`
+}
+
+// CreateSessionRequest describes the device a session is created for
+type CreateSessionRequest struct {
+	UserAgent string
+	IPAddress string
+}
+
+const sessionColumns = `id, user_id, user_agent, ip_address, last_used_at, expires_at, revoked_at, created_at, updated_at`
+
+// SessionRepository handles database operations for sessions, refresh tokens
+// and revoked access tokens
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession starts a session for the user with its first refresh token
+func (r *SessionRepository) CreateSession(userID int, req CreateSessionRequest, refreshToken string, expiresAt time.Time) (*Session, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO sessions (user_id, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + sessionColumns
+
+	var session Session
+	err = tx.Get(&session, query, userID, req.UserAgent, req.IPAddress, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO refresh_tokens (session_id, token_hash) VALUES ($1, $2)`, session.ID, hashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for newRefreshToken and extends
+// the session to expiresAt. It returns nil if the token is unknown or its
+// session has ended. Presenting a token that was already rotated revokes the
+// session and returns ErrRefreshTokenReused.
+func (r *SessionRepository) RotateRefreshToken(refreshToken, newRefreshToken string, expiresAt time.Time) (*Session, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var token struct {
+		ID        int        `db:"id"`
+		SessionID int        `db:"session_id"`
+		UsedAt    *time.Time `db:"used_at"`
+	}
+	err = tx.Get(&token, `SELECT id, session_id, used_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, hashToken(refreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Refresh token not found
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if token.UsedAt != nil {
+		_, err = tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, token.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	query := `
+		UPDATE sessions
+		SET last_used_at = CURRENT_TIMESTAMP, expires_at = $1
+		WHERE id = $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING ` + sessionColumns
+
+	var session Session
+	err = tx.Get(&session, query, expiresAt, token.SessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Session revoked or expired
+		}
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO refresh_tokens (session_id, token_hash) VALUES ($1, $2)`, session.ID, hashToken(newRefreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetActiveSessions returns the user's sessions that are neither revoked nor expired
+func (r *SessionRepository) GetActiveSessions(userID int) ([]Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC
+	`
+
+	sessions := []Session{}
+	err := r.db.Select(&sessions, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. It reports whether an
+// active session was revoked.
+func (r *SessionRepository) RevokeSession(sessionID, userID int) (bool, error) {
+	query := `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RevokeAllSessions ends every session of the user
+func (r *SessionRepository) RevokeAllSessions(userID int) error {
+	return revokeAllSessions(r.db, userID)
+}
+
+// RevokeOtherSessions ends every session of the user except the given one
+func (r *SessionRepository) RevokeOtherSessions(userID, sessionID int) error {
+	query := `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+
+	_, err := r.db.Exec(query, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// revokeAllSessions ends every session of the user
+func revokeAllSessions(e sqlx.Execer, userID int) error {
+	_, err := e.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken adds an access token's jti to the denylist until the
+// token expires. Entries past their expiry are pruned on the way.
+func (r *SessionRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err = r.db.Exec(query, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	return nil
+}
+
+// IsAccessTokenActive reports whether an access token with the given jti,
+// issued for the session and user, may still be used. Sessions of deleted
+// users are removed with them.
+func (r *SessionRepository) IsAccessTokenActive(sessionID, userID int, jti string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		) AND NOT EXISTS (
+			SELECT 1 FROM revoked_tokens WHERE jti = $3
+		)
+	`
+
+	var active bool
+	err := r.db.Get(&active, query, sessionID, userID, jti)
+	if err != nil {
+		return false, fmt.Errorf("failed to check access token: %w", err)
+	}
+
+	return active, nil
+}
+
+// IsTokenRevoked reports whether a token's jti is on the denylist, for
+// single-use tokens that are not tied to a session
+func (r *SessionRepository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.Get(&revoked, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return revoked, nil
+}
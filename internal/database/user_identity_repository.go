@@ -0,0 +1,195 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserIdentity represents an external OpenID Connect account linked to a user
+type UserIdentity struct {
+	ID          int        `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"user_id"`
+	Provider    string     `db:"provider" json:"provider"`
+	Subject     string     `db:"subject" json:"-"`
+	Email       string     `db:"email" json:"email"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// ExternalIdentity is what a provider asserted about the user signing in
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+const userIdentityColumns = `id, user_id, provider, subject, email, last_login_at, created_at, updated_at`
+
+// UserIdentityRepository handles database operations for linked external identities
+type UserIdentityRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *sqlx.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+// SignIn returns the user an external identity belongs to. An identity seen
+// for the first time is linked to the account with the same email address,
+// or to a new account if there is none. Linking requires the provider to
+// have verified the address (ErrIdentityEmailUnverified), and fails with
+// ErrIdentityConflict if the account already links another identity of the
+// same provider.
+func (r *UserIdentityRepository) SignIn(identity ExternalIdentity) (*UserResponse, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	query := `
+		UPDATE user_identities
+		SET last_login_at = CURRENT_TIMESTAMP, email = $3
+		WHERE provider = $1 AND subject = $2
+		RETURNING user_id
+	`
+	err = tx.Get(&userID, query, identity.Provider, identity.Subject, identity.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+
+	if err == sql.ErrNoRows {
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, ErrIdentityEmailUnverified
+		}
+
+		userID, err = findOrCreateIdentityUser(tx, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		linkQuery := `
+			INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+			ON CONFLICT (user_id, provider) DO NOTHING
+		`
+		result, err := tx.Exec(linkQuery, userID, identity.Provider, identity.Subject, identity.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link user identity: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return nil, ErrIdentityConflict
+		}
+
+		// The provider vouched for the address
+		if _, err = markEmailVerified(tx, userID, identity.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	var user User
+	err = tx.Get(&user, `SELECT id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}, nil
+}
+
+// GetIdentities returns the identities linked to the user
+func (r *UserIdentityRepository) GetIdentities(userID int) ([]UserIdentity, error) {
+	query := `
+		SELECT ` + userIdentityColumns + `
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY provider ASC
+	`
+
+	identities := []UserIdentity{}
+	err := r.db.Select(&identities, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// DeleteIdentity unlinks one of the user's identities. It reports whether an identity was unlinked.
+func (r *UserIdentityRepository) DeleteIdentity(identityID, userID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, identityID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user identity: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// findOrCreateIdentityUser returns the user with the identity's email address,
+// creating one if needed. New accounts get a random password, which the user
+// can replace through a password reset.
+func findOrCreateIdentityUser(tx *sqlx.Tx, identity ExternalIdentity) (int, error) {
+	var userID int
+	err := tx.Get(&userID, `SELECT id FROM users WHERE LOWER(email) = LOWER($1) FOR UPDATE`, identity.Email)
+	if err == nil {
+		return userID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return 0, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	query := `
+		INSERT INTO users (first_name, last_name, email, password_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	err = tx.Get(&userID, query, strings.TrimSpace(identity.FirstName), strings.TrimSpace(identity.LastName), identity.Email, string(hashedPassword))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return userID, nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -11,23 +12,25 @@ import (
 
 // User represents a user in the database
 type User struct {
-	ID           int       `db:"id" json:"id"`
-	FirstName    string    `db:"first_name" json:"first_name"`
-	LastName     string    `db:"last_name" json:"last_name"`
-	Email        string    `db:"email" json:"email"`
-	PasswordHash string    `db:"password_hash" json:"-"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ID              int        `db:"id" json:"id"`
+	FirstName       string     `db:"first_name" json:"first_name"`
+	LastName        string     `db:"last_name" json:"last_name"`
+	Email           string     `db:"email" json:"email"`
+	PasswordHash    string     `db:"password_hash" json:"-"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 // UserResponse represents a user response without sensitive data
 type UserResponse struct {
-	ID        int       `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              int        `json:"id"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // nil until the address is confirmed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // CreateUserRequest represents the request to create a new user
@@ -45,6 +48,23 @@ type UpdateUserRequest struct {
 	Email     string `json:"email"`
 }
 
+// BandPolicy decides what happens to the bands a deleted account is the only owner of
+type BandPolicy string
+
+const (
+	// BandPolicyTransfer makes the band's longest-standing admin its owner
+	BandPolicyTransfer BandPolicy = "transfer"
+	// BandPolicyDelete deletes the band with its members and playlists
+	BandPolicyDelete BandPolicy = "delete"
+)
+
+// DeleteAccountRequest represents the request to delete the current account
+type DeleteAccountRequest struct {
+	Password   string     `json:"password"`
+	BandPolicy BandPolicy `json:"band_policy,omitempty"`
+	MFACodeRequest
+}
+
 // LoginRequest represents the login request
 type LoginRequest struct {
 	Email    string `json:"email"`
@@ -82,7 +102,7 @@ func (r *UserRepository) CreateUser(req CreateUserRequest) (*UserResponse, error
 	query := `
 		INSERT INTO users (first_name, last_name, email, password_hash)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, first_name, last_name, email, password_hash, created_at, updated_at
+		RETURNING id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at
 	`
 
 	var user User
@@ -92,19 +112,20 @@ func (r *UserRepository) CreateUser(req CreateUserRequest) (*UserResponse, error
 	}
 
 	return &UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}, nil
 }
 
 // GetUserByID returns a user by ID
 func (r *UserRepository) GetUserByID(userID int) (*UserResponse, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password_hash, created_at, updated_at
+		SELECT id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -119,19 +140,20 @@ func (r *UserRepository) GetUserByID(userID int) (*UserResponse, error) {
 	}
 
 	return &UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}, nil
 }
 
 // GetUserByEmail returns a user by email
 func (r *UserRepository) GetUserByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password_hash, created_at, updated_at
+		SELECT id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -167,16 +189,19 @@ func (r *UserRepository) AuthenticateUser(req LoginRequest) (*UserResponse, erro
 	}
 
 	return &UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}, nil
 }
 
-// UpdateUser updates a user's information
+// UpdateUser updates a user's information. Empty fields are left unchanged.
+// Changing the email address marks it as unverified again and deletes the
+// password reset tokens mailed to the old one.
 func (r *UserRepository) UpdateUser(userID int, req UpdateUserRequest) (*UserResponse, error) {
 	// First, check if the user exists
 	user, err := r.GetUserByID(userID)
@@ -198,19 +223,29 @@ func (r *UserRepository) UpdateUser(userID int, req UpdateUserRequest) (*UserRes
 		}
 	}
 
-	// Build dynamic query based on provided fields
+	// Empty strings keep the current values
 	query := `
 		UPDATE users
-		SET first_name = COALESCE($1, first_name),
-			last_name = COALESCE($2, last_name),
-			email = COALESCE($3, email),
+		SET first_name = COALESCE(NULLIF($1, ''), first_name),
+			last_name = COALESCE(NULLIF($2, ''), last_name),
+			email = COALESCE(NULLIF($3, ''), email),
+			email_verified_at = CASE
+				WHEN NULLIF($3, '') IS NOT NULL AND LOWER($3) <> LOWER(email) THEN NULL
+				ELSE email_verified_at
+			END,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = $4
-		RETURNING id, first_name, last_name, email, password_hash, created_at, updated_at
+		RETURNING id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at
 	`
 
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var updatedUser User
-	err = r.db.Get(&updatedUser, query, req.FirstName, req.LastName, req.Email, userID)
+	err = tx.Get(&updatedUser, query, req.FirstName, req.LastName, req.Email, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -218,16 +253,44 @@ func (r *UserRepository) UpdateUser(userID int, req UpdateUserRequest) (*UserRes
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// Reset links mailed to the old address stop working
+	if !strings.EqualFold(updatedUser.Email, user.Email) {
+		_, err = tx.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`, userID, TokenPasswordReset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete reset tokens: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &UserResponse{
-		ID:        updatedUser.ID,
-		FirstName: updatedUser.FirstName,
-		LastName:  updatedUser.LastName,
-		Email:     updatedUser.Email,
-		CreatedAt: updatedUser.CreatedAt,
-		UpdatedAt: updatedUser.UpdatedAt,
+		ID:              updatedUser.ID,
+		FirstName:       updatedUser.FirstName,
+		LastName:        updatedUser.LastName,
+		Email:           updatedUser.Email,
+		EmailVerifiedAt: updatedUser.EmailVerifiedAt,
+		CreatedAt:       updatedUser.CreatedAt,
+		UpdatedAt:       updatedUser.UpdatedAt,
 	}, nil
 }
 
+// VerifyPassword reports whether password is the user's current password
+func (r *UserRepository) VerifyPassword(userID int, password string) (bool, error) {
+	var passwordHash string
+	err := r.db.Get(&passwordHash, `SELECT password_hash FROM users WHERE id = $1`, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil, nil
+}
+
 // UpdatePassword updates a user's password
 func (r *UserRepository) UpdatePassword(userID int, newPassword string) error {
 	// Hash new password
@@ -260,6 +323,33 @@ func (r *UserRepository) UpdatePassword(userID int, newPassword string) error {
 	return nil
 }
 
+// MarkEmailVerified records that the user confirmed email, as long as it is
+// still their address. It reports whether the user was updated.
+func (r *UserRepository) MarkEmailVerified(userID int, email string) (bool, error) {
+	return markEmailVerified(r.db, userID, email)
+}
+
+// markEmailVerified sets email_verified_at if email is still the user's address
+func markEmailVerified(e sqlx.Execer, userID int, email string) (bool, error) {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND LOWER(email) = LOWER($2)
+	`
+
+	result, err := e.Exec(query, userID, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
 // DeleteUser deletes a user
 func (r *UserRepository) DeleteUser(userID int) error {
 	query := `DELETE FROM users WHERE id = $1`
@@ -281,10 +371,116 @@ func (r *UserRepository) DeleteUser(userID int) error {
 	return nil
 }
 
+// DeleteAccount deletes a user after settling the bands they are the only
+// owner of according to policy. Without a policy the account is only deleted
+// if there are no such bands; otherwise ErrBandPolicyRequired is returned.
+// Bands with other owners are kept. It reports whether a user was deleted.
+func (r *UserRepository) DeleteAccount(userID int, policy BandPolicy) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Bands where the user is the only owner
+	query := `
+		SELECT b.id, b.name
+		FROM bands b
+		JOIN band_users bu ON bu.band_id = b.id AND bu.user_id = $1 AND bu.role = 'owner'
+		WHERE NOT EXISTS (
+			SELECT 1 FROM band_users o
+			WHERE o.band_id = b.id AND o.role = 'owner' AND o.user_id <> $1
+		)
+		ORDER BY b.name
+		FOR UPDATE OF b
+	`
+
+	var bands []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	err = tx.Select(&bands, query, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get owned bands: %w", err)
+	}
+
+	if len(bands) > 0 && policy == "" {
+		names := make([]string, len(bands))
+		for i, band := range bands {
+			names[i] = band.Name
+		}
+		return false, fmt.Errorf("%w: %s", ErrBandPolicyRequired, strings.Join(names, ", "))
+	}
+
+	for _, band := range bands {
+		switch policy {
+		case BandPolicyDelete:
+			_, err = tx.Exec(`DELETE FROM bands WHERE id = $1`, band.ID)
+			if err != nil {
+				return false, fmt.Errorf("failed to delete band: %w", err)
+			}
+		case BandPolicyTransfer:
+			result, err := tx.Exec(`
+				UPDATE band_users SET role = 'owner', updated_at = CURRENT_TIMESTAMP
+				WHERE band_id = $1 AND user_id = (
+					SELECT user_id FROM band_users
+					WHERE band_id = $1 AND role = 'admin' AND user_id <> $2
+					ORDER BY created_at ASC, user_id ASC
+					LIMIT 1
+				)
+			`, band.ID, userID)
+			if err != nil {
+				return false, fmt.Errorf("failed to transfer band: %w", err)
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return false, fmt.Errorf("failed to get rows affected: %w", err)
+			}
+			if rowsAffected == 0 {
+				return false, fmt.Errorf("%w: %s", ErrNoTransferTarget, band.Name)
+			}
+		default:
+			return false, fmt.Errorf("unknown band policy %q", policy)
+		}
+	}
+
+	// bands.user_id cascades on delete, so kept bands the user created are
+	// handed to one of their remaining owners first
+	_, err = tx.Exec(`
+		UPDATE bands b
+		SET user_id = (
+			SELECT user_id FROM band_users
+			WHERE band_id = b.id AND role = 'owner' AND user_id <> $1
+			ORDER BY created_at ASC, user_id ASC
+			LIMIT 1
+		)
+		WHERE b.user_id = $1
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to reassign bands: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
 // GetAllUsers returns all users (for admin purposes)
 func (r *UserRepository) GetAllUsers() ([]UserResponse, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password_hash, created_at, updated_at
+		SELECT id, first_name, last_name, email, password_hash, email_verified_at, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -298,12 +494,13 @@ func (r *UserRepository) GetAllUsers() ([]UserResponse, error) {
 	var responses []UserResponse
 	for _, user := range users {
 		responses = append(responses, UserResponse{
-			ID:        user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:              user.ID,
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			Email:           user.Email,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
 		})
 	}
 
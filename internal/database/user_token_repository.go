@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenPurpose is what a single-use user token may be redeemed for
+type TokenPurpose string
+
+const (
+	// TokenPasswordReset allows setting a new password without the old one
+	TokenPasswordReset TokenPurpose = "password_reset"
+	// TokenEmailVerification confirms that the user controls their email address
+	TokenEmailVerification TokenPurpose = "email_verification"
+)
+
+// UserToken represents a single-use token emailed to a user
+type UserToken struct {
+	ID        int          `db:"id" json:"id"`
+	UserID    int          `db:"user_id" json:"user_id"`
+	Purpose   TokenPurpose `db:"purpose" json:"purpose"`
+	Email     string       `db:"email" json:"email"`
+	ExpiresAt time.Time    `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time   `db:"used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+// UserTokenRepository handles database operations for password reset and
+// email verification tokens. Only hashes of the tokens are stored.
+type UserTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserTokenRepository creates a new user token repository
+func NewUserTokenRepository(db *sqlx.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// CreateToken stores a token for the user, issued for the given email address
+func (r *UserTokenRepository) CreateToken(userID int, purpose TokenPurpose, email, token string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(query, userID, purpose, email, hashToken(token), expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+
+	return nil
+}
+
+// CountRecentTokens returns how many tokens were issued for the email address
+// and purpose since the given time, for throttling
+func (r *UserTokenRepository) CountRecentTokens(email string, purpose TokenPurpose, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM user_tokens
+		WHERE LOWER(email) = LOWER($1) AND purpose = $2 AND created_at > $3
+	`
+
+	var count int
+	err := r.db.Get(&count, query, email, purpose, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// ResetPassword redeems a password reset token and sets the new password.
+// All of the user's sessions and outstanding reset tokens are revoked, and
+// the email address counts as verified. It returns nil if the token is
+// unknown, used or expired.
+func (r *UserTokenRepository) ResetPassword(token, newPassword string) (*UserToken, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userToken, err := consumeToken(tx, TokenPasswordReset, token)
+	if err != nil || userToken == nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, string(hashedPassword), userToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userToken.UserID, TokenPasswordReset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke reset tokens: %w", err)
+	}
+
+	if err = revokeAllSessions(tx, userToken.UserID); err != nil {
+		return nil, err
+	}
+
+	// Receiving the reset link proves control of the address as well
+	if _, err = markEmailVerified(tx, userToken.UserID, userToken.Email); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userToken, nil
+}
+
+// VerifyEmail redeems an email verification token. It returns nil if the
+// token is unknown, used or expired, or if the user has since changed their
+// email address.
+func (r *UserTokenRepository) VerifyEmail(token string) (*UserToken, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userToken, err := consumeToken(tx, TokenEmailVerification, token)
+	if err != nil || userToken == nil {
+		return nil, err
+	}
+
+	verified, err := markEmailVerified(tx, userToken.UserID, userToken.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, nil // Address changed since the token was issued
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return userToken, nil
+}
+
+// consumeToken marks a valid token as used and returns it, or nil if there is none
+func consumeToken(tx *sqlx.Tx, purpose TokenPurpose, token string) (*UserToken, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id, user_id, purpose, email, expires_at, used_at, created_at
+	`
+
+	var userToken UserToken
+	err := tx.Get(&userToken, query, hashToken(token), purpose)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Token not found
+		}
+		return nil, fmt.Errorf("failed to use token: %w", err)
+	}
+
+	return &userToken, nil
+}
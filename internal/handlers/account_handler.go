@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/mailer"
+)
+
+const (
+	// passwordResetTTL is how long a password reset link stays valid
+	passwordResetTTL = time.Hour
+	// emailVerificationTTL is how long an email verification link stays valid
+	emailVerificationTTL = 48 * time.Hour
+	// maxEmailsPerHour limits how many links of each kind are mailed to one address per hour
+	maxEmailsPerHour = 3
+)
+
+// errEmailThrottled is returned when too many links were mailed to an address recently
+var errEmailThrottled = errors.New("too many emails sent to this address")
+
+// EmailRequest carries an email address in a request body
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest represents the request to confirm an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangePasswordRequest represents the request to change the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// AccountHandler handles HTTP requests for account self-service: profile
+// changes, passwords, email verification and account deletion
+type AccountHandler struct {
+	userRepo    *database.UserRepository
+	tokenRepo   *database.UserTokenRepository
+	sessionRepo *database.SessionRepository
+	mfaRepo     *database.MFARepository
+	mailer      mailer.Mailer
+	logger      *log.Logger
+	appURL      string
+}
+
+// NewAccountHandler creates a new AccountHandler. Emailed links point at appURL.
+func NewAccountHandler(userRepo *database.UserRepository, tokenRepo *database.UserTokenRepository, sessionRepo *database.SessionRepository, mfaRepo *database.MFARepository, m mailer.Mailer, logger *log.Logger, appURL string) *AccountHandler {
+	return &AccountHandler{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		mfaRepo:     mfaRepo,
+		mailer:      m,
+		logger:      logger,
+		appURL:      strings.TrimRight(appURL, "/"),
+	}
+}
+
+// UpdateProfile updates the current user's name and email address. A new
+// address must be verified again.
+func (h *AccountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.UpdateUserRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate fields; empty ones are left unchanged
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.userRepo.GetUserByID(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get user: %v", err)
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	if current == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	user, err := h.userRepo.UpdateUser(userID, req)
+	if err != nil {
+		if strings.Contains(err.Error(), "email already exists") {
+			http.Error(w, "Email already exists", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to update user: %v", err)
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(current.Email, user.Email) {
+		err = h.sendVerificationEmail(r.Context(), *user)
+		if err != nil {
+			h.logger.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ChangePassword changes the current user's password after checking the
+// current one, and signs out every other session
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims := currentClaims(r.Context())
+
+	var req ChangePasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.userRepo.VerifyPassword(claims.UserID, req.CurrentPassword)
+	if err != nil {
+		h.logger.Printf("Failed to verify password: %v", err)
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	err = h.userRepo.UpdatePassword(claims.UserID, req.NewPassword)
+	if err != nil {
+		h.logger.Printf("Failed to update password: %v", err)
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.sessionRepo.RevokeOtherSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		h.logger.Printf("Failed to revoke other sessions: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been changed"})
+}
+
+// DeleteAccount deletes the current user after re-authentication with the
+// password and, when TOTP is enabled, a second factor. Bands the user is the
+// only owner of are transferred or deleted according to band_policy.
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.DeleteAccountRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+	if req.BandPolicy != "" && req.BandPolicy != database.BandPolicyTransfer && req.BandPolicy != database.BandPolicyDelete {
+		http.Error(w, "Band policy must be transfer or delete", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.userRepo.VerifyPassword(userID, req.Password)
+	if err != nil {
+		h.logger.Printf("Failed to verify password: %v", err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get TOTP settings: %v", err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	if userTOTP.Enabled() {
+		if req.Code == "" && req.RecoveryCode == "" {
+			http.Error(w, "A two-factor code is required", http.StatusUnauthorized)
+			return
+		}
+		if !writeSecondFactorResult(w, h.logger, h.mfaRepo, userTOTP, req.MFACodeRequest) {
+			return
+		}
+	}
+
+	deleted, err := h.userRepo.DeleteAccount(userID, req.BandPolicy)
+	if err != nil {
+		if errors.Is(err, database.ErrBandPolicyRequired) || errors.Is(err, database.ErrNoTransferTarget) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to delete account: %v", err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the address belongs to an account.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		h.logger.Printf("Failed to get user by email: %v", err)
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	if user != nil {
+		err = h.sendToken(r.Context(), user.ID, user.Email, database.TokenPasswordReset)
+		if err != nil {
+			h.logger.Printf("Failed to send password reset to user %d: %v", user.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the address belongs to an account, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token and signs the user
+// out everywhere
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := h.tokenRepo.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		h.logger.Printf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if userToken == nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been reset"})
+}
+
+// VerifyEmail confirms the user's email address with a verification token
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := h.tokenRepo.VerifyEmail(req.Token)
+	if err != nil {
+		h.logger.Printf("Failed to verify email: %v", err)
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	if userToken == nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email has been verified"})
+}
+
+// ResendVerification emails the authenticated user a new verification link
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	user, err := h.userRepo.GetUserByID(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get user: %v", err)
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	err = h.sendToken(r.Context(), user.ID, user.Email, database.TokenEmailVerification)
+	if err != nil {
+		if errors.Is(err, errEmailThrottled) {
+			http.Error(w, "Too many emails sent, try again later", http.StatusTooManyRequests)
+			return
+		}
+		h.logger.Printf("Failed to send verification email: %v", err)
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail mails a verification link to a newly registered user
+func (h *AccountHandler) sendVerificationEmail(ctx context.Context, user database.UserResponse) error {
+	return h.sendToken(ctx, user.ID, user.Email, database.TokenEmailVerification)
+}
+
+// sendToken issues a single-use token for the purpose and emails its link,
+// unless the address has received too many recently
+func (h *AccountHandler) sendToken(ctx context.Context, userID int, email string, purpose database.TokenPurpose) error {
+	count, err := h.tokenRepo.CountRecentTokens(email, purpose, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= maxEmailsPerHour {
+		return errEmailThrottled
+	}
+
+	token, err := generateRandomString(32)
+	if err != nil {
+		return err
+	}
+
+	var msg mailer.Message
+	switch purpose {
+	case database.TokenPasswordReset:
+		link := h.appURL + "/reset-password?token=" + url.QueryEscape(token)
+		msg = mailer.Message{
+			To:      email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Someone asked to reset the password of your Playlists account.\n\nChoose a new password here:\n%s\n\nThis link expires in one hour. If you did not ask for it, you can ignore this email.\n",
+				link),
+		}
+		err = h.tokenRepo.CreateToken(userID, purpose, email, token, time.Now().Add(passwordResetTTL))
+	case database.TokenEmailVerification:
+		link := h.appURL + "/verify-email?token=" + url.QueryEscape(token)
+		msg = mailer.Message{
+			To:      email,
+			Subject: "Confirm your email address",
+			Body:    fmt.Sprintf("Confirm the email address of your Playlists account here:\n%s\n\nThis link expires in 48 hours.\n", link),
+		}
+		err = h.tokenRepo.CreateToken(userID, purpose, email, token, time.Now().Add(emailVerificationTTL))
+	default:
+		return fmt.Errorf("unknown token purpose %q", purpose)
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, msg)
+}
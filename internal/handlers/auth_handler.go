@@ -2,36 +2,97 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nahue/playlists/internal/database"
 )
 
+// User is the public view of a user returned with a token
+type User struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+// AuthResponse is returned by successful register, login and refresh requests
+type AuthResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+	User         User   `json:"user"`
+}
+
+// MFAChallenge is returned by login instead of tokens when the account has
+// two-factor authentication enabled. The MFA token is exchanged for tokens
+// together with a code at /auth/login/mfa.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"` // MFA token lifetime in seconds
+}
+
+// LoginMFARequest represents the second step of a login with two-factor authentication
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	database.MFACodeRequest
+}
+
+// MFAClaims are the claims of the short-lived token issued between the
+// password and the second factor of a login
+type MFAClaims struct {
+	UserID int `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// RefreshRequest represents the request to exchange a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // AuthHandler handles HTTP requests for authentication operations
 type AuthHandler struct {
-	userRepo *database.UserRepository
-	logger   *log.Logger
+	userRepo       *database.UserRepository
+	sessionRepo    *database.SessionRepository
+	invitationRepo *database.BandInvitationRepository
+	mfaRepo        *database.MFARepository
+	patRepo        *database.PersonalAccessTokenRepository
+	accounts       *AccountHandler
+	jwtConfig      JWTConfig
+	logger         *log.Logger
 }
 
-// NewAuthHandler creates a new AuthHandler with the given repository and logger
-func NewAuthHandler(userRepo *database.UserRepository, logger *log.Logger) *AuthHandler {
+// NewAuthHandler creates a new AuthHandler with the given repositories, token settings and logger.
+// New accounts get their verification email through accounts.
+func NewAuthHandler(userRepo *database.UserRepository, sessionRepo *database.SessionRepository, invitationRepo *database.BandInvitationRepository, mfaRepo *database.MFARepository, patRepo *database.PersonalAccessTokenRepository, accounts *AccountHandler, jwtConfig JWTConfig, logger *log.Logger) *AuthHandler {
 	return &AuthHandler{
-		userRepo: userRepo,
-		logger:   logger,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		invitationRepo: invitationRepo,
+		mfaRepo:        mfaRepo,
+		patRepo:        patRepo,
+		accounts:       accounts,
+		jwtConfig:      jwtConfig,
+		logger:         logger,
 	}
 }
 
-// Register creates a new user account
+// Register creates a new user account. If an invitation token is given the
+// new account joins the invited band.
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var req database.CreateUserRequest
+	var req struct {
+		database.CreateUserRequest
+		InvitationToken string `json:"invitation_token,omitempty"`
+	}
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -44,8 +105,33 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check the invitation before creating the account so a bad link fails cleanly
+	var invitation *InvitationClaims
+	if req.InvitationToken != "" {
+		invitation, err = parseInvitationToken(h.jwtConfig, req.InvitationToken)
+		if err != nil {
+			http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+			return
+		}
+
+		pending, err := h.invitationRepo.GetInvitationByToken(invitation.InvitationID, invitation.ID)
+		if err != nil {
+			h.logger.Printf("Failed to get invitation: %v", err)
+			http.Error(w, "Error creating user", http.StatusInternalServerError)
+			return
+		}
+		if pending == nil || pending.Status != database.InvitationPending {
+			http.Error(w, "Invitation is no longer valid", http.StatusGone)
+			return
+		}
+		if !strings.EqualFold(pending.Email, req.Email) {
+			http.Error(w, "Invitation was sent to a different email address", http.StatusForbidden)
+			return
+		}
+	}
+
 	// Create user
-	user, err := h.userRepo.CreateUser(req)
+	user, err := h.userRepo.CreateUser(req.CreateUserRequest)
 	if err != nil {
 		h.logger.Printf("Failed to create user: %v", err)
 		if strings.Contains(err.Error(), "email already exists") {
@@ -56,30 +142,44 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.generateJWT(*user)
+	// The account exists either way; a failed accept can be retried after logging in
+	verified := false
+	if invitation != nil {
+		_, err = h.invitationRepo.AcceptInvitation(invitation.InvitationID, invitation.ID, user.ID)
+		if err != nil {
+			h.logger.Printf("Failed to accept invitation %d: %v", invitation.InvitationID, err)
+		} else {
+			// The invitation link was mailed to this address, which proves control of it
+			verified, err = h.userRepo.MarkEmailVerified(user.ID, user.Email)
+			if err != nil {
+				h.logger.Printf("Failed to mark email verified: %v", err)
+			}
+		}
+	}
+
+	if !verified {
+		err = h.accounts.sendVerificationEmail(r.Context(), *user)
+		if err != nil {
+			h.logger.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	// Start a session for the new account
+	response, err := h.startSession(r, *user)
 	if err != nil {
-		h.logger.Printf("Failed to generate JWT: %v", err)
+		h.logger.Printf("Failed to start session: %v", err)
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
-		User: User{
-			ID:        user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		},
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
-// Login authenticates a user and returns a JWT token
+// Login authenticates a user and starts a session, returning an access and a
+// refresh token. Accounts with two-factor authentication get an MFAChallenge
+// instead, to be completed at LoginMFA.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req database.LoginRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -102,22 +202,94 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.generateJWT(*user)
+	// Start a session for this device, unless a second factor is needed first
+	response, challenge, err := h.completeLogin(r, *user)
 	if err != nil {
-		h.logger.Printf("Failed to generate JWT: %v", err)
+		h.logger.Printf("Failed to start session: %v", err)
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
-		User: User{
-			ID:        user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		},
+	w.Header().Set("Content-Type", "application/json")
+	if challenge != nil {
+		json.NewEncoder(w).Encode(challenge)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// LoginMFA completes a login started with a password by checking a TOTP or
+// recovery code, then starts the session. Each MFA token works only once.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		http.Error(w, "MFA token and a code are required", http.StatusBadRequest)
+		return
+	}
+
+	claims := &MFAClaims{}
+	err = h.jwtConfig.parse(req.MFAToken, claims, mfaAudience)
+	if err != nil {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := h.sessionRepo.IsTokenRevoked(claims.ID)
+	if err != nil {
+		h.logger.Printf("Failed to check MFA token: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	if revoked {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	userTOTP, err := h.mfaRepo.GetTOTP(claims.UserID)
+	if err != nil {
+		h.logger.Printf("Failed to get TOTP settings: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	// TOTP may have been disabled since the password step
+	if !userTOTP.Enabled() {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	if !writeSecondFactorResult(w, h.logger, h.mfaRepo, userTOTP, req.MFACodeRequest) {
+		return
+	}
+
+	err = h.sessionRepo.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		h.logger.Printf("Failed to revoke MFA token: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.userRepo.GetUserByID(claims.UserID)
+	if err != nil || user == nil {
+		h.logger.Printf("Failed to get user: %v", err)
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	// Start a session for this device
+	response, err := h.startSession(r, *user)
+	if err != nil {
+		h.logger.Printf("Failed to start session: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -126,7 +298,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 // GetProfile returns the current user's profile
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 
 	user, err := h.userRepo.GetUserByID(userID)
 	if err != nil {
@@ -144,15 +316,149 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-// Logout (client-side token removal, but we can blacklist tokens here if needed)
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token can only be used once.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate refresh token: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := h.sessionRepo.RotateRefreshToken(req.RefreshToken, refreshToken, time.Now().Add(h.jwtConfig.RefreshTTL))
+	if err != nil {
+		if errors.Is(err, database.ErrRefreshTokenReused) {
+			h.logger.Printf("Refresh token reuse detected, session revoked")
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		h.logger.Printf("Failed to rotate refresh token: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	if session == nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.userRepo.GetUserByID(session.UserID)
+	if err != nil || user == nil {
+		h.logger.Printf("Failed to get session user: %v", err)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := h.newAuthResponse(*user, session.ID, refreshToken)
+	if err != nil {
+		h.logger.Printf("Failed to generate JWT: %v", err)
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Logout ends the current session and revokes the access token used for the request
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// For now, just return success - client should remove the token
-	// In a production system, you might want to blacklist the token
-	w.WriteHeader(http.StatusOK)
+	claims := currentClaims(r.Context())
+
+	_, err := h.sessionRepo.RevokeSession(claims.SessionID, claims.UserID)
+	if err != nil {
+		h.logger.Printf("Failed to revoke session: %v", err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.revokeAccessToken(w, claims) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
 }
 
-// AuthMiddleware validates JWT tokens and adds user info to request context
+// LogoutAll ends every session of the current user, on all devices
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims := currentClaims(r.Context())
+
+	err := h.sessionRepo.RevokeAllSessions(claims.UserID)
+	if err != nil {
+		h.logger.Printf("Failed to revoke sessions: %v", err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.revokeAccessToken(w, claims) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out of all sessions"})
+}
+
+// GetSessions lists the current user's active sessions
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := currentClaims(r.Context())
+
+	sessions, err := h.sessionRepo.GetActiveSessions(claims.UserID)
+	if err != nil {
+		h.logger.Printf("Failed to get sessions: %v", err)
+		http.Error(w, "Failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession ends one of the current user's sessions, e.g. a lost device
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	sessionIDStr := chi.URLParam(r, "sessionId")
+	sessionID, err := strconv.Atoi(sessionIDStr)
+	if err != nil {
+		http.Error(w, "Invalid session ID format", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.sessionRepo.RevokeSession(sessionID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to revoke session: %v", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuthMiddleware validates access tokens against their session and the
+// revoked token list, and adds the user to the request context. Personal
+// access tokens are accepted too; see authenticatePersonalAccessToken.
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get token from Authorization header
@@ -171,91 +477,217 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 		// Extract token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		if strings.HasPrefix(tokenString, personalAccessTokenPrefix) {
+			h.authenticatePersonalAccessToken(w, r, next, tokenString)
+			return
+		}
 
+		// Parse and validate token
+		claims := &Claims{}
+		err := h.jwtConfig.parse(tokenString, claims, accessAudience)
 		if err != nil {
 			h.logger.Printf("Token validation failed: %v", err)
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		if !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		// Verify the session is still active and the token was not revoked.
+		// Sessions are deleted with their user.
+		active, err := h.sessionRepo.IsAccessTokenActive(claims.SessionID, claims.UserID, claims.ID)
+		if err != nil {
+			h.logger.Printf("Failed to verify session: %v", err)
+			http.Error(w, "Session verification failed", http.StatusUnauthorized)
 			return
 		}
 
-		// Extract claims
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		if !active {
+			http.Error(w, "Session expired or revoked", http.StatusUnauthorized)
 			return
 		}
 
-		// Verify user still exists in database
-		user, err := h.userRepo.GetUserByID(claims.UserID)
-		if err != nil {
-			h.logger.Printf("Failed to verify user: %v", err)
-			http.Error(w, "User verification failed", http.StatusUnauthorized)
+		// Call next handler with the user in the request context
+		ctx := WithUserID(r.Context(), claims.UserID)
+		ctx = context.WithValue(ctx, claimsKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireSession rejects requests authenticated with a personal access
+// token. It guards account management, which needs a signed-in session.
+func (h *AuthHandler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(claimsKey).(*Claims); !ok {
+			http.Error(w, "This endpoint requires signing in; personal access tokens are not accepted", http.StatusForbidden)
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		if user == nil {
-			http.Error(w, "User not found", http.StatusUnauthorized)
-			return
+// authenticatePersonalAccessToken authenticates a request with a personal
+// access token. Tokens without the bands:write scope are read-only.
+func (h *AuthHandler) authenticatePersonalAccessToken(w http.ResponseWriter, r *http.Request, next http.Handler, tokenString string) {
+	pat, err := h.patRepo.AuthenticateToken(tokenString)
+	if err != nil {
+		h.logger.Printf("Failed to verify personal access token: %v", err)
+		http.Error(w, "Token verification failed", http.StatusUnauthorized)
+		return
+	}
+
+	if pat == nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !isSafeMethod(r.Method) && !pat.HasScope(database.ScopeBandsWrite) {
+		http.Error(w, "Token is read-only; the bands:write scope is required", http.StatusForbidden)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), pat.UserID)))
+}
+
+// isSafeMethod reports whether the HTTP method only reads
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// completeLogin finishes the first step of a login. It starts a session, or
+// returns an MFA challenge if the user has two-factor authentication enabled.
+func (h *AuthHandler) completeLogin(r *http.Request, user database.UserResponse) (*AuthResponse, *MFAChallenge, error) {
+	userTOTP, err := h.mfaRepo.GetTOTP(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if userTOTP.Enabled() {
+		mfaToken, err := h.generateMFAToken(user.ID)
+		if err != nil {
+			return nil, nil, err
 		}
+		return nil, &MFAChallenge{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(mfaTokenTTL.Seconds()),
+		}, nil
+	}
+
+	response, err := h.startSession(r, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, nil, nil
+}
 
-		// Add user info to request context
-		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
-		ctx = context.WithValue(ctx, "userEmail", claims.Email)
+// startSession creates a session for the request's device and returns its tokens
+func (h *AuthHandler) startSession(r *http.Request, user database.UserResponse) (*AuthResponse, error) {
+	refreshToken, err := generateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
 
-		// Call next handler with updated context
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	req := database.CreateSessionRequest{
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	}
+
+	session, err := h.sessionRepo.CreateSession(user.ID, req, refreshToken, time.Now().Add(h.jwtConfig.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return h.newAuthResponse(user, session.ID, refreshToken)
 }
 
-// generateJWT creates a JWT token for the given user
-func (h *AuthHandler) generateJWT(user database.UserResponse) (string, error) {
-	// Generate a random string for additional security
-	randomString, err := h.generateRandomString(32)
+// newAuthResponse issues an access token for the session and bundles it with the refresh token
+func (h *AuthHandler) newAuthResponse(user database.UserResponse, sessionID int, refreshToken string) (*AuthResponse, error) {
+	token, err := h.generateJWT(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(h.jwtConfig.AccessTTL.Seconds()),
+		User: User{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+		},
+	}, nil
+}
+
+// revokeAccessToken denylists the access token until it expires, writing an
+// error response on failure
+func (h *AuthHandler) revokeAccessToken(w http.ResponseWriter, claims *Claims) bool {
+	err := h.sessionRepo.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		h.logger.Printf("Failed to revoke access token: %v", err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// generateJWT creates an access token for the given user and session
+func (h *AuthHandler) generateJWT(user database.UserResponse, sessionID int) (string, error) {
+	// A random token ID keeps every issued token unique and revocable
+	tokenID, err := generateRandomString(32)
 	if err != nil {
 		return "", err
 	}
 
-	// Create claims
+	now := time.Now()
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "playlists-app",
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.jwtConfig.AccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    h.jwtConfig.Issuer,
 			Subject:   strconv.Itoa(user.ID),
-			ID:        randomString,
+			Audience:  jwt.ClaimStrings{accessAudience},
+			ID:        tokenID,
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return h.jwtConfig.sign(claims)
+}
 
-	// Sign token
-	tokenString, err := token.SignedString(jwtSecret)
+// generateMFAToken creates the short-lived token that carries a login from
+// the password to the second factor
+func (h *AuthHandler) generateMFAToken(userID int) (string, error) {
+	tokenID, err := generateRandomString(32)
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	now := time.Now()
+	claims := MFAClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    h.jwtConfig.Issuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{mfaAudience},
+			ID:        tokenID,
+		},
+	}
+
+	return h.jwtConfig.sign(claims)
 }
 
-// generateRandomString creates a random string of the specified length
-func (h *AuthHandler) generateRandomString(length int) (string, error) {
-	bytes := make([]byte, length)
-	_, err := rand.Read(bytes)
+// clientIP returns the request's remote address without the port. RealIP
+// middleware has already applied proxy headers.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return "", err
+		return r.RemoteAddr
 	}
-	return base64.URLEncoding.EncodeToString(bytes), nil
+	return host
 }
@@ -24,9 +24,9 @@ func NewBandHandler(bandRepo *database.BandRepository, logger *log.Logger) *Band
 	}
 }
 
-// GetBands returns all bands for the authenticated user
+// GetBands returns all bands the authenticated user has access to
 func (h *BandHandler) GetBands(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 
 	bands, err := h.bandRepo.GetBandsByUserID(userID)
 	if err != nil {
@@ -40,7 +40,7 @@ func (h *BandHandler) GetBands(w http.ResponseWriter, r *http.Request) {
 
 // CreateBand creates a new band for the authenticated user
 func (h *BandHandler) CreateBand(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 
 	var req database.CreateBandRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -67,9 +67,9 @@ func (h *BandHandler) CreateBand(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(band)
 }
 
-// GetBand returns a specific band by ID (only if owned by the authenticated user)
+// GetBand returns a specific band by ID (only if the authenticated user has access to it)
 func (h *BandHandler) GetBand(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -92,9 +92,9 @@ func (h *BandHandler) GetBand(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(band)
 }
 
-// UpdateBand updates a specific band (only if owned by the authenticated user)
+// UpdateBand updates a specific band (requires the admin role)
 func (h *BandHandler) UpdateBand(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -117,6 +117,9 @@ func (h *BandHandler) UpdateBand(w http.ResponseWriter, r *http.Request) {
 
 	band, err := h.bandRepo.UpdateBand(id, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		http.Error(w, "Failed to update band", http.StatusInternalServerError)
 		return
 	}
@@ -130,9 +133,9 @@ func (h *BandHandler) UpdateBand(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(band)
 }
 
-// DeleteBand deletes a specific band (only if owned by the authenticated user)
+// DeleteBand deletes a specific band (requires the owner role)
 func (h *BandHandler) DeleteBand(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -142,6 +145,9 @@ func (h *BandHandler) DeleteBand(w http.ResponseWriter, r *http.Request) {
 
 	err = h.bandRepo.DeleteBand(id, userID)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		http.Error(w, "Failed to delete band", http.StatusInternalServerError)
 		return
 	}
@@ -151,7 +157,7 @@ func (h *BandHandler) DeleteBand(w http.ResponseWriter, r *http.Request) {
 
 // GetBandMembers returns all members of a specific band
 func (h *BandHandler) GetBandMembers(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -159,10 +165,10 @@ func (h *BandHandler) GetBandMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user owns the band
+	// Check if user has access to the band
 	band, err := h.bandRepo.GetBandByID(bandID, userID)
 	if err != nil {
-		http.Error(w, "Failed to verify band ownership", http.StatusInternalServerError)
+		http.Error(w, "Failed to verify band access", http.StatusInternalServerError)
 		return
 	}
 
@@ -177,7 +183,7 @@ func (h *BandHandler) GetBandMembers(w http.ResponseWriter, r *http.Request) {
 
 // AddBandMember adds a new member to a band
 func (h *BandHandler) AddBandMember(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -200,6 +206,9 @@ func (h *BandHandler) AddBandMember(w http.ResponseWriter, r *http.Request) {
 
 	member, err := h.bandRepo.AddBandMember(bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		http.Error(w, "Failed to add band member", http.StatusInternalServerError)
 		return
 	}
@@ -216,7 +225,7 @@ func (h *BandHandler) AddBandMember(w http.ResponseWriter, r *http.Request) {
 
 // UpdateBandMember updates a specific band member
 func (h *BandHandler) UpdateBandMember(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -246,6 +255,9 @@ func (h *BandHandler) UpdateBandMember(w http.ResponseWriter, r *http.Request) {
 
 	member, err := h.bandRepo.UpdateBandMember(memberID, bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		http.Error(w, "Failed to update band member", http.StatusInternalServerError)
 		return
 	}
@@ -261,7 +273,7 @@ func (h *BandHandler) UpdateBandMember(w http.ResponseWriter, r *http.Request) {
 
 // DeleteBandMember deletes a specific band member
 func (h *BandHandler) DeleteBandMember(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -278,6 +290,9 @@ func (h *BandHandler) DeleteBandMember(w http.ResponseWriter, r *http.Request) {
 
 	err = h.bandRepo.DeleteBandMember(memberID, bandID, userID)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		http.Error(w, "Failed to delete band member", http.StatusInternalServerError)
 		return
 	}
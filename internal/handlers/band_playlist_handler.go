@@ -2,31 +2,65 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/chordpro"
 	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/music"
+	"github.com/nahue/playlists/internal/setlist"
 )
 
+// maxImportSize is the largest setlist file accepted for import
+const maxImportSize = 1 << 20
+
+// importContentTypes maps the content types of setlist files to their format
+var importContentTypes = map[string]setlist.Format{
+	"text/csv":                      setlist.FormatCSV,
+	"application/json":              setlist.FormatJSON,
+	"audio/x-mpegurl":               setlist.FormatM3U,
+	"audio/mpegurl":                 setlist.FormatM3U,
+	"application/vnd.apple.mpegurl": setlist.FormatM3U,
+	"text/plain":                    setlist.FormatText,
+}
+
+// ImportResult is the outcome of importing a setlist file: the songs added
+// to the playlist, or that would be added on a dry run, and the lines that
+// were rejected
+type ImportResult struct {
+	DryRun   bool                        `json:"dry_run"`
+	Songs    []database.BandPlaylistSong `json:"songs"`
+	Rejected []setlist.Rejection         `json:"rejected"`
+}
+
 // BandPlaylistHandler handles HTTP requests for band playlist operations
 type BandPlaylistHandler struct {
 	playlistRepo *database.BandPlaylistRepository
+	bandRepo     *database.BandRepository
 	logger       *log.Logger
 }
 
-// NewBandPlaylistHandler creates a new BandPlaylistHandler with the given repository
-func NewBandPlaylistHandler(playlistRepo *database.BandPlaylistRepository, logger *log.Logger) *BandPlaylistHandler {
+// NewBandPlaylistHandler creates a new BandPlaylistHandler with the given repositories
+func NewBandPlaylistHandler(playlistRepo *database.BandPlaylistRepository, bandRepo *database.BandRepository, logger *log.Logger) *BandPlaylistHandler {
 	return &BandPlaylistHandler{
 		playlistRepo: playlistRepo,
+		bandRepo:     bandRepo,
 		logger:       logger,
 	}
 }
 
 // GetPlaylists returns all playlists for a specific band
 func (h *BandPlaylistHandler) GetPlaylists(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -45,9 +79,35 @@ func (h *BandPlaylistHandler) GetPlaylists(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(playlists)
 }
 
+// GetTemplates returns the template playlists of a band
+func (h *BandPlaylistHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.playlistRepo.GetTemplates(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get templates: %v", err)
+		http.Error(w, "Failed to get templates", http.StatusInternalServerError)
+		return
+	}
+
+	if templates == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
 // GetPlaylist returns a specific playlist by ID
 func (h *BandPlaylistHandler) GetPlaylist(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -80,7 +140,7 @@ func (h *BandPlaylistHandler) GetPlaylist(w http.ResponseWriter, r *http.Request
 
 // CreatePlaylist creates a new playlist for a band
 func (h *BandPlaylistHandler) CreatePlaylist(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -100,9 +160,20 @@ func (h *BandPlaylistHandler) CreatePlaylist(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Playlist name is required", http.StatusBadRequest)
 		return
 	}
+	if req.ChangeoverDuration < 0 || (req.SetTargetDuration != nil && *req.SetTargetDuration <= 0) {
+		http.Error(w, "Changeover duration cannot be negative and set target duration must be positive", http.StatusBadRequest)
+		return
+	}
 
 	playlist, err := h.playlistRepo.CreatePlaylist(bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrTemplateNotFound) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Printf("Failed to create playlist: %v", err)
 		http.Error(w, "Failed to create playlist", http.StatusInternalServerError)
 		return
@@ -120,7 +191,7 @@ func (h *BandPlaylistHandler) CreatePlaylist(w http.ResponseWriter, r *http.Requ
 
 // UpdatePlaylist updates a specific playlist
 func (h *BandPlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -147,9 +218,16 @@ func (h *BandPlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Playlist name is required", http.StatusBadRequest)
 		return
 	}
+	if req.ChangeoverDuration < 0 || (req.SetTargetDuration != nil && *req.SetTargetDuration <= 0) {
+		http.Error(w, "Changeover duration cannot be negative and set target duration must be positive", http.StatusBadRequest)
+		return
+	}
 
 	playlist, err := h.playlistRepo.UpdatePlaylist(playlistID, bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		h.logger.Printf("Failed to update playlist: %v", err)
 		http.Error(w, "Failed to update playlist", http.StatusInternalServerError)
 		return
@@ -166,7 +244,7 @@ func (h *BandPlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Requ
 
 // DeletePlaylist deletes a specific playlist
 func (h *BandPlaylistHandler) DeletePlaylist(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -183,6 +261,9 @@ func (h *BandPlaylistHandler) DeletePlaylist(w http.ResponseWriter, r *http.Requ
 
 	err = h.playlistRepo.DeletePlaylist(playlistID, bandID, userID)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		h.logger.Printf("Failed to delete playlist: %v", err)
 		http.Error(w, "Failed to delete playlist", http.StatusInternalServerError)
 		return
@@ -193,7 +274,7 @@ func (h *BandPlaylistHandler) DeletePlaylist(w http.ResponseWriter, r *http.Requ
 
 // GetPlaylistSongs returns all songs for a specific playlist
 func (h *BandPlaylistHandler) GetPlaylistSongs(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -221,7 +302,7 @@ func (h *BandPlaylistHandler) GetPlaylistSongs(w http.ResponseWriter, r *http.Re
 
 // AddSong adds a new song to a playlist
 func (h *BandPlaylistHandler) AddSong(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -244,13 +325,33 @@ func (h *BandPlaylistHandler) AddSong(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate required fields
-	if req.Artist == "" || req.Song == "" {
-		http.Error(w, "Artist and song are required", http.StatusBadRequest)
+	if req.BandSongID == nil && (req.Artist == "" || req.Song == "") {
+		http.Error(w, "A band song ID, or artist and song, are required", http.StatusBadRequest)
+		return
+	}
+	err = req.SongMetadata.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Position != nil && *req.Position < 0 {
+		http.Error(w, "Position cannot be negative", http.StatusBadRequest)
 		return
 	}
 
 	song, err := h.playlistRepo.AddSong(playlistID, bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandSongNotFound) {
+			http.Error(w, "Band song not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrSectionNotFound) {
+			http.Error(w, "Section not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Printf("Failed to add song: %v", err)
 		http.Error(w, "Failed to add song", http.StatusInternalServerError)
 		return
@@ -268,7 +369,7 @@ func (h *BandPlaylistHandler) AddSong(w http.ResponseWriter, r *http.Request) {
 
 // UpdateSong updates a specific song in a playlist
 func (h *BandPlaylistHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -298,13 +399,33 @@ func (h *BandPlaylistHandler) UpdateSong(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate required fields
-	if req.Artist == "" || req.Song == "" {
-		http.Error(w, "Artist and song are required", http.StatusBadRequest)
+	if req.BandSongID == nil && (req.Artist == "" || req.Song == "") {
+		http.Error(w, "A band song ID, or artist and song, are required", http.StatusBadRequest)
+		return
+	}
+	err = req.SongMetadata.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Position < 0 {
+		http.Error(w, "Position cannot be negative", http.StatusBadRequest)
 		return
 	}
 
 	song, err := h.playlistRepo.UpdateSong(songID, playlistID, bandID, userID, req)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandSongNotFound) {
+			http.Error(w, "Band song not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrSectionNotFound) {
+			http.Error(w, "Section not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Printf("Failed to update song: %v", err)
 		http.Error(w, "Failed to update song", http.StatusInternalServerError)
 		return
@@ -321,7 +442,7 @@ func (h *BandPlaylistHandler) UpdateSong(w http.ResponseWriter, r *http.Request)
 
 // DeleteSong deletes a specific song from a playlist
 func (h *BandPlaylistHandler) DeleteSong(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int)
+	userID := currentUserID(r.Context())
 	bandIDStr := chi.URLParam(r, "bandId")
 	bandID, err := strconv.Atoi(bandIDStr)
 	if err != nil {
@@ -345,6 +466,9 @@ func (h *BandPlaylistHandler) DeleteSong(w http.ResponseWriter, r *http.Request)
 
 	err = h.playlistRepo.DeleteSong(songID, playlistID, bandID, userID)
 	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
 		h.logger.Printf("Failed to delete song: %v", err)
 		http.Error(w, "Failed to delete song", http.StatusInternalServerError)
 		return
@@ -352,3 +476,790 @@ func (h *BandPlaylistHandler) DeleteSong(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// CreateSection adds a section, such as a set or the encore, to a playlist
+func (h *BandPlaylistHandler) CreateSection(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.CreateSectionRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Name == "" {
+		http.Error(w, "Section name is required", http.StatusBadRequest)
+		return
+	}
+	if req.TargetDuration != nil && *req.TargetDuration <= 0 {
+		http.Error(w, "Target duration must be positive", http.StatusBadRequest)
+		return
+	}
+
+	section, err := h.playlistRepo.CreateSection(playlistID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to create section: %v", err)
+		http.Error(w, "Failed to create section", http.StatusInternalServerError)
+		return
+	}
+
+	if section == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(section)
+}
+
+// UpdateSection updates a section of a playlist
+func (h *BandPlaylistHandler) UpdateSection(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	sectionIDStr := chi.URLParam(r, "sectionId")
+	sectionID, err := strconv.Atoi(sectionIDStr)
+	if err != nil {
+		http.Error(w, "Invalid section ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.UpdateSectionRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Name == "" {
+		http.Error(w, "Section name is required", http.StatusBadRequest)
+		return
+	}
+	if req.TargetDuration != nil && *req.TargetDuration <= 0 {
+		http.Error(w, "Target duration must be positive", http.StatusBadRequest)
+		return
+	}
+
+	section, err := h.playlistRepo.UpdateSection(sectionID, playlistID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to update section: %v", err)
+		http.Error(w, "Failed to update section", http.StatusInternalServerError)
+		return
+	}
+
+	if section == nil {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
+
+// DeleteSection removes a section from a playlist, keeping its songs
+func (h *BandPlaylistHandler) DeleteSection(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	sectionIDStr := chi.URLParam(r, "sectionId")
+	sectionID, err := strconv.Atoi(sectionIDStr)
+	if err != nil {
+		http.Error(w, "Invalid section ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.playlistRepo.DeleteSection(sectionID, playlistID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to delete section: %v", err)
+		http.Error(w, "Failed to delete section", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderSongs sets the order of all songs of a playlist at once
+func (h *BandPlaylistHandler) ReorderSongs(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.ReorderSongsRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	songs, err := h.playlistRepo.ReorderSongs(playlistID, bandID, userID, req.SongIDs)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrInvalidSongOrder) {
+			http.Error(w, "Song IDs must list every song of the playlist once", http.StatusBadRequest)
+			return
+		}
+		h.logger.Printf("Failed to reorder songs: %v", err)
+		http.Error(w, "Failed to reorder songs", http.StatusInternalServerError)
+		return
+	}
+
+	if songs == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(songs)
+}
+
+// MoveSong moves or copies a song to a playlist of the same band
+func (h *BandPlaylistHandler) MoveSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.MoveSongRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.PlaylistID == 0 {
+		http.Error(w, "Target playlist ID is required", http.StatusBadRequest)
+		return
+	}
+	if req.Position != nil && *req.Position < 0 {
+		http.Error(w, "Position cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.playlistRepo.MoveSong(songID, playlistID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrPlaylistNotFound) {
+			http.Error(w, "Target playlist not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Printf("Failed to move song: %v", err)
+		http.Error(w, "Failed to move song", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if req.Copy {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(song)
+}
+
+// GetSongChart returns the chord sheet of a playlist song in the key the
+// playlist plays it in. It takes the format, key and transpose query
+// parameters of the catalog song chart, with transpose counted from the
+// playlist's key.
+func (h *BandPlaylistHandler) GetSongChart(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	format, err := chartFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	songChart, err := h.playlistRepo.GetSongChart(songID, playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist song chart: %v", err)
+		http.Error(w, "Failed to get chart", http.StatusInternalServerError)
+		return
+	}
+
+	if songChart == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+	if songChart.Chart.ChordPro == "" {
+		http.Error(w, "Song has no chart", http.StatusNotFound)
+		return
+	}
+
+	t, err := playlistSongTransposition(r, songChart)
+	if err != nil {
+		var problems chordpro.ErrorList
+		if errors.As(err, &problems) {
+			h.logger.Printf("Failed to parse stored chart of song %d: %v", songChart.Song.BandSongID, err)
+			http.Error(w, "Failed to parse chart", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = writeChart(w, format, songChart.Chart, t)
+	if err != nil {
+		h.logger.Printf("Failed to render chart of song %d: %v", songChart.Song.BandSongID, err)
+		http.Error(w, "Failed to render chart", http.StatusInternalServerError)
+	}
+}
+
+// TransposeSong transposes the chart of a playlist song by the key or
+// transpose query parameter and saves the new key as the playlist's key for
+// the song. It returns the chart in the new key.
+func (h *BandPlaylistHandler) TransposeSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if r.URL.Query().Get("key") == "" && r.URL.Query().Get("transpose") == "" {
+		http.Error(w, "Key or transpose is required", http.StatusBadRequest)
+		return
+	}
+
+	songChart, err := h.playlistRepo.GetSongChart(songID, playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist song chart: %v", err)
+		http.Error(w, "Failed to get chart", http.StatusInternalServerError)
+		return
+	}
+
+	if songChart == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	t, err := playlistSongTransposition(r, songChart)
+	if err != nil {
+		var problems chordpro.ErrorList
+		if errors.As(err, &problems) {
+			h.logger.Printf("Failed to parse stored chart of song %d: %v", songChart.Song.BandSongID, err)
+			http.Error(w, "Failed to parse chart", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if t.key == nil {
+		http.Error(w, "Song has no key to transpose from", http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.playlistRepo.SetSongKey(songID, playlistID, bandID, userID, t.key.String())
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to update song key: %v", err)
+		http.Error(w, "Failed to update song key", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	// Songs without a chart only get their key changed
+	if songChart.Chart.ChordPro == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ChartResponse{BandSongChart: songChart.Chart, Key: song.SongKey, Transpose: t.semitones})
+		return
+	}
+
+	err = writeChart(w, "json", songChart.Chart, t)
+	if err != nil {
+		h.logger.Printf("Failed to render chart of song %d: %v", songChart.Song.BandSongID, err)
+		http.Error(w, "Failed to render chart", http.StatusInternalServerError)
+	}
+}
+
+// playlistSongTransposition works out how to transpose the chart of a
+// playlist song, starting from the key the playlist plays it in
+func playlistSongTransposition(r *http.Request, songChart *database.PlaylistSongChart) (transposition, error) {
+	from, err := chartKey(songChart.Chart)
+	if err != nil {
+		return transposition{}, err
+	}
+
+	var base *music.Key
+	key, err := music.ParseKey(songChart.Song.SongKey)
+	if err == nil {
+		base = &key
+	}
+	if from == nil {
+		// Charts without a key are taken to be written in the song's key
+		from = base
+	}
+	return chartTransposition(r, from, base)
+}
+
+// DuplicatePlaylist copies a playlist with its sections and songs
+func (h *BandPlaylistHandler) DuplicatePlaylist(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional
+	var req database.DuplicatePlaylistRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := h.playlistRepo.DuplicatePlaylist(playlistID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to duplicate playlist: %v", err)
+		http.Error(w, "Failed to duplicate playlist", http.StatusInternalServerError)
+		return
+	}
+
+	if playlist == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(playlist)
+}
+
+// ImportSongs adds the songs of an uploaded CSV, M3U or text file to the end
+// of a playlist. The file is the request body; its format is the format
+// query parameter or else follows from the content type. With dry_run=true
+// nothing is saved.
+func (h *BandPlaylistHandler) ImportSongs(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	format, err := importFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid dry_run value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	parsed, err := setlist.Parse(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Import file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid import file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req database.ImportSongsRequest
+	for _, section := range parsed.Sections {
+		req.Sections = append(req.Sections, database.CreateSectionRequest{Name: section.Name, TargetDuration: section.TargetDuration})
+	}
+
+	// Rows with invalid song details are rejected like unreadable lines
+	rejected := append([]setlist.Rejection{}, parsed.Rejected...)
+	for _, entry := range parsed.Entries {
+		song := database.ImportSongRequest{
+			AddSongRequest: database.AddSongRequest{
+				Artist: entry.Artist,
+				Song:   entry.Title,
+				Notes:  entry.Notes,
+				SongMetadata: database.SongMetadata{
+					SongKey:       entry.Key,
+					Tempo:         entry.Tempo,
+					TimeSignature: entry.TimeSignature,
+					Duration:      entry.Duration,
+					Capo:          entry.Capo,
+					Tuning:        entry.Tuning,
+				},
+			},
+			Section: entry.Section,
+		}
+		err = song.SongMetadata.Normalize()
+		if err != nil {
+			rejected = append(rejected, setlist.Rejection{Line: entry.Line, Text: entry.Artist + " - " + entry.Title, Reason: err.Error()})
+			continue
+		}
+		req.Songs = append(req.Songs, song)
+	}
+	sort.SliceStable(rejected, func(i, j int) bool { return rejected[i].Line < rejected[j].Line })
+
+	imported, err := h.playlistRepo.ImportSongs(playlistID, bandID, userID, req, dryRun)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to import songs: %v", err)
+		http.Error(w, "Failed to import songs", http.StatusInternalServerError)
+		return
+	}
+
+	if imported == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	result := ImportResult{DryRun: dryRun, Songs: imported, Rejected: rejected}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// importFormat returns the format of an uploaded setlist file
+func importFormat(r *http.Request) (setlist.Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return setlist.ParseFormat(name)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil {
+		if format, ok := importContentTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+	return "", errors.New("import format must be csv, json, m3u or text")
+}
+
+// exportContentTypes are the content types and file extensions of the export formats
+var exportContentTypes = map[setlist.Format]struct{ contentType, extension string }{
+	setlist.FormatCSV:      {"text/csv; charset=utf-8", "csv"},
+	setlist.FormatJSON:     {"application/json", "json"},
+	setlist.FormatM3U:      {"audio/x-mpegurl; charset=utf-8", "m3u"},
+	setlist.FormatXSPF:     {"application/xspf+xml", "xspf"},
+	setlist.FormatMarkdown: {"text/markdown; charset=utf-8", "md"},
+}
+
+// ExportPlaylist downloads a playlist as CSV, JSON, M3U, XSPF or Markdown,
+// chosen with the format query parameter
+func (h *BandPlaylistHandler) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	format, err := setlist.ParseFormat(r.URL.Query().Get("format"))
+	export, ok := exportContentTypes[format]
+	if err != nil || !ok {
+		http.Error(w, "Export format must be csv, json, m3u, xspf or md", http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := h.playlistRepo.GetPlaylistByID(playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist: %v", err)
+		http.Error(w, "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+
+	if playlist == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	filename := exportFilename(playlist.Name) + "." + export.extension
+	w.Header().Set("Content-Type", export.contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	err = setlist.Write(format, w, setlistDocument(playlist))
+	if err != nil {
+		h.logger.Printf("Failed to export playlist: %v", err)
+	}
+}
+
+// PrintPlaylist renders a playlist as a PDF to print: a stage sheet in large
+// type, or with layout=detailed a sheet with each song's details. The date
+// query parameter (YYYY-MM-DD) goes in the header, today by default.
+func (h *BandPlaylistHandler) PrintPlaylist(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	opts := setlist.PDFOptions{Layout: setlist.StageSheet, Date: time.Now()}
+	if value := r.URL.Query().Get("layout"); value != "" {
+		opts.Layout, err = setlist.ParsePDFLayout(value)
+		if err != nil {
+			http.Error(w, "Layout must be stage or detailed", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("date"); value != "" {
+		opts.Date, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	playlist, err := h.playlistRepo.GetPlaylistByID(playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist: %v", err)
+		http.Error(w, "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+
+	if playlist == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	band, err := h.bandRepo.GetBandByID(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band: %v", err)
+		http.Error(w, "Failed to get band", http.StatusInternalServerError)
+		return
+	}
+	if band != nil {
+		opts.Band = band.Name
+	}
+
+	filename := exportFilename(playlist.Name) + "-" + string(opts.Layout) + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	err = setlist.WritePDF(w, setlistDocument(playlist), opts)
+	if err != nil {
+		h.logger.Printf("Failed to print playlist: %v", err)
+	}
+}
+
+// setlistDocument converts a playlist for export
+func setlistDocument(playlist *database.BandPlaylistWithSongs) setlist.Document {
+	doc := setlist.Document{
+		Name:               playlist.Name,
+		Description:        playlist.Description,
+		ChangeoverDuration: playlist.ChangeoverDuration,
+		SetTargetDuration:  playlist.SetTargetDuration,
+	}
+
+	sectionIndexes := make(map[int]int)
+	for i, section := range playlist.Sections {
+		sectionIndexes[section.ID] = i + 1
+		doc.Sections = append(doc.Sections, setlist.Section{Name: section.Name, TargetDuration: section.TargetDuration})
+	}
+
+	for _, song := range playlist.Songs {
+		var section string
+		var sectionIndex int
+		if song.SectionID != nil {
+			sectionIndex = sectionIndexes[*song.SectionID]
+			if sectionIndex > 0 {
+				section = doc.Sections[sectionIndex-1].Name
+			}
+		}
+		doc.Songs = append(doc.Songs, setlist.Song{
+			Artist:        song.Artist,
+			Title:         song.Song,
+			Section:       section,
+			SectionIndex:  sectionIndex,
+			Notes:         song.Notes,
+			Key:           song.SongKey,
+			Tempo:         song.Tempo,
+			TimeSignature: song.TimeSignature,
+			Duration:      song.Duration,
+			Capo:          song.Capo,
+			Tuning:        song.Tuning,
+		})
+	}
+
+	return doc
+}
+
+// exportFilename turns a playlist name into a file name without extension
+func exportFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return "playlist"
+	}
+	return name
+}
@@ -0,0 +1,559 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/chordpro"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/music"
+)
+
+// maxChartSize is the largest ChordPro document accepted for a song
+const maxChartSize = 256 << 10
+
+// UpdateChartRequest represents the request to replace the chord sheet of a song
+type UpdateChartRequest struct {
+	ChordPro string `json:"chordpro"`
+}
+
+// ChartResponse is the chord sheet of a song, as served and as parsed. Key
+// is the key it is served in, when known, and Transpose how many semitones
+// it was moved from the key it is written in.
+type ChartResponse struct {
+	database.BandSongChart
+	Key       string          `json:"key"`
+	Transpose int             `json:"transpose"`
+	Sheet     *chordpro.Sheet `json:"sheet"`
+}
+
+// ChartErrorResponse lists the problems found in a ChordPro document
+type ChartErrorResponse struct {
+	Error  string             `json:"error"`
+	Errors chordpro.ErrorList `json:"errors"`
+}
+
+// BandSongHandler handles HTTP requests for a band's song catalog
+type BandSongHandler struct {
+	songRepo *database.BandSongRepository
+	logger   *log.Logger
+}
+
+// NewBandSongHandler creates a new BandSongHandler with the given repository
+func NewBandSongHandler(songRepo *database.BandSongRepository, logger *log.Logger) *BandSongHandler {
+	return &BandSongHandler{
+		songRepo: songRepo,
+		logger:   logger,
+	}
+}
+
+// GetSongs returns the song catalog of a band
+func (h *BandSongHandler) GetSongs(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songs, err := h.songRepo.GetSongs(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band songs: %v", err)
+		http.Error(w, "Failed to get band songs", http.StatusInternalServerError)
+		return
+	}
+
+	if songs == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(songs)
+}
+
+// GetSong returns a specific song of a band's catalog
+func (h *BandSongHandler) GetSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.songRepo.GetSong(songID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band song: %v", err)
+		http.Error(w, "Failed to get band song", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(song)
+}
+
+// CreateSong adds a song to a band's catalog
+func (h *BandSongHandler) CreateSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.CreateBandSongRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	req.Artist = strings.TrimSpace(req.Artist)
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Artist == "" || req.Title == "" {
+		http.Error(w, "Artist and title are required", http.StatusBadRequest)
+		return
+	}
+	err = req.SongMetadata.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.songRepo.CreateSong(bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandSongExists) {
+			http.Error(w, "Band already has this song", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to create band song: %v", err)
+		http.Error(w, "Failed to create band song", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(song)
+}
+
+// UpdateSong updates a song of a band's catalog, and with it every playlist that uses it
+func (h *BandSongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.UpdateBandSongRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	req.Artist = strings.TrimSpace(req.Artist)
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Artist == "" || req.Title == "" {
+		http.Error(w, "Artist and title are required", http.StatusBadRequest)
+		return
+	}
+	err = req.SongMetadata.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.songRepo.UpdateSong(songID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandSongExists) {
+			http.Error(w, "Band already has this song", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to update band song: %v", err)
+		http.Error(w, "Failed to update band song", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(song)
+}
+
+// DeleteSong removes a song from a band's catalog once no playlist uses it
+func (h *BandSongHandler) DeleteSong(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.songRepo.DeleteSong(songID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandSongInUse) {
+			http.Error(w, "Song is still used in playlists", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to delete band song: %v", err)
+		http.Error(w, "Failed to delete band song", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetChart returns the ChordPro chord sheet of a catalog song. The format
+// query parameter picks json (the default, with the parsed sheet), html,
+// text or chordpro for the document itself. The key parameter transposes
+// the chart to another key, and transpose moves it by a number of semitones.
+func (h *BandSongHandler) GetChart(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	format, err := chartFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	chart, err := h.songRepo.GetChart(songID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band song chart: %v", err)
+		http.Error(w, "Failed to get chart", http.StatusInternalServerError)
+		return
+	}
+
+	if chart == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+	if chart.ChordPro == "" {
+		http.Error(w, "Song has no chart", http.StatusNotFound)
+		return
+	}
+
+	from, err := chartKey(*chart)
+	if err != nil {
+		h.logger.Printf("Failed to parse stored chart of song %d: %v", songID, err)
+		http.Error(w, "Failed to parse chart", http.StatusInternalServerError)
+		return
+	}
+
+	transposition, err := chartTransposition(r, from, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = writeChart(w, format, *chart, transposition)
+	if err != nil {
+		h.logger.Printf("Failed to render chart of song %d: %v", songID, err)
+		http.Error(w, "Failed to render chart", http.StatusInternalServerError)
+	}
+}
+
+// UpdateChart replaces the ChordPro chord sheet of a catalog song. Documents
+// that do not parse are rejected with the problems found and their lines.
+func (h *BandSongHandler) UpdateChart(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateChartRequest
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChartSize)).Decode(&req)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Chart is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if strings.TrimSpace(req.ChordPro) == "" {
+		http.Error(w, "ChordPro document is required", http.StatusBadRequest)
+		return
+	}
+	_, err = chordpro.Parse(req.ChordPro)
+	if err != nil {
+		var problems chordpro.ErrorList
+		if !errors.As(err, &problems) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChartErrorResponse{Error: "Invalid ChordPro document", Errors: problems})
+		return
+	}
+
+	chart, err := h.songRepo.SetChart(songID, bandID, userID, req.ChordPro)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to update band song chart: %v", err)
+		http.Error(w, "Failed to update chart", http.StatusInternalServerError)
+		return
+	}
+
+	if chart == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	from, err := chartKey(*chart)
+	if err == nil {
+		err = writeChart(w, "json", *chart, transposition{key: from})
+	}
+	if err != nil {
+		h.logger.Printf("Failed to render chart of song %d: %v", songID, err)
+		http.Error(w, "Failed to render chart", http.StatusInternalServerError)
+	}
+}
+
+// DeleteChart removes the chord sheet of a catalog song
+func (h *BandSongHandler) DeleteChart(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	chart, err := h.songRepo.SetChart(songID, bandID, userID, "")
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to delete band song chart: %v", err)
+		http.Error(w, "Failed to delete chart", http.StatusInternalServerError)
+		return
+	}
+
+	if chart == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// chartFormats are the formats a chart can be served in
+var chartFormats = []string{"json", "html", "text", "chordpro"}
+
+// chartFormat returns the format query parameter of a chart request
+func chartFormat(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return "json", nil
+	}
+	for _, f := range chartFormats {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", errors.New("chart format must be json, html, text or chordpro")
+}
+
+// transposition is how far a chart is moved and the key it ends up in, nil
+// when the key it is written in is unknown
+type transposition struct {
+	semitones int
+	key       *music.Key
+}
+
+// flats reports whether transposed chords are spelled with flats: as the
+// target key is written, or else when transposing down
+func (t transposition) flats() bool {
+	if t.key != nil {
+		return t.key.Flats()
+	}
+	return t.semitones < 0
+}
+
+// chartKey returns the key a chart is written in: its key directive, or
+// else the catalog key of the song. It is nil when neither is known.
+func chartKey(chart database.BandSongChart) (*music.Key, error) {
+	sheet, err := chordpro.Parse(chart.ChordPro)
+	if err != nil {
+		return nil, err
+	}
+
+	name := chart.SongKey
+	if keys := sheet.Meta["key"]; len(keys) > 0 {
+		name = keys[0]
+	}
+	key, err := music.ParseKey(name)
+	if err != nil {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+// chartTransposition works out how to transpose a chart written in from.
+// It starts in base when given, then moves to the key query parameter,
+// keeping the mode of the chart, or by the transpose one in semitones.
+func chartTransposition(r *http.Request, from, base *music.Key) (transposition, error) {
+	t := transposition{key: from}
+	if from != nil && base != nil {
+		t = transposition{semitones: music.Interval(*from, *base), key: base}
+	}
+
+	query := r.URL.Query()
+	if name := query.Get("key"); name != "" {
+		key, err := music.ParseKey(name)
+		if err != nil {
+			return t, err
+		}
+		if from == nil {
+			return t, errors.New("song has no key to transpose from")
+		}
+		key.Minor = from.Minor
+		return transposition{semitones: music.Interval(*from, key), key: &key}, nil
+	}
+
+	if value := query.Get("transpose"); value != "" {
+		semitones, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || semitones < -11 || semitones > 11 {
+			return t, errors.New("transpose must be a number of semitones between -11 and 11")
+		}
+		t.semitones += semitones
+		if t.key != nil {
+			key := t.key.Transpose(semitones)
+			t.key = &key
+		}
+	}
+
+	return t, nil
+}
+
+// writeChart transposes a chart and writes it in the given format
+func writeChart(w http.ResponseWriter, format string, chart database.BandSongChart, t transposition) error {
+	if t.semitones != 0 {
+		chart.ChordPro = chordpro.Transpose(chart.ChordPro, t.semitones, t.flats())
+	}
+
+	if format == "chordpro" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(chart.ChordPro))
+		return nil
+	}
+
+	sheet, err := chordpro.Parse(chart.ChordPro)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(sheet.HTML()))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(sheet.Text()))
+	default:
+		response := ChartResponse{BandSongChart: chart, Transpose: t.semitones, Sheet: sheet}
+		if t.key != nil {
+			response.Key = t.key.String()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+	return nil
+}
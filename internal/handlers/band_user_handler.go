@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+// BandUserHandler handles HTTP requests for band membership and roles
+type BandUserHandler struct {
+	bandUserRepo *database.BandUserRepository
+	logger       *log.Logger
+}
+
+// NewBandUserHandler creates a new BandUserHandler with the given repository
+func NewBandUserHandler(bandUserRepo *database.BandUserRepository, logger *log.Logger) *BandUserHandler {
+	return &BandUserHandler{
+		bandUserRepo: bandUserRepo,
+		logger:       logger,
+	}
+}
+
+// GetBandUsers returns all users with access to a band
+func (h *BandUserHandler) GetBandUsers(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	bandUsers, err := h.bandUserRepo.GetBandUsers(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band users: %v", err)
+		http.Error(w, "Failed to get band users", http.StatusInternalServerError)
+		return
+	}
+
+	if bandUsers == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bandUsers)
+}
+
+// AddBandUser gives an existing user access to a band
+func (h *BandUserHandler) AddBandUser(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.AddBandUserRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Email == "" || !req.Role.Valid() {
+		http.Error(w, "Email and a valid role are required", http.StatusBadRequest)
+		return
+	}
+
+	bandUser, err := h.bandUserRepo.AddBandUser(bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrAlreadyMember) {
+			http.Error(w, "User is already a band member", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to add band user: %v", err)
+		http.Error(w, "Failed to add band user", http.StatusInternalServerError)
+		return
+	}
+
+	if bandUser == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bandUser)
+}
+
+// UpdateBandUser changes a user's role in a band
+func (h *BandUserHandler) UpdateBandUser(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	targetIDStr := chi.URLParam(r, "userId")
+	targetID, err := strconv.Atoi(targetIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.UpdateBandUserRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if !req.Role.Valid() {
+		http.Error(w, "A valid role is required", http.StatusBadRequest)
+		return
+	}
+
+	bandUser, err := h.bandUserRepo.UpdateBandUserRole(bandID, targetID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrLastOwner) {
+			http.Error(w, "Band must keep at least one owner", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to update band user: %v", err)
+		http.Error(w, "Failed to update band user", http.StatusInternalServerError)
+		return
+	}
+
+	if bandUser == nil {
+		http.Error(w, "Band user not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bandUser)
+}
+
+// RemoveBandUser revokes a user's access to a band
+func (h *BandUserHandler) RemoveBandUser(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	targetIDStr := chi.URLParam(r, "userId")
+	targetID, err := strconv.Atoi(targetIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.bandUserRepo.RemoveBandUser(bandID, targetID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrLastOwner) {
+			http.Error(w, "Band must keep at least one owner", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to remove band user: %v", err)
+		http.Error(w, "Failed to remove band user", http.StatusInternalServerError)
+		return
+	}
+
+	if !removed {
+		http.Error(w, "Band user not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
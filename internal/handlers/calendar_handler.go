@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/ical"
+)
+
+const (
+	// calendarFeedPrefix starts every calendar feed token
+	calendarFeedPrefix = "cal_"
+	// calendarFeedPrefixLength is how much of a token is kept to identify it in listings
+	calendarFeedPrefixLength = 12
+	// calendarProductID identifies the application in calendar feeds
+	calendarProductID = "-//Playlists//Band Calendar//EN"
+)
+
+// CreatedCalendarFeed is the response to creating a calendar feed. The
+// subscription URL holds the token and is only returned once.
+type CreatedCalendarFeed struct {
+	database.CalendarFeed
+	URL string `json:"url"`
+}
+
+// CalendarHandler handles HTTP requests for calendar feeds of gigs and rehearsals
+type CalendarHandler struct {
+	feedRepo *database.CalendarFeedRepository
+	logger   *log.Logger
+	apiURL   string
+}
+
+// NewCalendarHandler creates a new CalendarHandler. apiURL is the public URL
+// of the API feeds are served from.
+func NewCalendarHandler(feedRepo *database.CalendarFeedRepository, logger *log.Logger, apiURL string) *CalendarHandler {
+	return &CalendarHandler{
+		feedRepo: feedRepo,
+		logger:   logger,
+		apiURL:   strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// GetFeeds lists the current user's calendar feeds
+func (h *CalendarHandler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	feeds, err := h.feedRepo.GetFeeds(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get calendar feeds: %v", err)
+		http.Error(w, "Failed to get calendar feeds", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feeds)
+}
+
+// CreateFeed creates a calendar feed of a band, or of all the user's bands
+// when no band_id is given. It replaces the user's previous feed of the same
+// bands, so creating a feed again also revokes the old URL.
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	// An empty body asks for a feed of all the user's bands
+	var req database.CreateCalendarFeedRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate calendar feed token: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	token := calendarFeedPrefix + strings.TrimRight(secret, "=")
+
+	feed, err := h.feedRepo.CreateFeed(userID, req, token, token[:calendarFeedPrefixLength])
+	if err != nil {
+		h.logger.Printf("Failed to create calendar feed: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	if feed == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedCalendarFeed{CalendarFeed: *feed, URL: h.apiURL + "/calendar/" + token + ".ics"})
+}
+
+// DeleteFeed revokes one of the current user's calendar feeds
+func (h *CalendarHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	feedIDStr := chi.URLParam(r, "feedId")
+	feedID, err := strconv.Atoi(feedIDStr)
+	if err != nil {
+		http.Error(w, "Invalid feed ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.feedRepo.DeleteFeed(feedID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to delete calendar feed: %v", err)
+		http.Error(w, "Failed to revoke calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFeed serves a calendar feed as an iCalendar file. The token in the URL
+// is the only credential, so calendar apps can subscribe without signing in.
+func (h *CalendarHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(chi.URLParam(r, "token"), ".ics")
+
+	feed, err := h.feedRepo.AuthenticateFeed(token)
+	if err != nil {
+		h.logger.Printf("Failed to authenticate calendar feed: %v", err)
+		http.Error(w, "Failed to get calendar", http.StatusInternalServerError)
+		return
+	}
+
+	if feed == nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	gigs, err := h.feedRepo.GetFeedGigs(feed)
+	if err != nil {
+		h.logger.Printf("Failed to get calendar gigs: %v", err)
+		http.Error(w, "Failed to get calendar", http.StatusInternalServerError)
+		return
+	}
+
+	rehearsals, err := h.feedRepo.GetFeedRehearsals(feed)
+	if err != nil {
+		h.logger.Printf("Failed to get calendar rehearsals: %v", err)
+		http.Error(w, "Failed to get calendar", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{ProductID: calendarProductID, Name: feed.BandName}
+	if feed.BandID == nil {
+		cal.Name = "Gigs and rehearsals"
+	}
+	for _, gig := range gigs {
+		cal.Events = append(cal.Events, h.gigEvent(gig, feed.BandID == nil))
+	}
+	for _, rehearsal := range rehearsals {
+		cal.Events = append(cal.Events, h.rehearsalEvent(rehearsal, feed.BandID == nil))
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	cal.WriteTo(w)
+}
+
+// gigEvent returns the calendar event of a gig. Feeds of several bands name
+// the band in the summary.
+func (h *CalendarHandler) gigEvent(gig database.CalendarGig, withBand bool) ical.Event {
+	summary := gig.Name
+	if summary == "" && gig.VenueName != "" {
+		summary = "Gig at " + gig.VenueName
+	} else if summary == "" {
+		summary = "Gig"
+	}
+	if withBand {
+		summary = gig.BandName + ": " + summary
+	}
+
+	location := gig.VenueName
+	if gig.VenueAddress != "" {
+		location = strings.TrimPrefix(location+", "+gig.VenueAddress, ", ")
+	}
+
+	var details []string
+	for _, t := range []struct {
+		label string
+		at    *time.Time
+	}{
+		{"Load-in", gig.LoadInAt},
+		{"Soundcheck", gig.SoundcheckAt},
+		{"Set", gig.SetAt},
+	} {
+		if t.at != nil {
+			details = append(details, t.label+" "+t.at.Format("15:04"))
+		}
+	}
+	if gig.Notes != "" {
+		details = append(details, gig.Notes)
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("gig-%d@%s", gig.ID, h.uidDomain()),
+		Sequence:    gig.Sequence,
+		Status:      strings.ToUpper(gig.Status),
+		Summary:     summary,
+		Location:    location,
+		Description: strings.Join(details, "\n"),
+		Start:       gig.StartsAt,
+		Created:     gig.CreatedAt,
+		Modified:    gig.UpdatedAt,
+	}
+}
+
+// rehearsalEvent returns the calendar event of a scheduled or cancelled
+// rehearsal. Feeds of several bands name the band in the summary.
+func (h *CalendarHandler) rehearsalEvent(rehearsal database.CalendarRehearsal, withBand bool) ical.Event {
+	summary := "Rehearsal"
+	if rehearsal.Title != "" {
+		summary = rehearsal.Title
+	}
+	if withBand {
+		summary = rehearsal.BandName + ": " + summary
+	}
+
+	status := ical.StatusConfirmed
+	if rehearsal.Status == database.RehearsalCancelled {
+		status = ical.StatusCancelled
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("rehearsal-%d@%s", rehearsal.ID, h.uidDomain()),
+		Sequence:    rehearsal.Sequence,
+		Status:      status,
+		Summary:     summary,
+		Location:    rehearsal.Location,
+		Description: rehearsal.Notes,
+		Start:       *rehearsal.StartsAt,
+		End:         rehearsal.EndsAt,
+		Created:     rehearsal.CreatedAt,
+		Modified:    rehearsal.UpdatedAt,
+	}
+}
+
+// uidDomain returns the domain calendar event UIDs are made unique with
+func (h *CalendarHandler) uidDomain() string {
+	u, err := url.Parse(h.apiURL)
+	if err != nil || u.Hostname() == "" {
+		return "playlists"
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/nahue/playlists/internal/database"
+)
+
+// writeForbidden responds with 403 if err is a band permission error and reports whether it did
+func writeForbidden(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, database.ErrForbidden) {
+		http.Error(w, "Insufficient band permissions", http.StatusForbidden)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+// GigHandler handles HTTP requests for a band's gigs
+type GigHandler struct {
+	gigRepo *database.GigRepository
+	logger  *log.Logger
+}
+
+// NewGigHandler creates a new GigHandler with the given repository
+func NewGigHandler(gigRepo *database.GigRepository, logger *log.Logger) *GigHandler {
+	return &GigHandler{
+		gigRepo: gigRepo,
+		logger:  logger,
+	}
+}
+
+// GetGigs returns the gigs of a band. The when query parameter lists only
+// upcoming or past gigs.
+func (h *GigHandler) GetGigs(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	period := database.EventPeriod(r.URL.Query().Get("when"))
+	if period != "" && period != database.EventsUpcoming && period != database.EventsPast {
+		http.Error(w, "When must be upcoming or past", http.StatusBadRequest)
+		return
+	}
+
+	gigs, err := h.gigRepo.GetGigs(bandID, userID, period)
+	if err != nil {
+		h.logger.Printf("Failed to get gigs: %v", err)
+		http.Error(w, "Failed to get gigs", http.StatusInternalServerError)
+		return
+	}
+
+	if gigs == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gigs)
+}
+
+// GetGig returns a specific gig of a band with its playlists
+func (h *GigHandler) GetGig(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	gigIDStr := chi.URLParam(r, "gigId")
+	gigID, err := strconv.Atoi(gigIDStr)
+	if err != nil {
+		http.Error(w, "Invalid gig ID format", http.StatusBadRequest)
+		return
+	}
+
+	gig, err := h.gigRepo.GetGig(gigID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get gig: %v", err)
+		http.Error(w, "Failed to get gig", http.StatusInternalServerError)
+		return
+	}
+
+	if gig == nil {
+		http.Error(w, "Gig not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gig)
+}
+
+// CreateGig adds a gig to a band
+func (h *GigHandler) CreateGig(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.CreateGigRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	err = req.GigDetails.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gig, err := h.gigRepo.CreateGig(bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrPlaylistNotFound) {
+			http.Error(w, "Playlist not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Printf("Failed to create gig: %v", err)
+		http.Error(w, "Failed to create gig", http.StatusInternalServerError)
+		return
+	}
+
+	if gig == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(gig)
+}
+
+// UpdateGig updates a gig of a band and replaces its playlists
+func (h *GigHandler) UpdateGig(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	gigIDStr := chi.URLParam(r, "gigId")
+	gigID, err := strconv.Atoi(gigIDStr)
+	if err != nil {
+		http.Error(w, "Invalid gig ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.UpdateGigRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	err = req.GigDetails.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gig, err := h.gigRepo.UpdateGig(gigID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrPlaylistNotFound) {
+			http.Error(w, "Playlist not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Printf("Failed to update gig: %v", err)
+		http.Error(w, "Failed to update gig", http.StatusInternalServerError)
+		return
+	}
+
+	if gig == nil {
+		http.Error(w, "Gig not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gig)
+}
+
+// DeleteGig removes a gig of a band, keeping its playlists
+func (h *GigHandler) DeleteGig(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	gigIDStr := chi.URLParam(r, "gigId")
+	gigID, err := strconv.Atoi(gigIDStr)
+	if err != nil {
+		http.Error(w, "Invalid gig ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.gigRepo.DeleteGig(gigID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to delete gig: %v", err)
+		http.Error(w, "Failed to delete gig", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Gig not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
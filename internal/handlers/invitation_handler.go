@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/mailer"
+)
+
+// invitationTTL is how long an invitation link stays valid
+const invitationTTL = 7 * 24 * time.Hour
+
+// invitationAudience marks JWTs that are invitation tokens rather than access tokens
+const invitationAudience = "band_invitation"
+
+// InvitationClaims are the claims of a signed invitation token
+type InvitationClaims struct {
+	InvitationID int    `json:"invitation_id"`
+	Email        string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// InvitationTokenRequest carries an invitation token in a request body
+type InvitationTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// CreatedInvitation is the response to creating an invitation. The link is
+// only returned once so it can be shared by other means than email.
+type CreatedInvitation struct {
+	database.BandInvitation
+	InviteURL string `json:"invite_url"`
+}
+
+// InvitationHandler handles HTTP requests for band invitations
+type InvitationHandler struct {
+	invitationRepo *database.BandInvitationRepository
+	mailer         mailer.Mailer
+	jwtConfig      JWTConfig
+	logger         *log.Logger
+	appURL         string
+}
+
+// NewInvitationHandler creates a new InvitationHandler. Invitation links point at appURL.
+func NewInvitationHandler(invitationRepo *database.BandInvitationRepository, m mailer.Mailer, jwtConfig JWTConfig, logger *log.Logger, appURL string) *InvitationHandler {
+	return &InvitationHandler{
+		invitationRepo: invitationRepo,
+		mailer:         m,
+		jwtConfig:      jwtConfig,
+		logger:         logger,
+		appURL:         strings.TrimRight(appURL, "/"),
+	}
+}
+
+// CreateInvitation invites an email address to join a band and emails them the link
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.CreateInvitationRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() || req.Role == database.BandRoleOwner {
+		http.Error(w, "Role must be admin, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	tokenID, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate invitation token: %v", err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(invitationTTL)
+
+	invitation, err := h.invitationRepo.CreateInvitation(bandID, userID, req, tokenID, expiresAt)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandMemberNotFound) {
+			http.Error(w, "Band member not found", http.StatusBadRequest)
+			return
+		}
+		h.logger.Printf("Failed to create invitation: %v", err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if invitation == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	token, err := signInvitationToken(h.jwtConfig, invitation)
+	if err != nil {
+		h.logger.Printf("Failed to sign invitation token: %v", err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	inviteURL := h.appURL + "/invitations?token=" + url.QueryEscape(token)
+
+	// The invitation stands even if mail delivery fails; the link is returned below
+	err = h.mailer.Send(r.Context(), mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You've been invited to join %s", invitation.BandName),
+		Body: fmt.Sprintf("You've been invited to join %s as %s.\n\nAccept or decline the invitation here:\n%s\n\nThis link expires on %s.\n",
+			invitation.BandName, invitation.Role, inviteURL, invitation.ExpiresAt.Format("January 2, 2006")),
+	})
+	if err != nil {
+		h.logger.Printf("Failed to send invitation email: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedInvitation{BandInvitation: *invitation, InviteURL: inviteURL})
+}
+
+// GetInvitations returns all invitations of a band
+func (h *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	invitations, err := h.invitationRepo.GetInvitations(bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to get invitations: %v", err)
+		http.Error(w, "Failed to get invitations", http.StatusInternalServerError)
+		return
+	}
+
+	if invitations == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RevokeInvitation revokes a pending invitation
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	invitationIDStr := chi.URLParam(r, "invitationId")
+	invitationID, err := strconv.Atoi(invitationIDStr)
+	if err != nil {
+		http.Error(w, "Invalid invitation ID format", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.invitationRepo.RevokeInvitation(invitationID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to revoke invitation: %v", err)
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "Pending invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetInvitationByToken shows the invitation a link refers to, so the
+// recipient can see what they are accepting before logging in
+func (h *InvitationHandler) GetInvitationByToken(w http.ResponseWriter, r *http.Request) {
+	claims, err := parseInvitationToken(h.jwtConfig, r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.invitationRepo.GetInvitationByToken(claims.InvitationID, claims.ID)
+	if err != nil {
+		h.logger.Printf("Failed to get invitation: %v", err)
+		http.Error(w, "Failed to get invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if invitation == nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// AcceptInvitation adds the authenticated user to the invited band
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req InvitationTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := parseInvitationToken(h.jwtConfig, req.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return
+	}
+
+	bandUser, err := h.invitationRepo.AcceptInvitation(claims.InvitationID, claims.ID, userID)
+	if err != nil {
+		writeInvitationError(w, h.logger, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bandUser)
+}
+
+// DeclineInvitation declines an invitation; no account is needed
+func (h *InvitationHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	var req InvitationTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := parseInvitationToken(h.jwtConfig, req.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return
+	}
+
+	err = h.invitationRepo.DeclineInvitation(claims.InvitationID, claims.ID)
+	if err != nil {
+		writeInvitationError(w, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeInvitationError maps invitation errors to HTTP responses
+func writeInvitationError(w http.ResponseWriter, logger *log.Logger, err error) {
+	switch {
+	case errors.Is(err, database.ErrInvitationUnavailable):
+		http.Error(w, "Invitation is no longer valid", http.StatusGone)
+	case errors.Is(err, database.ErrInvitationEmailMismatch):
+		http.Error(w, "Invitation was sent to a different email address", http.StatusForbidden)
+	default:
+		logger.Printf("Failed to respond to invitation: %v", err)
+		http.Error(w, "Failed to respond to invitation", http.StatusInternalServerError)
+	}
+}
+
+// signInvitationToken creates the signed, expiring token sent in invitation links
+func signInvitationToken(jwtConfig JWTConfig, invitation *database.BandInvitation) (string, error) {
+	claims := InvitationClaims{
+		InvitationID: invitation.ID,
+		Email:        invitation.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(invitation.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{invitationAudience},
+			ID:        invitation.TokenID,
+		},
+	}
+
+	return jwtConfig.sign(claims)
+}
+
+// parseInvitationToken verifies an invitation token's signature, audience and expiry
+func parseInvitationToken(jwtConfig JWTConfig, tokenString string) (*InvitationClaims, error) {
+	claims := &InvitationClaims{}
+	if err := jwtConfig.parse(tokenString, claims, invitationAudience); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTConfigParse(t *testing.T) {
+	config := JWTConfig{Secret: []byte("test-secret"), Issuer: "playlists-test", AccessTTL: time.Hour}
+
+	newClaims := func(audience string, expiresAt time.Time) *Claims {
+		return &Claims{
+			UserID: 7,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    config.Issuer,
+				Audience:  jwt.ClaimStrings{audience},
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}
+	}
+
+	token, err := config.sign(newClaims(accessAudience, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+
+	claims := &Claims{}
+	if err := config.parse(token, claims, accessAudience); err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if claims.UserID != 7 {
+		t.Errorf("Expected user ID 7, got %d", claims.UserID)
+	}
+
+	// Tokens for another purpose are not access tokens
+	if err := config.parse(token, &Claims{}, invitationAudience); err == nil {
+		t.Error("Expected an audience mismatch to be rejected")
+	}
+
+	// Tokens signed with another secret are rejected
+	other := JWTConfig{Secret: []byte("other-secret"), Issuer: config.Issuer}
+	if err := other.parse(token, &Claims{}, accessAudience); err == nil {
+		t.Error("Expected a token signed with another secret to be rejected")
+	}
+
+	expired, err := config.sign(newClaims(accessAudience, time.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+	if err := config.parse(expired, &Claims{}, accessAudience); err == nil {
+		t.Error("Expected an expired token to be rejected")
+	}
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/live"
+)
+
+const (
+	// liveKeepAlive is how often an idle live stream sends a comment so
+	// proxies keep the connection open
+	liveKeepAlive = 15 * time.Second
+	// liveRetry is how long clients wait before reconnecting to a live stream
+	liveRetry = 3 * time.Second
+)
+
+// liveStreamAudience marks JWTs that open the live stream of a playlist
+const liveStreamAudience = "live_stream"
+
+// LiveStreamClaims are the claims of a token that opens the live stream of
+// one playlist without an Authorization header, as browsers' EventSource
+// needs. It is tied to the access token it was issued with and expires with it.
+type LiveStreamClaims struct {
+	UserID        int    `json:"user_id"`
+	BandID        int    `json:"band_id"`
+	PlaylistID    int    `json:"playlist_id"`
+	SessionID     int    `json:"sid"`
+	AccessTokenID string `json:"ati"`
+	jwt.RegisteredClaims
+}
+
+// LiveStreamToken is a token for the live stream of a playlist
+type LiveStreamToken struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"` // token lifetime in seconds
+}
+
+// LiveHandler handles HTTP requests for live setlist sessions, where the
+// leader drives a playlist during a show and connected clients follow it
+// over Server-Sent Events
+type LiveHandler struct {
+	hub          *live.Hub
+	playlistRepo *database.BandPlaylistRepository
+	songRepo     *database.BandSongRepository
+	bandUserRepo *database.BandUserRepository
+	sessionRepo  *database.SessionRepository
+	jwtConfig    JWTConfig
+	logger       *log.Logger
+}
+
+// NewLiveHandler creates a new LiveHandler with the given hub and repositories
+func NewLiveHandler(hub *live.Hub, playlistRepo *database.BandPlaylistRepository, songRepo *database.BandSongRepository, bandUserRepo *database.BandUserRepository, sessionRepo *database.SessionRepository, jwtConfig JWTConfig, logger *log.Logger) *LiveHandler {
+	return &LiveHandler{
+		hub:          hub,
+		playlistRepo: playlistRepo,
+		songRepo:     songRepo,
+		bandUserRepo: bandUserRepo,
+		sessionRepo:  sessionRepo,
+		jwtConfig:    jwtConfig,
+		logger:       logger,
+	}
+}
+
+// liveSongRequest represents a request that references a song of a live session
+type liveSongRequest struct {
+	SongID int `json:"song_id"`
+}
+
+// liveEncoreRequest represents the request to add a catalog song as an encore
+type liveEncoreRequest struct {
+	BandSongID int `json:"band_song_id"`
+}
+
+// livePlaylist reads the band and playlist of a request and checks that the
+// user has the required role in the band, responding with an error when the
+// IDs are malformed, the playlist is not found or the role is too low
+func (h *LiveHandler) livePlaylist(w http.ResponseWriter, r *http.Request, required database.BandRole) (int, *database.BandPlaylistWithSongs, bool) {
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	playlist, ok := h.authorizePlaylist(w, bandID, playlistID, currentUserID(r.Context()), required)
+	return bandID, playlist, ok
+}
+
+// authorizePlaylist gets a playlist of the band when the user has the
+// required role in it, responding with an error otherwise
+func (h *LiveHandler) authorizePlaylist(w http.ResponseWriter, bandID, playlistID, userID int, required database.BandRole) (*database.BandPlaylistWithSongs, bool) {
+	role, err := h.bandUserRepo.GetRole(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band role: %v", err)
+		http.Error(w, "Failed to get playlist", http.StatusInternalServerError)
+		return nil, false
+	}
+	if role != "" && !role.Allows(required) {
+		writeForbidden(w, database.ErrForbidden)
+		return nil, false
+	}
+
+	playlist, err := h.playlistRepo.GetPlaylistByID(playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist: %v", err)
+		http.Error(w, "Failed to get playlist", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if playlist == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return playlist, true
+}
+
+// writeLiveError responds to the errors of live session operations and
+// reports whether it did
+func writeLiveError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, live.ErrNoSession):
+		http.Error(w, "Playlist is not live", http.StatusNotFound)
+	case errors.Is(err, live.ErrNotLeader):
+		http.Error(w, "Another member is leading the live session", http.StatusConflict)
+	case errors.Is(err, live.ErrSongNotFound):
+		http.Error(w, "Song not found", http.StatusNotFound)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeLiveState responds with the state of a live session after an event
+func writeLiveState(w http.ResponseWriter, event live.Event) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event.State)
+}
+
+// writeLiveEvent writes an event of a live session in Server-Sent Events format
+func writeLiveEvent(w io.Writer, event live.Event) error {
+	data, err := json.Marshal(event.State)
+	if err != nil {
+		return err
+	}
+
+	if event.ID != "" {
+		_, err = fmt.Fprintf(w, "id: %s\n", event.ID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// Stream sends the live session of a playlist as Server-Sent Events. It
+// starts with the events the client missed since the Last-Event-ID header
+// or last_event_id query parameter, or with a snapshot of the session.
+// Browsers' EventSource cannot send the Authorization header this needs, so
+// they use CreateStreamToken and StreamWithToken instead.
+func (h *LiveHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	_, playlist, ok := h.livePlaylist(w, r, database.BandRoleViewer)
+	if !ok {
+		return
+	}
+
+	h.stream(w, r, playlist.ID, currentClaims(r.Context()).ExpiresAt)
+}
+
+// CreateStreamToken issues a token that opens the live stream of a playlist
+// with StreamWithToken. It expires with the access token of the request.
+func (h *LiveHandler) CreateStreamToken(w http.ResponseWriter, r *http.Request) {
+	bandID, playlist, ok := h.livePlaylist(w, r, database.BandRoleViewer)
+	if !ok {
+		return
+	}
+
+	claims := currentClaims(r.Context())
+	streamClaims := LiveStreamClaims{
+		UserID:        claims.UserID,
+		BandID:        bandID,
+		PlaylistID:    playlist.ID,
+		SessionID:     claims.SessionID,
+		AccessTokenID: claims.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: claims.ExpiresAt,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    h.jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{liveStreamAudience},
+		},
+	}
+
+	token, err := h.jwtConfig.sign(streamClaims)
+	if err != nil {
+		h.logger.Printf("Failed to sign live stream token: %v", err)
+		http.Error(w, "Failed to create stream token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LiveStreamToken{Token: token, ExpiresIn: int(time.Until(claims.ExpiresAt.Time).Seconds())})
+}
+
+// StreamWithToken sends the live session of a playlist as Server-Sent
+// Events like Stream, authenticated by a token from CreateStreamToken in
+// the token query parameter. Access is checked again on every connection.
+func (h *LiveHandler) StreamWithToken(w http.ResponseWriter, r *http.Request) {
+	claims := &LiveStreamClaims{}
+	err := h.jwtConfig.parse(r.URL.Query().Get("token"), claims, liveStreamAudience)
+	if err != nil {
+		http.Error(w, "Invalid or expired stream token", http.StatusUnauthorized)
+		return
+	}
+
+	// The stream token is revoked with the access token it was issued with
+	active, err := h.sessionRepo.IsAccessTokenActive(claims.SessionID, claims.UserID, claims.AccessTokenID)
+	if err != nil {
+		h.logger.Printf("Failed to verify session: %v", err)
+		http.Error(w, "Session verification failed", http.StatusUnauthorized)
+		return
+	}
+
+	if !active {
+		http.Error(w, "Session expired or revoked", http.StatusUnauthorized)
+		return
+	}
+
+	playlist, ok := h.authorizePlaylist(w, claims.BandID, claims.PlaylistID, claims.UserID, database.BandRoleViewer)
+	if !ok {
+		return
+	}
+
+	h.stream(w, r, playlist.ID, claims.ExpiresAt)
+}
+
+// stream sends the live session of a playlist as Server-Sent Events until
+// the client goes away or its credentials expire at expiresAt
+func (h *LiveHandler) stream(w http.ResponseWriter, r *http.Request, playlistID int, expiresAt *jwt.NumericDate) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, backlog := h.hub.Subscribe(playlistID, lastEventID)
+	defer sub.Close()
+
+	// Streams outlive the server's write timeout
+	err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil {
+		h.logger.Printf("Failed to clear write deadline of live stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", liveRetry.Milliseconds())
+	for _, event := range backlog {
+		err = writeLiveEvent(w, event)
+		if err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	// The stream ends with the credentials; the client reconnects with
+	// fresh ones and resumes
+	var expired <-chan time.Time
+	if expiresAt != nil {
+		timer := time.NewTimer(time.Until(expiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-sub.Events:
+			// Clients that fall behind are dropped and resume when they reconnect
+			if !ok {
+				return
+			}
+			err = writeLiveEvent(w, event)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// Start starts the live session of a playlist with the user as leader, or
+// makes the user the leader of the session already live
+func (h *LiveHandler) Start(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	_, playlist, ok := h.livePlaylist(w, r, database.BandRoleEditor)
+	if !ok {
+		return
+	}
+
+	songs := make([]live.Song, 0, len(playlist.Songs))
+	for _, song := range playlist.Songs {
+		playlistSongID := song.ID
+		songs = append(songs, live.Song{
+			PlaylistSongID: &playlistSongID,
+			BandSongID:     song.BandSongID,
+			Artist:         song.Artist,
+			Title:          song.Song,
+			SongKey:        song.SongKey,
+		})
+	}
+
+	writeLiveState(w, h.hub.Start(playlist.ID, userID, songs))
+}
+
+// End ends the live session of a playlist
+func (h *LiveHandler) End(w http.ResponseWriter, r *http.Request) {
+	_, playlist, ok := h.livePlaylist(w, r, database.BandRoleEditor)
+	if !ok {
+		return
+	}
+
+	_, err := h.hub.End(playlist.ID)
+	if err != nil {
+		if writeLiveError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to end live session: %v", err)
+		http.Error(w, "Failed to end live session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changeSong applies a change to a song of a live session on behalf of its leader
+func (h *LiveHandler) changeSong(w http.ResponseWriter, r *http.Request, change func(playlistID, userID, songID int) (live.Event, error)) {
+	userID := currentUserID(r.Context())
+	_, playlist, ok := h.livePlaylist(w, r, database.BandRoleEditor)
+	if !ok {
+		return
+	}
+
+	var req liveSongRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.SongID == 0 {
+		http.Error(w, "Song ID is required", http.StatusBadRequest)
+		return
+	}
+
+	event, err := change(playlist.ID, userID, req.SongID)
+	if err != nil {
+		if writeLiveError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to change live session: %v", err)
+		http.Error(w, "Failed to change live session", http.StatusInternalServerError)
+		return
+	}
+
+	writeLiveState(w, event)
+}
+
+// SetCurrent marks the song being played
+func (h *LiveHandler) SetCurrent(w http.ResponseWriter, r *http.Request) {
+	h.changeSong(w, r, h.hub.SetCurrent)
+}
+
+// SetNext marks the song to be played next
+func (h *LiveHandler) SetNext(w http.ResponseWriter, r *http.Request) {
+	h.changeSong(w, r, h.hub.SetNext)
+}
+
+// Skip leaves a song out of the live session
+func (h *LiveHandler) Skip(w http.ResponseWriter, r *http.Request) {
+	h.changeSong(w, r, h.hub.Skip)
+}
+
+// InsertEncore adds a song of the band's catalog to the end of the live
+// session as an encore
+func (h *LiveHandler) InsertEncore(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, playlist, ok := h.livePlaylist(w, r, database.BandRoleEditor)
+	if !ok {
+		return
+	}
+
+	var req liveEncoreRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.BandSongID == 0 {
+		http.Error(w, "Band song ID is required", http.StatusBadRequest)
+		return
+	}
+
+	song, err := h.songRepo.GetSong(req.BandSongID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get band song: %v", err)
+		http.Error(w, "Failed to get band song", http.StatusInternalServerError)
+		return
+	}
+
+	if song == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	event, err := h.hub.InsertEncore(playlist.ID, userID, live.Song{
+		BandSongID: song.ID,
+		Artist:     song.Artist,
+		Title:      song.Title,
+		SongKey:    song.SongKey,
+	})
+	if err != nil {
+		if writeLiveError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to insert encore: %v", err)
+		http.Error(w, "Failed to insert encore", http.StatusInternalServerError)
+		return
+	}
+
+	writeLiveState(w, event)
+}
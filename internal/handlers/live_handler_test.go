@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamWithTokenRejectsOtherTokens(t *testing.T) {
+	config := JWTConfig{Secret: []byte("test-secret"), Issuer: "playlists-test", AccessTTL: time.Hour}
+	h := NewLiveHandler(nil, nil, nil, nil, nil, config, nil)
+
+	registered := func(audience string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    config.Issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	accessToken, err := config.sign(&Claims{UserID: 7, RegisteredClaims: registered(accessAudience)})
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+
+	// Access tokens do not belong in URLs, so only stream tokens are accepted
+	for _, token := range []string{"", "garbage", accessToken} {
+		w := httptest.NewRecorder()
+		h.StreamWithToken(w, httptest.NewRequest(http.MethodGet, "/live?token="+token, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("StreamWithToken(%q) status = %d, want %d", token, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	streamToken, err := config.sign(&LiveStreamClaims{UserID: 7, BandID: 1, PlaylistID: 2, RegisteredClaims: registered(liveStreamAudience)})
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+	claims := &LiveStreamClaims{}
+	if err := config.parse(streamToken, claims, liveStreamAudience); err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if claims.UserID != 7 || claims.BandID != 1 || claims.PlaylistID != 2 {
+		t.Errorf("claims = %+v", claims)
+	}
+	if err := config.parse(streamToken, &Claims{}, accessAudience); err == nil {
+		t.Error("Expected a stream token to be rejected as an access token")
+	}
+}
@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/totp"
+)
+
+const (
+	// totpIssuer is the account name prefix shown in authenticator apps
+	totpIssuer = "Playlists"
+	// totpSkew is how many 30 second steps of clock drift are accepted either way
+	totpSkew = 1
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+	// maxMFAAttempts is how many wrong codes in a row lock verification
+	maxMFAAttempts = 5
+	// mfaLockout is how long verification stays locked after too many wrong codes
+	mfaLockout = 15 * time.Minute
+)
+
+// errMFALocked is returned when verification is locked after too many wrong codes
+var errMFALocked = errors.New("too many failed attempts")
+
+// recoveryCodeEncoding spells recovery codes in lowercase base32
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// EnrollTOTPRequest represents the request to start TOTP enrollment
+type EnrollTOTPRequest struct {
+	Password string `json:"password"`
+}
+
+// DisableTOTPRequest represents the request to turn off TOTP
+type DisableTOTPRequest struct {
+	Password string `json:"password"`
+	database.MFACodeRequest
+}
+
+// TOTPEnrollment is the secret to add to an authenticator app, also as an otpauth:// URI for QR codes
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse returns newly issued recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatus describes the current user's second factor
+type MFAStatus struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// MFAHandler handles HTTP requests for managing two-factor authentication
+type MFAHandler struct {
+	userRepo *database.UserRepository
+	mfaRepo  *database.MFARepository
+	logger   *log.Logger
+}
+
+// NewMFAHandler creates a new MFAHandler with the given repositories and logger
+func NewMFAHandler(userRepo *database.UserRepository, mfaRepo *database.MFARepository, logger *log.Logger) *MFAHandler {
+	return &MFAHandler{
+		userRepo: userRepo,
+		mfaRepo:  mfaRepo,
+		logger:   logger,
+	}
+}
+
+// GetStatus reports whether the current user has TOTP enabled
+func (h *MFAHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get TOTP settings: %v", err)
+		http.Error(w, "Failed to get two-factor status", http.StatusInternalServerError)
+		return
+	}
+
+	status := MFAStatus{TOTPEnabled: userTOTP.Enabled()}
+	if status.TOTPEnabled {
+		status.RecoveryCodesRemaining, err = h.mfaRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			h.logger.Printf("Failed to count recovery codes: %v", err)
+			http.Error(w, "Failed to get two-factor status", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnrollTOTP starts TOTP enrollment after checking the password. The secret
+// only takes effect once ConfirmTOTP receives a first valid code.
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req EnrollTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.userRepo.VerifyPassword(userID, req.Password)
+	if err != nil {
+		h.logger.Printf("Failed to verify password: %v", err)
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		h.logger.Printf("Failed to get user: %v", err)
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.logger.Printf("Failed to generate TOTP secret: %v", err)
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.mfaRepo.BeginTOTPEnrollment(userID, secret)
+	if err != nil {
+		if errors.Is(err, database.ErrMFAAlreadyEnabled) {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		h.logger.Printf("Failed to begin TOTP enrollment: %v", err)
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(secret, totpIssuer, user.Email),
+	})
+}
+
+// ConfirmTOTP enables TOTP with the first code from the authenticator app
+// and returns the recovery codes
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.MFACodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get TOTP settings: %v", err)
+		http.Error(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	if userTOTP == nil || userTOTP.Enabled() {
+		http.Error(w, "No pending enrollment", http.StatusConflict)
+		return
+	}
+
+	if userTOTP.Locked(time.Now()) {
+		http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	step, valid := totp.Validate(userTOTP.Secret, req.Code, time.Now(), totpSkew)
+	if !valid {
+		if err := h.mfaRepo.RecordFailedAttempt(userID, maxMFAAttempts, mfaLockout); err != nil {
+			h.logger.Printf("Failed to record failed attempt: %v", err)
+		}
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		h.logger.Printf("Failed to generate recovery codes: %v", err)
+		http.Error(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	enabled, err := h.mfaRepo.ConfirmTOTPEnrollment(userID, step, codes)
+	if err != nil {
+		h.logger.Printf("Failed to confirm TOTP enrollment: %v", err)
+		http.Error(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	if !enabled {
+		http.Error(w, "No pending enrollment", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns off TOTP after checking the password and a current code
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req DisableTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Password == "" || (req.Code == "" && req.RecoveryCode == "") {
+		http.Error(w, "Password and a code are required", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.userRepo.VerifyPassword(userID, req.Password)
+	if err != nil {
+		h.logger.Printf("Failed to verify password: %v", err)
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	if !h.checkSecondFactor(w, userID, req.MFACodeRequest) {
+		return
+	}
+
+	_, err = h.mfaRepo.DisableTOTP(userID)
+	if err != nil {
+		h.logger.Printf("Failed to disable TOTP: %v", err)
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a current code
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.MFACodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkSecondFactor(w, userID, database.MFACodeRequest{Code: req.Code}) {
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		h.logger.Printf("Failed to generate recovery codes: %v", err)
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.mfaRepo.ReplaceRecoveryCodes(userID, codes)
+	if err != nil {
+		h.logger.Printf("Failed to replace recovery codes: %v", err)
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// checkSecondFactor verifies a code for the user's enabled TOTP, writing an
+// error response and returning false if it is missing or wrong
+func (h *MFAHandler) checkSecondFactor(w http.ResponseWriter, userID int, req database.MFACodeRequest) bool {
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get TOTP settings: %v", err)
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return false
+	}
+
+	if !userTOTP.Enabled() {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return false
+	}
+
+	return writeSecondFactorResult(w, h.logger, h.mfaRepo, userTOTP, req)
+}
+
+// writeSecondFactorResult verifies a TOTP or recovery code, writing an error
+// response and returning false unless it is valid
+func writeSecondFactorResult(w http.ResponseWriter, logger *log.Logger, mfaRepo *database.MFARepository, userTOTP *database.UserTOTP, req database.MFACodeRequest) bool {
+	ok, err := verifySecondFactor(mfaRepo, userTOTP, req)
+	if err != nil {
+		if errors.Is(err, errMFALocked) {
+			http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+			return false
+		}
+		logger.Printf("Failed to verify second factor: %v", err)
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return false
+	}
+
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code if one is given,
+// against the user's enabled TOTP. Codes are single-use, and wrong codes
+// count towards the lockout.
+func verifySecondFactor(mfaRepo *database.MFARepository, userTOTP *database.UserTOTP, req database.MFACodeRequest) (bool, error) {
+	now := time.Now()
+	if userTOTP.Locked(now) {
+		return false, errMFALocked
+	}
+
+	var ok bool
+	var err error
+	if req.RecoveryCode != "" {
+		ok, err = mfaRepo.UseRecoveryCode(userTOTP.UserID, normalizeRecoveryCode(req.RecoveryCode))
+	} else if step, valid := totp.Validate(userTOTP.Secret, req.Code, now, totpSkew); valid {
+		ok, err = mfaRepo.UseTOTPStep(userTOTP.UserID, step)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		if err := mfaRepo.RecordFailedAttempt(userTOTP.UserID, maxMFAAttempts, mfaLockout); err != nil {
+			return false, err
+		}
+	}
+
+	return ok, nil
+}
+
+// generateRecoveryCodes creates n random recovery codes formatted as xxxx-xxxx
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(bytes)
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode lowercases a recovery code and drops the separators
+// users may or may not type, giving the form it was stored under
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) == 8 {
+		code = code[:4] + "-" + code[4:]
+	}
+	return code
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/oidc"
+)
+
+const (
+	// oidcStateAudience marks JWTs that carry the state of a sign-in in progress
+	oidcStateAudience = "oidc_state"
+	// oidcStateTTL is how long a user has to sign in at the provider
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateCookie holds the signed state between the start and the callback
+	oidcStateCookie = "oidc_state"
+	// oidcCookiePath limits the state cookie to the OIDC endpoints
+	oidcCookiePath = "/auth/oidc"
+)
+
+// OIDCStateClaims are the claims of the state cookie. They tie the callback
+// to the browser that started the sign-in and keep the PKCE verifier and
+// nonce until the provider redirects back.
+type OIDCStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// OIDCHandler handles signing in with external OpenID Connect providers
+type OIDCHandler struct {
+	providers     map[string]*oidc.Provider
+	identityRepo  *database.UserIdentityRepository
+	auth          *AuthHandler
+	jwtConfig     JWTConfig
+	logger        *log.Logger
+	appURL        string
+	secureCookies bool
+}
+
+// NewOIDCHandler creates a new OIDCHandler for the given providers. Sessions
+// are started through auth, and users are sent back to appURL afterwards.
+// secureCookies should be set when the API is served over HTTPS.
+func NewOIDCHandler(providers []*oidc.Provider, identityRepo *database.UserIdentityRepository, auth *AuthHandler, jwtConfig JWTConfig, logger *log.Logger, appURL string, secureCookies bool) *OIDCHandler {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCHandler{
+		providers:     byName,
+		identityRepo:  identityRepo,
+		auth:          auth,
+		jwtConfig:     jwtConfig,
+		logger:        logger,
+		appURL:        strings.TrimRight(appURL, "/"),
+		secureCookies: secureCookies,
+	}
+}
+
+// GetProviders lists the names of the configured providers, for sign-in buttons
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
+
+// Start redirects the browser to the provider's sign-in page
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+	provider, ok := h.providers[providerName]
+	if !ok {
+		http.Error(w, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	state, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate OIDC state: %v", err)
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate OIDC nonce: %v", err)
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		h.logger.Printf("Failed to generate PKCE verifier: %v", err)
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		h.logger.Printf("Failed to build %s authorization URL: %v", providerName, err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now()
+	cookieValue, err := h.jwtConfig.sign(OIDCStateClaims{
+		Provider: providerName,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    h.jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+		},
+	})
+	if err != nil {
+		h.logger.Printf("Failed to sign OIDC state: %v", err)
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, h.stateCookie(cookieValue, int(oidcStateTTL.Seconds())))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes the sign-in when the provider redirects back. The
+// browser is sent to the login page with the tokens, or an MFA token, in the
+// URL fragment so they never reach server logs.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+	provider, ok := h.providers[providerName]
+	if !ok {
+		http.Error(w, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	// The state cookie is single-use
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, h.stateCookie("", -1))
+	if err != nil {
+		h.redirectWithError(w, r, "Sign-in expired, please try again")
+		return
+	}
+
+	claims := &OIDCStateClaims{}
+	err = h.jwtConfig.parse(cookie.Value, claims, oidcStateAudience)
+	if err != nil || claims.Provider != providerName {
+		h.redirectWithError(w, r, "Sign-in expired, please try again")
+		return
+	}
+
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(claims.State)) != 1 {
+		h.redirectWithError(w, r, "Sign-in could not be verified, please try again")
+		return
+	}
+
+	if providerError := query.Get("error"); providerError != "" {
+		h.logger.Printf("%s sign-in failed: %s %s", providerName, providerError, query.Get("error_description"))
+		h.redirectWithError(w, r, "Sign-in was cancelled or denied")
+		return
+	}
+
+	rawIDToken, err := provider.Exchange(r.Context(), query.Get("code"), claims.Verifier)
+	if err != nil {
+		h.logger.Printf("Failed to exchange %s authorization code: %v", providerName, err)
+		h.redirectWithError(w, r, "Sign-in failed")
+		return
+	}
+
+	idClaims, err := provider.VerifyIDToken(r.Context(), rawIDToken, claims.Nonce)
+	if err != nil {
+		h.logger.Printf("Failed to verify %s ID token: %v", providerName, err)
+		h.redirectWithError(w, r, "Sign-in failed")
+		return
+	}
+
+	firstName, lastName := idClaims.GivenName, idClaims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(idClaims.Name, " ")
+	}
+
+	user, err := h.identityRepo.SignIn(database.ExternalIdentity{
+		Provider:      providerName,
+		Subject:       idClaims.Subject,
+		Email:         strings.TrimSpace(idClaims.Email),
+		EmailVerified: bool(idClaims.EmailVerified),
+		FirstName:     firstName,
+		LastName:      lastName,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrIdentityEmailUnverified):
+			h.redirectWithError(w, r, "Your identity provider did not confirm your email address")
+		case errors.Is(err, database.ErrIdentityConflict):
+			h.redirectWithError(w, r, "Your account is already linked to another "+providerName+" account")
+		default:
+			h.logger.Printf("Failed to sign in with %s: %v", providerName, err)
+			h.redirectWithError(w, r, "Sign-in failed")
+		}
+		return
+	}
+
+	response, challenge, err := h.auth.completeLogin(r, *user)
+	if err != nil {
+		h.logger.Printf("Failed to start session: %v", err)
+		h.redirectWithError(w, r, "Sign-in failed")
+		return
+	}
+
+	fragment := url.Values{}
+	if challenge != nil {
+		fragment.Set("mfa_token", challenge.MFAToken)
+	} else {
+		fragment.Set("token", response.Token)
+		fragment.Set("refresh_token", response.RefreshToken)
+		fragment.Set("expires_in", strconv.Itoa(response.ExpiresIn))
+	}
+	http.Redirect(w, r, h.appURL+"/login#"+fragment.Encode(), http.StatusFound)
+}
+
+// GetIdentities lists the external identities linked to the current user
+func (h *OIDCHandler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	identities, err := h.identityRepo.GetIdentities(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get user identities: %v", err)
+		http.Error(w, "Failed to get identities", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+// DeleteIdentity unlinks one of the current user's external identities
+func (h *OIDCHandler) DeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	identityIDStr := chi.URLParam(r, "identityId")
+	identityID, err := strconv.Atoi(identityIDStr)
+	if err != nil {
+		http.Error(w, "Invalid identity ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.identityRepo.DeleteIdentity(identityID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to delete user identity: %v", err)
+		http.Error(w, "Failed to unlink identity", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stateCookie builds the state cookie; a negative maxAge deletes it
+func (h *OIDCHandler) stateCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		// Lax still sends the cookie on the provider's top-level redirect back
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// redirectWithError sends the browser back to the login page with a message
+func (h *OIDCHandler) redirectWithError(w http.ResponseWriter, r *http.Request, message string) {
+	fragment := url.Values{}
+	fragment.Set("error", message)
+	http.Redirect(w, r, h.appURL+"/login#"+fragment.Encode(), http.StatusFound)
+}
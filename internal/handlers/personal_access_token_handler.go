@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+const (
+	// personalAccessTokenPrefix starts every personal access token, telling them
+	// apart from JWTs and making leaked tokens easy to scan for
+	personalAccessTokenPrefix = "plt_"
+	// personalAccessTokenPrefixLength is how much of a token is kept to identify it in listings
+	personalAccessTokenPrefixLength = 12
+	// maxTokenNameLength matches the name column
+	maxTokenNameLength = 100
+)
+
+// CreatedPersonalAccessToken is the response to creating a token. The token
+// itself is only returned once.
+type CreatedPersonalAccessToken struct {
+	database.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// PersonalAccessTokenHandler handles HTTP requests for managing personal access tokens
+type PersonalAccessTokenHandler struct {
+	patRepo *database.PersonalAccessTokenRepository
+	logger  *log.Logger
+}
+
+// NewPersonalAccessTokenHandler creates a new PersonalAccessTokenHandler with the given repository and logger
+func NewPersonalAccessTokenHandler(patRepo *database.PersonalAccessTokenRepository, logger *log.Logger) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		patRepo: patRepo,
+		logger:  logger,
+	}
+}
+
+// GetTokens lists the current user's personal access tokens
+func (h *PersonalAccessTokenHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	tokens, err := h.patRepo.GetTokens(userID)
+	if err != nil {
+		h.logger.Printf("Failed to get personal access tokens: %v", err)
+		http.Error(w, "Failed to get tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateToken creates a personal access token. Tokens are read-only unless
+// granted bands:write, and never expire unless expires_at is given.
+func (h *PersonalAccessTokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.CreatePersonalAccessTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxTokenNameLength {
+		http.Error(w, "Name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []database.TokenScope{database.ScopeRead}
+	}
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			http.Error(w, "Scopes must be read or bands:write", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := generateRandomString(32)
+	if err != nil {
+		h.logger.Printf("Failed to generate personal access token: %v", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	token := personalAccessTokenPrefix + strings.TrimRight(secret, "=")
+
+	pat, err := h.patRepo.CreateToken(userID, req, token, token[:personalAccessTokenPrefixLength])
+	if err != nil {
+		h.logger.Printf("Failed to create personal access token: %v", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedPersonalAccessToken{PersonalAccessToken: *pat, Token: token})
+}
+
+// DeleteToken revokes one of the current user's personal access tokens
+func (h *PersonalAccessTokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	tokenIDStr := chi.URLParam(r, "tokenId")
+	tokenID, err := strconv.Atoi(tokenIDStr)
+	if err != nil {
+		http.Error(w, "Invalid token ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.patRepo.DeleteToken(tokenID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to delete personal access token: %v", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+// autocompleteLimit caps the number of autocomplete suggestions returned
+const autocompleteLimit = 10
+
+// PlaylistHandler handles HTTP requests for the shared song request queue
+type PlaylistHandler struct {
+	entryRepo *database.PlaylistEntryRepository
+	logger    *log.Logger
+}
+
+// NewPlaylistHandler creates a new PlaylistHandler with the given repository
+func NewPlaylistHandler(entryRepo *database.PlaylistEntryRepository, logger *log.Logger) *PlaylistHandler {
+	return &PlaylistHandler{
+		entryRepo: entryRepo,
+		logger:    logger,
+	}
+}
+
+// GetPlaylist returns all entries in the queue
+func (h *PlaylistHandler) GetPlaylist(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.entryRepo.GetEntries()
+	if err != nil {
+		h.logger.Printf("Failed to get playlist: %v", err)
+		http.Error(w, "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AddToPlaylist adds a new entry to the queue
+func (h *PlaylistHandler) AddToPlaylist(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+
+	var req database.CreatePlaylistEntryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Artist == "" || req.Song == "" || req.UserName == "" {
+		http.Error(w, "Artist, song, and user name are required", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.entryRepo.CreateEntry(userID, req)
+	if err != nil {
+		h.logger.Printf("Failed to add playlist entry: %v", err)
+		http.Error(w, "Failed to add playlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetPlaylistEntry returns a specific entry by ID
+func (h *PlaylistHandler) GetPlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.entryRepo.GetEntryByID(id)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist entry: %v", err)
+		http.Error(w, "Failed to get playlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	if entry == nil {
+		http.Error(w, "Playlist entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdatePlaylistEntry updates a specific entry
+func (h *PlaylistHandler) UpdatePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.UpdatePlaylistEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.Artist == "" || req.Song == "" || req.UserName == "" {
+		http.Error(w, "Artist, song, and user name are required", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.entryRepo.UpdateEntry(id, req)
+	if err != nil {
+		h.logger.Printf("Failed to update playlist entry: %v", err)
+		http.Error(w, "Failed to update playlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	if entry == nil {
+		http.Error(w, "Playlist entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeletePlaylistEntry deletes a specific entry
+func (h *PlaylistHandler) DeletePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.entryRepo.DeleteEntry(id)
+	if err != nil {
+		h.logger.Printf("Failed to delete playlist entry: %v", err)
+		http.Error(w, "Failed to delete playlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Playlist entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetArtists handles the artist autocomplete endpoint
+func (h *PlaylistHandler) GetArtists(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		// Return empty array if no query provided
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]string{})
+		return
+	}
+
+	artists, err := h.entryRepo.GetArtists(query, autocompleteLimit)
+	if err != nil {
+		h.logger.Printf("Failed to get artists: %v", err)
+		http.Error(w, "Failed to get artists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artists)
+}
+
+// GetUserNames handles the user name autocomplete endpoint
+func (h *PlaylistHandler) GetUserNames(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		// Return empty array if no query provided
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]string{})
+		return
+	}
+
+	userNames, err := h.entryRepo.GetUserNames(query, autocompleteLimit)
+	if err != nil {
+		h.logger.Printf("Failed to get user names: %v", err)
+		http.Error(w, "Failed to get user names", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userNames)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+// ReadinessHandler handles HTTP requests for how ready band members are to
+// play the band's songs
+type ReadinessHandler struct {
+	readinessRepo *database.ReadinessRepository
+	logger        *log.Logger
+}
+
+// NewReadinessHandler creates a new ReadinessHandler with the given repository
+func NewReadinessHandler(readinessRepo *database.ReadinessRepository, logger *log.Logger) *ReadinessHandler {
+	return &ReadinessHandler{
+		readinessRepo: readinessRepo,
+		logger:        logger,
+	}
+}
+
+// GetReport returns how ready the band is to play the setlists of its
+// upcoming gigs, with the songs at risk
+func (h *ReadinessHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.readinessRepo.GetReadinessReport(bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get readiness report: %v", err)
+		http.Error(w, "Failed to get readiness report", http.StatusInternalServerError)
+		return
+	}
+
+	if report == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetPlaylistReadiness returns how ready the band is to play the songs of a playlist
+func (h *ReadinessHandler) GetPlaylistReadiness(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	playlistIDStr := chi.URLParam(r, "playlistId")
+	playlistID, err := strconv.Atoi(playlistIDStr)
+	if err != nil {
+		http.Error(w, "Invalid playlist ID format", http.StatusBadRequest)
+		return
+	}
+
+	readiness, err := h.readinessRepo.GetPlaylistReadiness(playlistID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get playlist readiness: %v", err)
+		http.Error(w, "Failed to get playlist readiness", http.StatusInternalServerError)
+		return
+	}
+
+	if readiness == nil {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readiness)
+}
+
+// GetSongReadiness returns how ready each band member is to play a catalog song
+func (h *ReadinessHandler) GetSongReadiness(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	readiness, err := h.readinessRepo.GetSongReadiness(songID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get song readiness: %v", err)
+		http.Error(w, "Failed to get song readiness", http.StatusInternalServerError)
+		return
+	}
+
+	if readiness == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readiness)
+}
+
+// SetSongReadiness records how ready a band member is to play a catalog song
+func (h *ReadinessHandler) SetSongReadiness(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	songIDStr := chi.URLParam(r, "songId")
+	songID, err := strconv.Atoi(songIDStr)
+	if err != nil {
+		http.Error(w, "Invalid song ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.SetReadinessRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if !req.Status.Valid() {
+		http.Error(w, "Status must be learning, rough, needs_work or solid", http.StatusBadRequest)
+		return
+	}
+	if req.LastRehearsedAt != nil && req.LastRehearsedAt.After(time.Now()) {
+		http.Error(w, "Last rehearsed time cannot be in the future", http.StatusBadRequest)
+		return
+	}
+
+	readiness, err := h.readinessRepo.SetReadiness(songID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		if errors.Is(err, database.ErrBandMemberNotFound) {
+			http.Error(w, "Band member not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Printf("Failed to set song readiness: %v", err)
+		http.Error(w, "Failed to set song readiness", http.StatusInternalServerError)
+		return
+	}
+
+	if readiness == nil {
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readiness)
+}
@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nahue/playlists/internal/database"
+)
+
+// RehearsalHandler handles HTTP requests for a band's rehearsals and their
+// availability polls
+type RehearsalHandler struct {
+	rehearsalRepo *database.RehearsalRepository
+	logger        *log.Logger
+}
+
+// NewRehearsalHandler creates a new RehearsalHandler with the given repository
+func NewRehearsalHandler(rehearsalRepo *database.RehearsalRepository, logger *log.Logger) *RehearsalHandler {
+	return &RehearsalHandler{
+		rehearsalRepo: rehearsalRepo,
+		logger:        logger,
+	}
+}
+
+// writeRehearsalError responds to the errors of rehearsal operations that
+// are the client's doing and reports whether it did
+func writeRehearsalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case writeForbidden(w, err):
+	case errors.Is(err, database.ErrRehearsalClosed):
+		http.Error(w, "Rehearsal is no longer open", http.StatusConflict)
+	case errors.Is(err, database.ErrNoAvailableSlot):
+		http.Error(w, "No member can make any of the slots", http.StatusConflict)
+	case errors.Is(err, database.ErrTooManySlots):
+		http.Error(w, fmt.Sprintf("Rehearsals can have at most %d slots", database.MaxRehearsalSlots), http.StatusConflict)
+	case errors.Is(err, database.ErrSlotNotFound):
+		http.Error(w, "Slot not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrBandMemberNotFound):
+		http.Error(w, "Band member not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrPlaylistSongNotFound):
+		http.Error(w, "Playlist song not found", http.StatusNotFound)
+	default:
+		return false
+	}
+	return true
+}
+
+// rehearsalParams reads the band and rehearsal IDs of a request, responding
+// with 400 when they are malformed
+func rehearsalParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	bandID, err := strconv.Atoi(chi.URLParam(r, "bandId"))
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	rehearsalID, err := strconv.Atoi(chi.URLParam(r, "rehearsalId"))
+	if err != nil {
+		http.Error(w, "Invalid rehearsal ID format", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return bandID, rehearsalID, true
+}
+
+// GetRehearsals returns the rehearsals of a band. The when query parameter
+// lists only upcoming or past rehearsals.
+func (h *RehearsalHandler) GetRehearsals(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	period := database.EventPeriod(r.URL.Query().Get("when"))
+	if period != "" && period != database.EventsUpcoming && period != database.EventsPast {
+		http.Error(w, "When must be upcoming or past", http.StatusBadRequest)
+		return
+	}
+
+	rehearsals, err := h.rehearsalRepo.GetRehearsals(bandID, userID, period)
+	if err != nil {
+		h.logger.Printf("Failed to get rehearsals: %v", err)
+		http.Error(w, "Failed to get rehearsals", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsals == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsals)
+}
+
+// GetRehearsal returns a rehearsal with its poll and agenda
+func (h *RehearsalHandler) GetRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	rehearsal, err := h.rehearsalRepo.GetRehearsal(rehearsalID, bandID, userID)
+	if err != nil {
+		h.logger.Printf("Failed to get rehearsal: %v", err)
+		http.Error(w, "Failed to get rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// CreateRehearsal proposes a rehearsal at one or more candidate times
+func (h *RehearsalHandler) CreateRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandIDStr := chi.URLParam(r, "bandId")
+	bandID, err := strconv.Atoi(bandIDStr)
+	if err != nil {
+		http.Error(w, "Invalid band ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req database.CreateRehearsalRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	err = req.RehearsalDetails.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Slots) == 0 || len(req.Slots) > database.MaxRehearsalSlots {
+		http.Error(w, fmt.Sprintf("Rehearsals need between 1 and %d slots", database.MaxRehearsalSlots), http.StatusBadRequest)
+		return
+	}
+	for _, slot := range req.Slots {
+		if slot.IsZero() {
+			http.Error(w, "Slots must be start times", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rehearsal, err := h.rehearsalRepo.CreateRehearsal(bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to create rehearsal: %v", err)
+		http.Error(w, "Failed to create rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Band not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// UpdateRehearsal updates the details of a rehearsal
+func (h *RehearsalHandler) UpdateRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req database.UpdateRehearsalRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	err = req.RehearsalDetails.Normalize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rehearsal, err := h.rehearsalRepo.UpdateRehearsal(rehearsalID, bandID, userID, req)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to update rehearsal: %v", err)
+		http.Error(w, "Failed to update rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// CancelRehearsal calls off a rehearsal
+func (h *RehearsalHandler) CancelRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	rehearsal, err := h.rehearsalRepo.CancelRehearsal(rehearsalID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to cancel rehearsal: %v", err)
+		http.Error(w, "Failed to cancel rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// DeleteRehearsal removes a rehearsal with its poll and agenda
+func (h *RehearsalHandler) DeleteRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.rehearsalRepo.DeleteRehearsal(rehearsalID, bandID, userID)
+	if err != nil {
+		if writeForbidden(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to delete rehearsal: %v", err)
+		http.Error(w, "Failed to delete rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddSlot adds a candidate time to the poll of a rehearsal
+func (h *RehearsalHandler) AddSlot(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req database.AddSlotRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if req.StartsAt.IsZero() {
+		http.Error(w, "Start time is required", http.StatusBadRequest)
+		return
+	}
+
+	slot, err := h.rehearsalRepo.AddSlot(rehearsalID, bandID, userID, req)
+	if err != nil {
+		if writeRehearsalError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to add rehearsal slot: %v", err)
+		http.Error(w, "Failed to add slot", http.StatusInternalServerError)
+		return
+	}
+
+	if slot == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(slot)
+}
+
+// DeleteSlot removes a candidate time, with its answers, from the poll of a rehearsal
+func (h *RehearsalHandler) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	slotIDStr := chi.URLParam(r, "slotId")
+	slotID, err := strconv.Atoi(slotIDStr)
+	if err != nil {
+		http.Error(w, "Invalid slot ID format", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.rehearsalRepo.DeleteSlot(slotID, rehearsalID, bandID, userID)
+	if err != nil {
+		if writeRehearsalError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to delete rehearsal slot: %v", err)
+		http.Error(w, "Failed to delete slot", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Slot not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetAvailability records whether a band member can make the slots of a
+// rehearsal. Members answer for themselves unless an editor gives member_id.
+func (h *RehearsalHandler) SetAvailability(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req database.AvailabilityRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if len(req.Answers) == 0 {
+		http.Error(w, "Answers are required", http.StatusBadRequest)
+		return
+	}
+	for _, answer := range req.Answers {
+		if !answer.Answer.Valid() {
+			http.Error(w, "Answers must be yes, maybe or no", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rehearsal, err := h.rehearsalRepo.SetAvailability(rehearsalID, bandID, userID, req)
+	if err != nil {
+		if writeRehearsalError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to set availability: %v", err)
+		http.Error(w, "Failed to set availability", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// ScheduleRehearsal closes the poll of a rehearsal and schedules it at the
+// given slot, or at the best one
+func (h *RehearsalHandler) ScheduleRehearsal(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req database.ScheduleRehearsalRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rehearsal, err := h.rehearsalRepo.ScheduleRehearsal(rehearsalID, bandID, userID, req)
+	if err != nil {
+		if writeRehearsalError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to schedule rehearsal: %v", err)
+		http.Error(w, "Failed to schedule rehearsal", http.StatusInternalServerError)
+		return
+	}
+
+	if rehearsal == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rehearsal)
+}
+
+// SetAgenda replaces the agenda of a rehearsal
+func (h *RehearsalHandler) SetAgenda(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	bandID, rehearsalID, ok := rehearsalParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req database.SetAgendaRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	for _, item := range req.Items {
+		if item.PlaylistSongID == nil && strings.TrimSpace(item.Title) == "" {
+			http.Error(w, "Agenda items need a playlist song or a title", http.StatusBadRequest)
+			return
+		}
+		if item.Duration != nil && *item.Duration <= 0 {
+			http.Error(w, "Agenda item durations must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+
+	agenda, err := h.rehearsalRepo.SetAgenda(rehearsalID, bandID, userID, req)
+	if err != nil {
+		if writeRehearsalError(w, err) {
+			return
+		}
+		h.logger.Printf("Failed to set rehearsal agenda: %v", err)
+		http.Error(w, "Failed to set agenda", http.StatusInternalServerError)
+		return
+	}
+
+	if agenda == nil {
+		http.Error(w, "Rehearsal not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agenda)
+}
@@ -0,0 +1,186 @@
+// Package ical writes iCalendar (RFC 5545) feeds that calendar apps can
+// subscribe to. Events keep the timezone they are given, and the feed
+// describes each timezone it uses so apps need no timezone database of
+// their own.
+package ical
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// maxLineLength is the length in octets content lines are folded at
+const maxLineLength = 75
+
+// refreshInterval is how often subscribed apps are asked to reload the feed
+const refreshInterval = "PT1H"
+
+const (
+	utcFormat   = "20060102T150405Z"
+	localFormat = "20060102T150405"
+)
+
+// Calendar is a feed of events
+type Calendar struct {
+	// ProductID identifies the application that wrote the feed
+	ProductID string
+	// Name is the name calendar apps show for the feed
+	Name   string
+	Events []Event
+}
+
+// Event is an event in a calendar. UID must stay the same for the life of
+// the event, and Sequence must grow whenever it is changed, so apps update
+// their copy instead of adding another.
+type Event struct {
+	UID         string
+	Sequence    int
+	Status      string
+	Summary     string
+	Location    string
+	Description string
+	// Start is written in its location. Events in UTC are written as UTC times.
+	Start time.Time
+	// End is optional; an event without one ends when it starts
+	End      *time.Time
+	Created  time.Time
+	Modified time.Time
+}
+
+// WriteTo writes the calendar as an iCalendar object
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeLine(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProductID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	line("REFRESH-INTERVAL;VALUE=DURATION", refreshInterval)
+	line("X-PUBLISHED-TTL", refreshInterval)
+
+	for _, tz := range c.timezones() {
+		tz.write(&b)
+	}
+
+	for _, event := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeText(event.UID))
+		line("DTSTAMP", event.Modified.UTC().Format(utcFormat))
+		line("CREATED", event.Created.UTC().Format(utcFormat))
+		line("LAST-MODIFIED", event.Modified.UTC().Format(utcFormat))
+		line("SEQUENCE", strconv.Itoa(event.Sequence))
+		writeLine(&b, "DTSTART"+dateTime(event.Start))
+		if event.End != nil {
+			writeLine(&b, "DTEND"+dateTime(*event.End))
+		}
+		if event.Status != "" {
+			line("STATUS", event.Status)
+		}
+		line("SUMMARY", escapeText(event.Summary))
+		if event.Location != "" {
+			line("LOCATION", escapeText(event.Location))
+		}
+		if event.Description != "" {
+			line("DESCRIPTION", escapeText(event.Description))
+		}
+		if event.Status == StatusCancelled {
+			line("TRANSP", "TRANSPARENT")
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return b.WriteTo(w)
+}
+
+// dateTime returns the parameters and value of a date-time property: a UTC
+// time, or a local time with the timezone it is in
+func dateTime(t time.Time) string {
+	if isUTC(t.Location()) {
+		return ":" + t.UTC().Format(utcFormat)
+	}
+	return ";TZID=" + t.Location().String() + ":" + t.Format(localFormat)
+}
+
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// escapeText escapes a text value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// writeLine writes a content line, folding it so no line is longer than
+// maxLineLength octets. Lines are only folded between characters.
+func writeLine(b *bytes.Buffer, s string) {
+	limit := maxLineLength
+	for len(s) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		b.WriteString(s[:n] + "\r\n ")
+		s = s[n:]
+		// Continuation lines start with a space
+		limit = maxLineLength - 1
+	}
+	b.WriteString(s + "\r\n")
+}
+
+// timezones returns the timezones the events are in, each covering the
+// years from the first to the last event in it
+func (c *Calendar) timezones() []timezone {
+	byName := map[string]*timezone{}
+	var names []string
+	for _, event := range c.Events {
+		times := []time.Time{event.Start}
+		if event.End != nil {
+			times = append(times, *event.End)
+		}
+		for _, t := range times {
+			if isUTC(t.Location()) {
+				continue
+			}
+			name := t.Location().String()
+			tz, ok := byName[name]
+			if !ok {
+				tz = &timezone{location: t.Location(), from: t, to: t}
+				byName[name] = tz
+				names = append(names, name)
+			}
+			if t.Before(tz.from) {
+				tz.from = t
+			}
+			if t.After(tz.to) {
+				tz.to = t
+			}
+		}
+	}
+
+	sort.Strings(names)
+	timezones := make([]timezone, len(names))
+	for i, name := range names {
+		timezones[i] = *byName[name]
+	}
+	return timezones
+}
@@ -0,0 +1,124 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTo(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+
+	modified := time.Date(2025, time.October, 1, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.November, 21, 23, 30, 0, 0, madrid)
+	cal := Calendar{
+		ProductID: "-//Test//EN",
+		Name:      "The Band",
+		Events: []Event{
+			{
+				UID:         "gig-1@example.com",
+				Sequence:    2,
+				Status:      StatusConfirmed,
+				Summary:     "Album launch, night one",
+				Location:    "Sala Apolo; Barcelona",
+				Description: "Load-in 17:00\nSet 22:00",
+				Start:       time.Date(2025, time.November, 21, 21, 0, 0, 0, madrid),
+				End:         &end,
+				Created:     modified,
+				Modified:    modified,
+			},
+			{
+				UID:      "gig-2@example.com",
+				Status:   StatusCancelled,
+				Summary:  "Festival",
+				Start:    time.Date(2025, time.July, 4, 18, 0, 0, 0, time.UTC),
+				Created:  modified,
+				Modified: modified,
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	if _, err := cal.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo returned error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\n",
+		"X-WR-CALNAME:The Band\r\n",
+		"UID:gig-1@example.com\r\nDTSTAMP:20251001T120000Z\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTART;TZID=Europe/Madrid:20251121T210000\r\nDTEND;TZID=Europe/Madrid:20251121T233000\r\nSTATUS:CONFIRMED\r\n",
+		`SUMMARY:Album launch\, night one` + "\r\n",
+		`LOCATION:Sala Apolo\; Barcelona` + "\r\n",
+		`DESCRIPTION:Load-in 17:00\nSet 22:00` + "\r\n",
+		"DTSTART:20250704T180000Z\r\nSTATUS:CANCELLED\r\n",
+		"TRANSP:TRANSPARENT\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("expected one VTIMEZONE for Europe/Madrid:\n%s", out)
+	}
+}
+
+func TestTimezoneObservances(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+
+	start := time.Date(2025, time.June, 1, 21, 0, 0, 0, madrid)
+	var b bytes.Buffer
+	timezone{location: madrid, from: start, to: start}.write(&b)
+
+	want := "BEGIN:VTIMEZONE\r\n" +
+		"TZID:Europe/Madrid\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20241027T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n" +
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20251026T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+	if got := b.String(); got != want {
+		t.Errorf("unexpected timezone:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTimezoneWithoutChanges(t *testing.T) {
+	var b bytes.Buffer
+	zone := time.FixedZone("X", -(3*3600 + 30*60))
+	start := time.Date(2025, time.June, 1, 21, 0, 0, 0, zone)
+	timezone{location: zone, from: start, to: start}.write(&b)
+
+	if !strings.Contains(b.String(), "DTSTART:19700101T000000\r\nTZOFFSETFROM:-0330\r\nTZOFFSETTO:-0330\r\n") {
+		t.Errorf("unexpected timezone:\n%s", b.String())
+	}
+}
+
+func TestWriteLineFolds(t *testing.T) {
+	var b bytes.Buffer
+	writeLine(&b, "DESCRIPTION:"+strings.Repeat("é", 60))
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two lines, got %q", lines)
+	}
+	for i, line := range lines {
+		if len(line) > maxLineLength {
+			t.Errorf("line %d is %d octets long", i, len(line))
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+	if unfolded := strings.ReplaceAll(b.String(), "\r\n ", ""); unfolded != "DESCRIPTION:"+strings.Repeat("é", 60)+"\r\n" {
+		t.Errorf("unfolded line = %q", unfolded)
+	}
+}
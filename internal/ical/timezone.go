@@ -0,0 +1,88 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// timezone is a timezone used by a calendar and the span of time its events
+// fall in
+type timezone struct {
+	location *time.Location
+	from, to time.Time
+}
+
+// observance is a period of a timezone with one UTC offset
+type observance struct {
+	start      time.Time
+	name       string
+	offsetFrom int
+	offsetTo   int
+	daylight   bool
+}
+
+// observances returns the offset in effect at the start of the year of the
+// first event and every change of offset until the end of the year of the
+// last event, as recorded in the Go timezone database
+func (tz timezone) observances() []observance {
+	t := time.Date(tz.from.Year(), time.January, 1, 0, 0, 0, 0, tz.location)
+	end := time.Date(tz.to.Year()+1, time.January, 1, 0, 0, 0, 0, tz.location)
+
+	start, next := t.ZoneBounds()
+	name, offset := t.Zone()
+	first := observance{start: start, name: name, offsetFrom: offset, offsetTo: offset, daylight: t.IsDST()}
+	if start.IsZero() {
+		// The zone has always had this offset
+		first.start = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.FixedZone("", offset))
+	} else {
+		_, first.offsetFrom = start.Add(-time.Second).Zone()
+	}
+	observances := []observance{first}
+
+	for !next.IsZero() && next.Before(end) {
+		t = next.In(tz.location)
+		name, offset := t.Zone()
+		_, offsetFrom := next.Add(-time.Second).In(tz.location).Zone()
+		observances = append(observances, observance{start: t, name: name, offsetFrom: offsetFrom, offsetTo: offset, daylight: t.IsDST()})
+		_, next = t.ZoneBounds()
+	}
+	return observances
+}
+
+// write writes the timezone as a VTIMEZONE component
+func (tz timezone) write(b *bytes.Buffer) {
+	writeLine(b, "BEGIN:VTIMEZONE")
+	writeLine(b, "TZID:"+tz.location.String())
+	for _, o := range tz.observances() {
+		kind := "STANDARD"
+		if o.daylight {
+			kind = "DAYLIGHT"
+		}
+		writeLine(b, "BEGIN:"+kind)
+		// Onsets are written in the local time they replace
+		writeLine(b, "DTSTART:"+o.start.In(time.FixedZone("", o.offsetFrom)).Format(localFormat))
+		writeLine(b, "TZOFFSETFROM:"+utcOffset(o.offsetFrom))
+		writeLine(b, "TZOFFSETTO:"+utcOffset(o.offsetTo))
+		if o.name != "" {
+			writeLine(b, "TZNAME:"+escapeText(o.name))
+		}
+		writeLine(b, "END:"+kind)
+	}
+	writeLine(b, "END:VTIMEZONE")
+}
+
+// utcOffset formats an offset in seconds east of UTC as +hhmm, or +hhmmss
+// when it is not a whole number of minutes
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
@@ -0,0 +1,425 @@
+// Package live keeps the state of live setlist sessions, where one band
+// member drives a playlist during a show and everyone else follows, and
+// fans every change out to the subscribed clients.
+package live
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoSession is returned when changing a playlist that has no live session
+	ErrNoSession = errors.New("playlist has no live session")
+
+	// ErrNotLeader is returned when someone other than the leader changes a live session
+	ErrNotLeader = errors.New("only the leader can change the live session")
+
+	// ErrSongNotFound is returned when an operation references a song outside the live session
+	ErrSongNotFound = errors.New("song is not in the live session")
+)
+
+// Event types. Every event carries the whole state of the session after it.
+const (
+	// EventState is sent to clients that connect or cannot resume where they left off
+	EventState   = "state"
+	EventStarted = "started"
+	EventLeader  = "leader"
+	EventCurrent = "current"
+	EventNext    = "next"
+	EventSkipped = "skipped"
+	EventEncore  = "encore"
+	EventEnded   = "ended"
+)
+
+const (
+	// historySize is how many events a session keeps for clients that resume
+	historySize = 256
+	// subscriberBuffer is how many events a subscriber can fall behind
+	// before it is dropped and has to resume
+	subscriberBuffer = 16
+)
+
+// Song is a song of a live session. IDs are given by the session, so a song
+// played twice, such as an encore reprise, has two IDs.
+type Song struct {
+	ID             int    `json:"id"`
+	PlaylistSongID *int   `json:"playlist_song_id"`
+	BandSongID     int    `json:"band_song_id"`
+	Artist         string `json:"artist"`
+	Title          string `json:"title"`
+	SongKey        string `json:"song_key"`
+	Encore         bool   `json:"encore"`
+	Skipped        bool   `json:"skipped"`
+}
+
+// State is the state of the live session of a playlist. LeaderID is the
+// user driving the session.
+type State struct {
+	PlaylistID int       `json:"playlist_id"`
+	Active     bool      `json:"active"`
+	LeaderID   int       `json:"leader_id"`
+	Songs      []Song    `json:"songs"`
+	CurrentID  *int      `json:"current_id"`
+	NextID     *int      `json:"next_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// clone returns a copy of the state that later changes do not affect
+func (s State) clone() State {
+	s.Songs = append([]Song{}, s.Songs...)
+	if s.CurrentID != nil {
+		id := *s.CurrentID
+		s.CurrentID = &id
+	}
+	if s.NextID != nil {
+		id := *s.NextID
+		s.NextID = &id
+	}
+	return s
+}
+
+// index returns the position of a song in the session, or -1
+func (s *State) index(songID int) int {
+	for i, song := range s.Songs {
+		if song.ID == songID {
+			return i
+		}
+	}
+	return -1
+}
+
+// following returns the first song that is not skipped after the given
+// one, or after the start when after is nil
+func (s *State) following(after *int) *int {
+	start := 0
+	if after != nil {
+		start = s.index(*after) + 1
+	}
+	for _, song := range s.Songs[start:] {
+		if !song.Skipped {
+			id := song.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+// Event is a change of a live session. IDs increase within a session and
+// are what clients send back as Last-Event-ID to resume.
+type Event struct {
+	ID    string
+	Type  string
+	State State
+	seq   int64
+}
+
+// Subscription receives the events of a playlist's live session. Events is
+// closed when the subscriber falls too far behind; it should reconnect and
+// resume from the last event it got.
+type Subscription struct {
+	Events     <-chan Event
+	events     chan Event
+	hub        *Hub
+	playlistID int
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	room := s.hub.rooms[s.playlistID]
+	if room == nil {
+		return
+	}
+	if _, ok := room.subscribers[s]; ok {
+		delete(room.subscribers, s)
+		close(s.events)
+	}
+	s.hub.evict(s.playlistID)
+}
+
+// room is the live session of a playlist with its subscribers. epoch tells
+// event IDs of this room apart from those of an earlier room of the
+// playlist, such as one from before a restart.
+type room struct {
+	epoch       string
+	seq         int64
+	nextSongID  int
+	state       State
+	history     []Event
+	subscribers map[*Subscription]struct{}
+}
+
+// Hub holds the live sessions of all playlists. It is safe for concurrent use.
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[int]*room
+}
+
+// NewHub creates a hub without live sessions
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[int]*room)}
+}
+
+// room returns the room of a playlist, creating it when needed. The caller
+// holds the lock.
+func (h *Hub) room(playlistID int) *room {
+	r := h.rooms[playlistID]
+	if r == nil {
+		r = &room{
+			epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+			state:       State{PlaylistID: playlistID, Songs: []Song{}},
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		h.rooms[playlistID] = r
+	}
+	return r
+}
+
+// evict drops the room of a playlist once it has no session and no
+// subscribers. The caller holds the lock.
+func (h *Hub) evict(playlistID int) {
+	r := h.rooms[playlistID]
+	if r != nil && !r.state.Active && len(r.subscribers) == 0 {
+		delete(h.rooms, playlistID)
+	}
+}
+
+// publish records a change of a room and sends it to its subscribers,
+// dropping those that fell behind. The caller holds the lock.
+func (r *room) publish(eventType string) Event {
+	r.seq++
+	r.state.UpdatedAt = time.Now()
+	event := Event{ID: r.eventID(r.seq), Type: eventType, State: r.state.clone(), seq: r.seq}
+
+	r.history = append(r.history, event)
+	if len(r.history) > historySize {
+		r.history = r.history[len(r.history)-historySize:]
+	}
+
+	for sub := range r.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(r.subscribers, sub)
+			close(sub.events)
+		}
+	}
+	return event
+}
+
+// eventID returns the ID of the event with the given sequence number
+func (r *room) eventID(seq int64) string {
+	return r.epoch + "-" + strconv.FormatInt(seq, 10)
+}
+
+// since returns the events after the one with the given ID. ok is false
+// when the ID is not one of this room's recent events.
+func (r *room) since(lastEventID string) (events []Event, ok bool) {
+	epoch, seqStr, found := strings.Cut(lastEventID, "-")
+	if !found || epoch != r.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseInt(seqStr, 10, 64)
+	if err != nil || seq > r.seq {
+		return nil, false
+	}
+	if seq == r.seq {
+		return nil, true
+	}
+	if len(r.history) == 0 || seq < r.history[0].seq-1 {
+		return nil, false
+	}
+
+	for _, event := range r.history {
+		if event.seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+// Subscribe follows the live session of a playlist, whether or not it has
+// started. The returned backlog holds the events after lastEventID, or a
+// snapshot of the state when the client cannot resume from there.
+func (h *Hub) Subscribe(playlistID int, lastEventID string) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.room(playlistID)
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, hub: h, playlistID: playlistID}
+	r.subscribers[sub] = struct{}{}
+
+	if lastEventID != "" {
+		if backlog, ok := r.since(lastEventID); ok {
+			return sub, backlog
+		}
+	}
+
+	snapshot := Event{Type: EventState, State: r.state.clone(), seq: r.seq}
+	if r.seq > 0 {
+		snapshot.ID = r.eventID(r.seq)
+	}
+	return sub, []Event{snapshot}
+}
+
+// State returns the state of a playlist's live session and whether it is active
+func (h *Hub) State(playlistID int) (State, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.rooms[playlistID]
+	if r == nil {
+		return State{PlaylistID: playlistID, Songs: []Song{}}, false
+	}
+	return r.state.clone(), r.state.Active
+}
+
+// Start starts the live session of a playlist with its songs in order and
+// the given user as leader. When the session is already live, the user
+// takes over as leader and the session carries on.
+func (h *Hub) Start(playlistID, leaderID int, songs []Song) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.room(playlistID)
+	if r.state.Active {
+		r.state.LeaderID = leaderID
+		return r.publish(EventLeader)
+	}
+
+	r.nextSongID = 0
+	r.state = State{PlaylistID: playlistID, Active: true, LeaderID: leaderID, Songs: make([]Song, 0, len(songs))}
+	for _, song := range songs {
+		r.nextSongID++
+		song.ID = r.nextSongID
+		r.state.Songs = append(r.state.Songs, song)
+	}
+	r.state.CurrentID = r.state.following(nil)
+	r.state.NextID = r.state.following(r.state.CurrentID)
+	return r.publish(EventStarted)
+}
+
+// lead returns the room of a playlist's live session when the user leads it.
+// The caller holds the lock.
+func (h *Hub) lead(playlistID, userID int) (*room, error) {
+	r := h.rooms[playlistID]
+	if r == nil || !r.state.Active {
+		return nil, ErrNoSession
+	}
+	if r.state.LeaderID != userID {
+		return nil, ErrNotLeader
+	}
+	return r, nil
+}
+
+// SetCurrent marks the song being played. The next song becomes the first
+// one after it that is not skipped.
+func (h *Hub) SetCurrent(playlistID, userID, songID int) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, err := h.lead(playlistID, userID)
+	if err != nil {
+		return Event{}, err
+	}
+	i := r.state.index(songID)
+	if i < 0 {
+		return Event{}, ErrSongNotFound
+	}
+
+	r.state.Songs[i].Skipped = false
+	r.state.CurrentID = &songID
+	r.state.NextID = r.state.following(&songID)
+	return r.publish(EventCurrent), nil
+}
+
+// SetNext marks the song to be played after the current one
+func (h *Hub) SetNext(playlistID, userID, songID int) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, err := h.lead(playlistID, userID)
+	if err != nil {
+		return Event{}, err
+	}
+	i := r.state.index(songID)
+	if i < 0 {
+		return Event{}, ErrSongNotFound
+	}
+
+	r.state.Songs[i].Skipped = false
+	r.state.NextID = &songID
+	return r.publish(EventNext), nil
+}
+
+// Skip leaves a song out. Skipping the current song moves on to the one
+// after it.
+func (h *Hub) Skip(playlistID, userID, songID int) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, err := h.lead(playlistID, userID)
+	if err != nil {
+		return Event{}, err
+	}
+	i := r.state.index(songID)
+	if i < 0 {
+		return Event{}, ErrSongNotFound
+	}
+
+	r.state.Songs[i].Skipped = true
+	if current := r.state.CurrentID; current != nil && *current == songID {
+		r.state.CurrentID = r.state.following(current)
+		r.state.NextID = r.state.following(r.state.CurrentID)
+	} else if next := r.state.NextID; next != nil && *next == songID {
+		r.state.NextID = r.state.following(r.state.CurrentID)
+	}
+	return r.publish(EventSkipped), nil
+}
+
+// InsertEncore adds a song to the end of the session as an encore. It is
+// up next when nothing else is.
+func (h *Hub) InsertEncore(playlistID, userID int, song Song) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, err := h.lead(playlistID, userID)
+	if err != nil {
+		return Event{}, err
+	}
+
+	r.nextSongID++
+	song.ID = r.nextSongID
+	song.Encore = true
+	song.Skipped = false
+	r.state.Songs = append(r.state.Songs, song)
+	if r.state.NextID == nil {
+		id := song.ID
+		r.state.NextID = &id
+	}
+	return r.publish(EventEncore), nil
+}
+
+// End ends the live session of a playlist. Subscribers stay connected and
+// follow the next session of the playlist.
+func (h *Hub) End(playlistID int) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.rooms[playlistID]
+	if r == nil || !r.state.Active {
+		return Event{}, ErrNoSession
+	}
+
+	r.state.Active = false
+	event := r.publish(EventEnded)
+	h.evict(playlistID)
+	return event, nil
+}
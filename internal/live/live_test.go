@@ -0,0 +1,221 @@
+package live
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func testSongs() []Song {
+	return []Song{
+		{BandSongID: 10, Artist: "Queen", Title: "Innuendo"},
+		{BandSongID: 11, Artist: "Queen", Title: "Bicycle Race"},
+		{BandSongID: 12, Artist: "Queen", Title: "Mustapha"},
+	}
+}
+
+func id(i int) *int { return &i }
+
+func assertPosition(t *testing.T, state State, current, next *int) {
+	t.Helper()
+	if (state.CurrentID == nil) != (current == nil) || (current != nil && *state.CurrentID != *current) {
+		t.Errorf("CurrentID = %v, want %v", deref(state.CurrentID), deref(current))
+	}
+	if (state.NextID == nil) != (next == nil) || (next != nil && *state.NextID != *next) {
+		t.Errorf("NextID = %v, want %v", deref(state.NextID), deref(next))
+	}
+}
+
+func deref(p *int) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestHubSession(t *testing.T) {
+	hub := NewHub()
+
+	event := hub.Start(1, 100, testSongs())
+	if event.Type != EventStarted || !event.State.Active || event.State.LeaderID != 100 {
+		t.Fatalf("Start = %+v", event)
+	}
+	assertPosition(t, event.State, id(1), id(2))
+
+	if _, err := hub.SetCurrent(1, 200, 2); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("SetCurrent by another user = %v, want ErrNotLeader", err)
+	}
+	if _, err := hub.SetCurrent(1, 100, 9); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("SetCurrent of an unknown song = %v, want ErrSongNotFound", err)
+	}
+	if _, err := hub.SetCurrent(2, 100, 1); !errors.Is(err, ErrNoSession) {
+		t.Errorf("SetCurrent without a session = %v, want ErrNoSession", err)
+	}
+
+	// Skipping the next song moves on to the one after it
+	event, err := hub.Skip(1, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPosition(t, event.State, id(1), id(3))
+
+	// Encores are up next once the set runs out
+	event, err = hub.SetCurrent(1, 100, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPosition(t, event.State, id(3), nil)
+	event, err = hub.InsertEncore(1, 100, Song{BandSongID: 10, Artist: "Queen", Title: "Innuendo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPosition(t, event.State, id(3), id(4))
+	if !event.State.Songs[3].Encore {
+		t.Error("inserted song is not an encore")
+	}
+
+	// Playing a skipped song brings it back
+	event, err = hub.SetCurrent(1, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.State.Songs[1].Skipped {
+		t.Error("current song is still skipped")
+	}
+	assertPosition(t, event.State, id(2), id(3))
+
+	// Starting again hands the session over without resetting it
+	event = hub.Start(1, 200, nil)
+	if event.Type != EventLeader || event.State.LeaderID != 200 || len(event.State.Songs) != 4 {
+		t.Errorf("Start of a live session = %+v", event)
+	}
+
+	if _, err := hub.End(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, active := hub.State(1); active {
+		t.Error("session is still active after End")
+	}
+	if _, err := hub.End(1); !errors.Is(err, ErrNoSession) {
+		t.Errorf("End of an ended session = %v, want ErrNoSession", err)
+	}
+}
+
+func TestHubSubscribeResume(t *testing.T) {
+	hub := NewHub()
+
+	// Clients connecting before the session starts get an empty snapshot
+	sub, backlog := hub.Subscribe(1, "")
+	if len(backlog) != 1 || backlog[0].Type != EventState || backlog[0].ID != "" || backlog[0].State.Active {
+		t.Fatalf("backlog before start = %+v", backlog)
+	}
+
+	started := hub.Start(1, 100, testSongs())
+	if got := <-sub.Events; got.ID != started.ID {
+		t.Errorf("received %q, want %q", got.ID, started.ID)
+	}
+	sub.Close()
+
+	current, _ := hub.SetCurrent(1, 100, 2)
+	skipped, _ := hub.Skip(1, 100, 3)
+
+	// Reconnecting resumes after the last event received
+	sub, backlog = hub.Subscribe(1, started.ID)
+	defer sub.Close()
+	if len(backlog) != 2 || backlog[0].ID != current.ID || backlog[1].ID != skipped.ID {
+		t.Errorf("resumed backlog = %+v", backlog)
+	}
+
+	_, backlog = hub.Subscribe(1, skipped.ID)
+	if len(backlog) != 0 {
+		t.Errorf("backlog of an up to date client = %+v", backlog)
+	}
+
+	// Unknown IDs, such as those from before a restart, get a snapshot
+	for _, lastEventID := range []string{"other-1", "garbage", current.ID + "0"} {
+		_, backlog = hub.Subscribe(1, lastEventID)
+		if len(backlog) != 1 || backlog[0].Type != EventState || backlog[0].ID != skipped.ID {
+			t.Errorf("backlog after %q = %+v", lastEventID, backlog)
+		}
+	}
+
+	// Events carry copies of the state
+	if backlog[0].State.Songs[2].Skipped != true || current.State.Songs[2].Skipped {
+		t.Error("events share state with later changes")
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	hub.Start(1, 100, testSongs())
+	sub, _ := hub.Subscribe(1, "")
+
+	var last Event
+	for i := 0; i < subscriberBuffer+1; i++ {
+		last, _ = hub.SetCurrent(1, 100, 1+i%3)
+	}
+
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events before being dropped, want %d", received, subscriberBuffer)
+	}
+	sub.Close()
+
+	// The dropped client catches up where it left off
+	_, backlog := hub.Subscribe(1, hub.rooms[1].history[len(hub.rooms[1].history)-2].ID)
+	if len(backlog) != 1 || backlog[0].ID != last.ID {
+		t.Errorf("backlog after being dropped = %+v", backlog)
+	}
+}
+
+func TestHubEvictsIdleRooms(t *testing.T) {
+	hub := NewHub()
+	sub, _ := hub.Subscribe(1, "")
+	sub.Close()
+	if len(hub.rooms) != 0 {
+		t.Error("room of an idle playlist was kept")
+	}
+
+	hub.Start(1, 100, testSongs())
+	hub.End(1)
+	if len(hub.rooms) != 0 {
+		t.Error("room of an ended session was kept")
+	}
+}
+
+func TestHubConcurrentSubscribers(t *testing.T) {
+	hub := NewHub()
+	hub.Start(1, 100, testSongs())
+
+	const subscribers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < subscribers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub, backlog := hub.Subscribe(1, "")
+			defer func() { sub.Close() }()
+			last := backlog[len(backlog)-1]
+			for last.State.Active {
+				event, ok := <-sub.Events
+				if !ok {
+					sub, backlog = hub.Subscribe(1, last.ID)
+					if len(backlog) == 0 {
+						continue
+					}
+					event = backlog[len(backlog)-1]
+				}
+				last = event
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		hub.SetCurrent(1, 100, 1+i%3)
+	}
+	hub.End(1)
+	wg.Wait()
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer writes messages to a logger instead of sending them
+type LogMailer struct {
+	from   string
+	logger *log.Logger
+}
+
+// NewLogMailer creates a mailer that logs every message
+func NewLogMailer(from string, logger *log.Logger) *LogMailer {
+	return &LogMailer{
+		from:   from,
+		logger: logger,
+	}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	m.logger.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message represents an outgoing plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config holds mailer configuration
+type Config struct {
+	Driver string // "log", "file" or "smtp"
+	From   string
+	Dir    string // output directory for the file driver
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// NewConfig creates a new mailer config from environment variables
+func NewConfig() *Config {
+	return &Config{
+		Driver: getEnv("MAIL_DRIVER", "log"),
+		From:   getEnv("MAIL_FROM", "Playlists <no-reply@localhost>"),
+		Dir:    getEnv("MAIL_DIR", "./tmp/mail"),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+	}
+}
+
+// New creates the mailer selected by the config driver
+func New(config *Config, logger *log.Logger) (Mailer, error) {
+	switch config.Driver {
+	case "log":
+		return NewLogMailer(config.From, logger), nil
+	case "file":
+		return NewFileMailer(config.From, config.Dir)
+	case "smtp":
+		return NewSMTPMailer(config.From, config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
+
+// format renders a message in RFC 5322 form. Non-ASCII subjects are
+// encoded as RFC 2047 encoded words.
+func format(from string, msg Message, date time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.String()
+}
+
+// validate rejects messages that would allow header injection
+func validate(msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("message has no recipient")
+	}
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("message headers must not contain line breaks")
+	}
+	return nil
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
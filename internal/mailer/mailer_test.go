@@ -0,0 +1,165 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := NewFileMailer("Playlists <no-reply@example.com>", dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer() error = %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{To: "band@example.com", Subject: "Hello", Body: "Line one\nLine two"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one .eml file, got %v (%v)", files, err)
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"To: band@example.com\r\n", "Subject: Hello\r\n", "\r\n\r\nLine one\r\nLine two"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestFormatEncodesSubject(t *testing.T) {
+	date := time.Date(2025, 10, 16, 20, 0, 0, 0, time.UTC)
+
+	got := format("no-reply@example.com", Message{To: "band@example.com", Subject: "Motörhead tonight"}, date)
+	if want := "Subject: =?utf-8?q?Mot=C3=B6rhead_tonight?=\r\n"; !strings.Contains(got, want) {
+		t.Errorf("Expected message to contain %q, got:\n%s", want, got)
+	}
+
+	// ASCII subjects are left as they are
+	got = format("no-reply@example.com", Message{To: "band@example.com", Subject: "Hello"}, date)
+	if want := "Subject: Hello\r\n"; !strings.Contains(got, want) {
+		t.Errorf("Expected message to contain %q, got:\n%s", want, got)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer("no-reply@example.com", log.New(&buf, "", 0))
+
+	err := m.Send(context.Background(), Message{To: "band@example.com", Subject: "Hello", Body: "Body"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "band@example.com") {
+		t.Errorf("Expected log to mention the recipient, got %q", buf.String())
+	}
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	m := NewLogMailer("no-reply@example.com", log.New(&bytes.Buffer{}, "", 0))
+
+	err := m.Send(context.Background(), Message{To: "band@example.com\r\nBcc: evil@example.com", Subject: "Hi"})
+	if err == nil {
+		t.Error("Expected an error for a recipient containing a line break")
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(ln, received)
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	m, err := NewSMTPMailer("Playlists <no-reply@example.com>", host, port, "", "")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = m.Send(ctx, Message{To: "band@example.com", Subject: "Hello", Body: "Body"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	commands := strings.Join(<-received, "\n")
+	for _, want := range []string{"MAIL FROM:<no-reply@example.com>", "RCPT TO:<band@example.com>", "Subject: Hello"} {
+		if !strings.Contains(commands, want) {
+			t.Errorf("Expected SMTP session to contain %q, got:\n%s", want, commands)
+		}
+	}
+}
+
+func TestNewSMTPMailerRejectsInvalidSender(t *testing.T) {
+	if _, err := NewSMTPMailer("not an address", "localhost", "25", "", ""); err == nil {
+		t.Error("Expected an error for an invalid sender address")
+	}
+}
+
+// serveSMTP accepts one connection, speaks just enough SMTP to take a
+// message and sends every line it received to lines
+func serveSMTP(ln net.Listener, lines chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var received []string
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		received = append(received, line)
+
+		switch {
+		case inData:
+			if line == "." {
+				inData = false
+				reply("250 OK")
+			}
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case line == "DATA":
+			inData = true
+			reply("354 Go ahead")
+		case line == "QUIT":
+			reply("221 Bye")
+			lines <- received
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+	lines <- received
+}
@@ -0,0 +1,83 @@
+// Package music holds the musical vocabulary songs are described with: keys
+// and time signatures, parsed leniently and written in one canonical form,
+// and the chords charts are written in, which can be transposed.
+package music
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tonics are the key names a key may be built on, with sharps written "#"
+// and flats "b"
+var tonics = []string{"C", "C#", "Db", "D", "D#", "Eb", "E", "F", "F#", "Gb", "G", "G#", "Ab", "A", "A#", "Bb", "B"}
+
+// TimeSignatures are the accepted time signatures
+var TimeSignatures = []string{"2/2", "3/2", "2/4", "3/4", "4/4", "5/4", "6/4", "7/4", "3/8", "5/8", "6/8", "7/8", "9/8", "12/8"}
+
+// Key is a major or minor key such as "Eb" or "C#m"
+type Key struct {
+	Tonic string
+	Minor bool
+}
+
+// String returns the key in canonical form: the tonic followed by "m" for minor keys
+func (k Key) String() string {
+	if k.Minor {
+		return k.Tonic + "m"
+	}
+	return k.Tonic
+}
+
+// ParseKey parses a key. The tonic is case-insensitive and may use "♯"/"♭";
+// "m", "min" and "minor" mark minor keys, "maj" and "major" major ones.
+func ParseKey(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Key{}, fmt.Errorf("invalid key %q", s)
+	}
+
+	tonic := strings.ToUpper(s[:1])
+	rest := s[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"), strings.HasPrefix(rest, "♯"):
+		tonic += "#"
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "#"), "♯")
+	case strings.HasPrefix(rest, "b"), strings.HasPrefix(rest, "♭"):
+		tonic += "b"
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "b"), "♭")
+	}
+
+	if !contains(tonics, tonic) {
+		return Key{}, fmt.Errorf("invalid key %q", s)
+	}
+
+	rest = strings.TrimSpace(rest)
+	switch {
+	case rest == "" || rest == "M" || strings.EqualFold(rest, "maj") || strings.EqualFold(rest, "major"):
+		return Key{Tonic: tonic}, nil
+	case rest == "m" || strings.EqualFold(rest, "min") || strings.EqualFold(rest, "minor"):
+		return Key{Tonic: tonic, Minor: true}, nil
+	}
+
+	return Key{}, fmt.Errorf("invalid key %q", s)
+}
+
+// ParseTimeSignature returns the time signature in canonical form, or an
+// error if it is not one of TimeSignatures
+func ParseTimeSignature(s string) (string, error) {
+	signature := strings.Join(strings.Fields(s), "")
+	if !contains(TimeSignatures, signature) {
+		return "", fmt.Errorf("invalid time signature %q", s)
+	}
+	return signature, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package music
+
+import "testing"
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"C", "C"},
+		{"c", "C"},
+		{"Eb", "Eb"},
+		{"bb", "Bb"},
+		{"b", "B"},
+		{"bm", "Bm"},
+		{"F#m", "F#m"},
+		{"f♯ minor", "F#m"},
+		{"A♭", "Ab"},
+		{"Dmin", "Dm"},
+		{"G major", "G"},
+		{"CM", "C"},
+		{" a#m ", "A#m"},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseKey(tt.in)
+		if err != nil {
+			t.Errorf("ParseKey(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got := key.String(); got != tt.want {
+			t.Errorf("ParseKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyRejectsUnknownKeys(t *testing.T) {
+	for _, in := range []string{"", "H", "E#", "Cb", "C##", "Cmaj7", "Am7", "Dorian", "m"} {
+		if key, err := ParseKey(in); err == nil {
+			t.Errorf("ParseKey(%q) = %q, want error", in, key)
+		}
+	}
+}
+
+func TestParseTimeSignature(t *testing.T) {
+	got, err := ParseTimeSignature(" 6 / 8 ")
+	if err != nil || got != "6/8" {
+		t.Errorf("ParseTimeSignature(\" 6 / 8 \") = %q, %v, want \"6/8\"", got, err)
+	}
+
+	for _, in := range []string{"", "4", "4/3", "11/8", "C"} {
+		if got, err := ParseTimeSignature(in); err == nil {
+			t.Errorf("ParseTimeSignature(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestTransposeChord(t *testing.T) {
+	tests := []struct {
+		chord     string
+		semitones int
+		flats     bool
+		want      string
+	}{
+		{"C", 2, false, "D"},
+		{"Am7", 1, true, "Bbm7"},
+		{"Am7", 1, false, "A#m7"},
+		{"D/F#", -2, false, "C/E"},
+		{"G/B", 3, true, "Bb/D"},
+		{"Bbmaj7#11", 2, false, "Cmaj7#11"},
+		{"C6/9", 5, true, "F6/9"},
+		{"Ebsus4", -3, false, "Csus4"},
+		{"F♯m", 1, false, "Gm"},
+		{"E7b9", 12, false, "E7b9"},
+		{"Cb", 1, false, "C"},
+	}
+
+	for _, tt := range tests {
+		got, err := TransposeChord(tt.chord, tt.semitones, tt.flats)
+		if err != nil {
+			t.Errorf("TransposeChord(%q) returned error: %v", tt.chord, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("TransposeChord(%q, %d, %v) = %q, want %q", tt.chord, tt.semitones, tt.flats, got, tt.want)
+		}
+	}
+
+	for _, chord := range []string{"", "N.C.", "x", "am"} {
+		if got, err := TransposeChord(chord, 2, false); err == nil {
+			t.Errorf("TransposeChord(%q) = %q, want error", chord, got)
+		}
+	}
+}
+
+func TestKeyTranspose(t *testing.T) {
+	tests := []struct {
+		key       string
+		semitones int
+		want      string
+		flats     bool
+	}{
+		{"C", 2, "D", false},
+		{"G", -2, "F", true},
+		{"A", 1, "Bb", true},
+		{"Em", 1, "Fm", true},
+		{"Am", -2, "Gm", true},
+		{"Bm", -4, "Gm", true},
+		{"E", 2, "F#", false},
+		{"Bbm", 3, "C#m", false},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseKey(tt.key)
+		if err != nil {
+			t.Fatalf("ParseKey(%q) returned error: %v", tt.key, err)
+		}
+		got := key.Transpose(tt.semitones)
+		if got.String() != tt.want || got.Flats() != tt.flats {
+			t.Errorf("%s.Transpose(%d) = %s (flats %v), want %s (flats %v)", tt.key, tt.semitones, got, got.Flats(), tt.want, tt.flats)
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+	}{
+		{"C", "D", 2},
+		{"D", "C", -2},
+		{"G", "Bb", 3},
+		{"Bb", "E", 6},
+		{"Am", "Gm", -2},
+	}
+
+	for _, tt := range tests {
+		from, _ := ParseKey(tt.from)
+		to, _ := ParseKey(tt.to)
+		if got := Interval(from, to); got != tt.want {
+			t.Errorf("Interval(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
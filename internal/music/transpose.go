@@ -0,0 +1,115 @@
+package music
+
+import (
+	"fmt"
+	"strings"
+)
+
+// noteSemitones are the semitones above C of the note names chords may use
+var noteSemitones = map[string]int{
+	"C": 0, "B#": 0, "C#": 1, "Db": 1, "D": 2, "D#": 3, "Eb": 3, "E": 4, "Fb": 4, "E#": 5, "F": 5,
+	"F#": 6, "Gb": 6, "G": 7, "G#": 8, "Ab": 8, "A": 9, "A#": 10, "Bb": 10, "B": 11, "Cb": 11,
+}
+
+// Note names by semitone above C, spelled with sharps or with flats
+var (
+	sharpNotes = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNotes  = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+)
+
+// Key names by semitone above C, in the spelling with the fewest accidentals
+var (
+	majorTonics = []string{"C", "Db", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+	minorTonics = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "G#", "A", "Bb", "B"}
+)
+
+// Semitone returns the semitones from C up to the tonic of the key
+func (k Key) Semitone() int {
+	return noteSemitones[k.Tonic]
+}
+
+// Flats reports whether the key signature is written with flats, so chords
+// in the key are spelled with flats rather than sharps
+func (k Key) Flats() bool {
+	if strings.HasSuffix(k.Tonic, "b") {
+		return true
+	}
+	if k.Minor {
+		return k.Tonic == "D" || k.Tonic == "G" || k.Tonic == "C" || k.Tonic == "F"
+	}
+	return k.Tonic == "F"
+}
+
+// Transpose returns the key the given number of semitones up, or down when
+// negative, spelled the way that key is usually written
+func (k Key) Transpose(semitones int) Key {
+	semitone := mod12(k.Semitone() + semitones)
+	if k.Minor {
+		return Key{Tonic: minorTonics[semitone], Minor: true}
+	}
+	return Key{Tonic: majorTonics[semitone]}
+}
+
+// Interval returns the shortest number of semitones from the tonic of one
+// key to the tonic of another, between -5 and 6
+func Interval(from, to Key) int {
+	semitones := mod12(to.Semitone() - from.Semitone())
+	if semitones > 6 {
+		semitones -= 12
+	}
+	return semitones
+}
+
+// TransposeChord moves a chord such as "F#m7" or "D/F#" the given number of
+// semitones, spelling the root and bass note with flats or sharps.
+// Extensions are kept as written.
+func TransposeChord(chord string, semitones int, flats bool) (string, error) {
+	root, rest, ok := splitNote(chord)
+	if !ok {
+		return "", fmt.Errorf("invalid chord %q", chord)
+	}
+
+	bass := ""
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		// A slash before a number is part of the chord, as in "C6/9"
+		note, tail, ok := splitNote(rest[i+1:])
+		if ok && tail == "" {
+			bass = "/" + transposeNote(note, semitones, flats)
+			rest = rest[:i]
+		}
+	}
+
+	return transposeNote(root, semitones, flats) + rest + bass, nil
+}
+
+// splitNote splits a note name, with sharps and flats written "#" and "b",
+// from the start of s
+func splitNote(s string) (string, string, bool) {
+	if s == "" || s[0] < 'A' || s[0] > 'G' {
+		return "", "", false
+	}
+
+	note, rest := s[:1], s[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"), strings.HasPrefix(rest, "b"):
+		note, rest = note+rest[:1], rest[1:]
+	case strings.HasPrefix(rest, "♯"):
+		note, rest = note+"#", strings.TrimPrefix(rest, "♯")
+	case strings.HasPrefix(rest, "♭"):
+		note, rest = note+"b", strings.TrimPrefix(rest, "♭")
+	}
+	return note, rest, true
+}
+
+// transposeNote moves a note name found by splitNote
+func transposeNote(note string, semitones int, flats bool) string {
+	semitone := mod12(noteSemitones[note] + semitones)
+	if flats {
+		return flatNotes[semitone]
+	}
+	return sharpNotes[semitone]
+}
+
+func mod12(n int) int {
+	return ((n % 12) + 12) % 12
+}
@@ -0,0 +1,251 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a minimal OpenID provider for tests
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	// What the token endpoint expects and returns
+	code      string
+	challenge string
+	idToken   string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	m := &mockIssuer{t: t, key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	discovery := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	}
+	mux.HandleFunc("/.well-known/openid-configuration", discovery)
+	// Serves a document naming a different issuer than the URL it came from
+	mux.HandleFunc("/other/.well-known/openid-configuration", discovery)
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": m.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		if clientID != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.FormValue("code") != m.code || CodeChallenge(r.FormValue("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": m.idToken})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return NewProvider(Config{
+		Name:         "mock",
+		IssuerURL:    m.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://app.example.com/auth/oidc/mock/callback",
+	}, m.server.Client())
+}
+
+// sign issues an ID token, letting the test adjust the claims first
+func (m *mockIssuer) sign(nonce string, adjust func(*Claims)) string {
+	now := time.Now()
+	claims := &Claims{
+		Email:         "john@example.com",
+		EmailVerified: true,
+		GivenName:     "John",
+		FamilyName:    "Doe",
+		Nonce:         nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.server.URL,
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{"client"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+	if adjust != nil {
+		adjust(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	verifier, err := GenerateVerifier()
+	if err != nil {
+		t.Fatalf("GenerateVerifier() error = %v", err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if !strings.HasPrefix(authURL, m.server.URL+"/authorize?") || query.Get("state") != "state-1" || query.Get("nonce") != "nonce-1" {
+		t.Errorf("Unexpected authorization URL %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid email profile" {
+		t.Errorf("Unexpected authorization parameters %v", query)
+	}
+
+	// The provider sends the user back with a code
+	m.code = "code-1"
+	m.challenge = query.Get("code_challenge")
+	m.idToken = m.sign("nonce-1", nil)
+
+	rawIDToken, err := p.Exchange(ctx, "code-1", verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "john@example.com" || !bool(claims.EmailVerified) {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	// A wrong verifier is rejected by the provider
+	if _, err := p.Exchange(ctx, "code-1", "wrong-verifier"); err == nil {
+		t.Error("Expected exchange with the wrong verifier to fail")
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"wrong nonce", m.sign("nonce-1", nil), "nonce-2"},
+		{"wrong audience", m.sign("nonce-1", func(c *Claims) { c.Audience = jwt.ClaimStrings{"someone-else"} }), "nonce-1"},
+		{"wrong issuer", m.sign("nonce-1", func(c *Claims) { c.Issuer = "https://evil.example.com" }), "nonce-1"},
+		{"expired", m.sign("nonce-1", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }), "nonce-1"},
+		{"missing subject", m.sign("nonce-1", func(c *Claims) { c.Subject = "" }), "nonce-1"},
+		{"foreign key", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": m.server.URL, "aud": "client", "sub": "x", "nonce": "nonce-1", "exp": time.Now().Add(time.Minute).Unix()})
+			token.Header["kid"] = m.kid
+			signed, _ := token.SignedString(other)
+			return signed
+		}(), "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.VerifyIDToken(ctx, tt.token, tt.nonce); err == nil {
+				t.Error("Expected the ID token to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRefetchesRotatedKeys(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, m.sign("n", nil), "n"); err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+
+	// The provider rotates its key; the next token triggers a refetch
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	m.key, m.kid = key, "key-2"
+	p.keysFetched = time.Time{}
+
+	if _, err := p.VerifyIDToken(ctx, m.sign("n", nil), "n"); err != nil {
+		t.Errorf("Expected the rotated key to be fetched, got %v", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	m := newMockIssuer(t)
+	p := NewProvider(Config{Name: "mock", IssuerURL: m.server.URL + "/other", ClientID: "client"}, m.server.Client())
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("Expected discovery to fail for a different issuer")
+	}
+}
+
+func TestFlexBool(t *testing.T) {
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"email_verified": "true"}`), &claims); err != nil || !bool(claims.EmailVerified) {
+		t.Errorf("Expected string true to decode as true, got %v (%v)", claims.EmailVerified, err)
+	}
+	if err := json.Unmarshal([]byte(`{"email_verified": false}`), &claims); err != nil || bool(claims.EmailVerified) {
+		t.Errorf("Expected false to decode as false, got %v (%v)", claims.EmailVerified, err)
+	}
+}
@@ -0,0 +1,106 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteToProducesValidCrossReferences(t *testing.T) {
+	doc := New(A4Width, A4Height)
+	doc.SetTitle("Friday (late)")
+	page := doc.AddPage()
+	page.Text(40, 60, HelveticaBold, 24, "Setlist")
+	page.Line(40, 70, 555, 70, 1)
+	doc.AddPage().Text(40, 60, Helvetica, 12, "Año – Canción")
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo returned error: %v", err)
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Errorf("page tree should count 2 pages")
+	}
+	if !bytes.Contains(out, []byte(`/Title (Friday \(late\))`)) {
+		t.Errorf("title should be escaped")
+	}
+
+	// Every cross-reference entry points at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("got %d objects, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("object %d offset %d does not point at %q", i+1, offset, want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	// H e l l o = 722 + 556 + 222 + 222 + 556
+	if got := TextWidth(Helvetica, 10, "Hello"); math.Abs(got-22.78) > 0.001 {
+		t.Errorf("TextWidth(Helvetica) = %v, want 22.78", got)
+	}
+	if TextWidth(HelveticaBold, 10, "Hello") <= TextWidth(Helvetica, 10, "Hello") {
+		t.Errorf("bold text should be wider")
+	}
+	if TextWidth(Helvetica, 10, "ñ") != TextWidth(Helvetica, 10, "n") {
+		t.Errorf("accented letters should be as wide as their base letter")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	got := encode("Canción – F♯m 日")
+	want := []byte("Canci\xf3n \x96 F#m ?")
+	if !bytes.Equal(got, want) {
+		t.Errorf("encode = %q, want %q", got, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := Wrap(Helvetica, 10, "one two three four five six", 60)
+	for _, line := range lines {
+		if TextWidth(Helvetica, 10, line) > 60 {
+			t.Errorf("line %q is wider than 60", line)
+		}
+	}
+	if strings.Join(lines, " ") != "one two three four five six" {
+		t.Errorf("wrapping lost words: %q", lines)
+	}
+
+	long := Wrap(Helvetica, 10, strings.Repeat("w", 40), 50)
+	if len(long) < 2 || strings.Join(long, "") != strings.Repeat("w", 40) {
+		t.Errorf("long words should be broken: %q", long)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	got := Truncate(Helvetica, 10, "Bohemian Rhapsody", 50)
+	if !strings.HasSuffix(got, "…") || TextWidth(Helvetica, 10, got) > 50 {
+		t.Errorf("Truncate = %q", got)
+	}
+	if Truncate(Helvetica, 10, "Short", 50) != "Short" {
+		t.Errorf("short text should not be truncated")
+	}
+}
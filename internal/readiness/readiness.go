@@ -0,0 +1,88 @@
+// Package readiness works out whether a band is ready to play a song from
+// how well each member says they know it and when it was last rehearsed.
+package readiness
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status is how ready a band member is to play a song
+type Status string
+
+const (
+	Learning  Status = "learning"
+	Rough     Status = "rough"
+	NeedsWork Status = "needs_work"
+	Solid     Status = "solid"
+)
+
+// Valid reports whether the status is learning, rough, needs_work or solid
+func (s Status) Valid() bool {
+	return s == Learning || s == Rough || s == NeedsWork || s == Solid
+}
+
+// StaleAfter is how long a song can go unrehearsed before it is at risk
+const StaleAfter = 30 * 24 * time.Hour
+
+// Summary tells how ready a band is to play a song
+type Summary struct {
+	Learning  int `json:"learning"`
+	Rough     int `json:"rough"`
+	NeedsWork int `json:"needs_work"`
+	Solid     int `json:"solid"`
+	// Unreported counts the members who have not given a status
+	Unreported      int        `json:"unreported"`
+	LastRehearsedAt *time.Time `json:"last_rehearsed_at"`
+	// AtRisk is set unless every member is solid on the song and it was
+	// rehearsed recently; Reasons says why
+	AtRisk  bool     `json:"at_risk"`
+	Reasons []string `json:"reasons"`
+}
+
+// Assess summarizes the statuses some of a band's members gave for a song
+// that was last rehearsed at lastRehearsed, if ever, for playing it at a
+// given time
+func Assess(statuses []Status, members int, lastRehearsed *time.Time, at time.Time) Summary {
+	s := Summary{LastRehearsedAt: lastRehearsed, Reasons: []string{}}
+	for _, status := range statuses {
+		switch status {
+		case Learning:
+			s.Learning++
+		case Rough:
+			s.Rough++
+		case NeedsWork:
+			s.NeedsWork++
+		case Solid:
+			s.Solid++
+		}
+	}
+	if reported := s.Learning + s.Rough + s.NeedsWork + s.Solid; reported < members {
+		s.Unreported = members - reported
+	}
+
+	s.addReason(s.Learning, "is still learning it", "are still learning it")
+	s.addReason(s.NeedsWork, "needs to work on it", "need to work on it")
+	s.addReason(s.Rough, "is rough on it", "are rough on it")
+	s.addReason(s.Unreported, "has not said how ready they are", "have not said how ready they are")
+
+	switch {
+	case lastRehearsed == nil:
+		s.Reasons = append(s.Reasons, "never rehearsed")
+	case at.Sub(*lastRehearsed) > StaleAfter:
+		s.Reasons = append(s.Reasons, fmt.Sprintf("not rehearsed for %d days", int(at.Sub(*lastRehearsed).Hours()/24)))
+	}
+
+	s.AtRisk = len(s.Reasons) > 0
+	return s
+}
+
+// addReason notes that count members are in some state, when any are
+func (s *Summary) addReason(count int, one, many string) {
+	switch {
+	case count == 1:
+		s.Reasons = append(s.Reasons, "1 member "+one)
+	case count > 1:
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d members %s", count, many))
+	}
+}
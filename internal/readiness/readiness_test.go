@@ -0,0 +1,48 @@
+package readiness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAssess(t *testing.T) {
+	at := time.Date(2025, 11, 20, 21, 0, 0, 0, time.UTC)
+	recent := at.Add(-3 * 24 * time.Hour)
+	stale := at.Add(-45 * 24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		statuses      []Status
+		members       int
+		lastRehearsed *time.Time
+		wantRisk      bool
+		wantReasons   []string
+	}{
+		{"everyone solid", []Status{Solid, Solid, Solid}, 3, &recent, false, []string{}},
+		{"one member learning", []Status{Solid, Learning, Solid}, 3, &recent, true, []string{"1 member is still learning it"}},
+		{"unreported members", []Status{Solid}, 3, &recent, true, []string{"2 members have not said how ready they are"}},
+		{"rough and needs work", []Status{Rough, NeedsWork, NeedsWork}, 3, &recent, true, []string{"2 members need to work on it", "1 member is rough on it"}},
+		{"never rehearsed", []Status{Solid}, 1, nil, true, []string{"never rehearsed"}},
+		{"not rehearsed lately", []Status{Solid}, 1, &stale, true, []string{"not rehearsed for 45 days"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Assess(tt.statuses, tt.members, tt.lastRehearsed, at)
+			if got.AtRisk != tt.wantRisk {
+				t.Errorf("AtRisk = %v, want %v", got.AtRisk, tt.wantRisk)
+			}
+			if !reflect.DeepEqual(got.Reasons, tt.wantReasons) {
+				t.Errorf("Reasons = %q, want %q", got.Reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestAssessCounts(t *testing.T) {
+	got := Assess([]Status{Solid, Rough, Learning, Solid}, 6, nil, time.Now())
+	if got.Solid != 2 || got.Rough != 1 || got.Learning != 1 || got.NeedsWork != 0 || got.Unreported != 2 {
+		t.Errorf("Assess counts = %+v", got)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/nahue/playlists/internal/app"
-	"github.com/nahue/playlists/internal/handlers"
 )
 
 // SetupRoutes configures all the routes for the application
@@ -18,7 +17,7 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:4321"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Last-Event-ID"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -35,26 +34,91 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", app.AuthHandler.Register)
 		r.Post("/login", app.AuthHandler.Login)
-		r.Post("/logout", app.AuthHandler.Logout)
+		r.Post("/login/mfa", app.AuthHandler.LoginMFA)
+		r.Post("/refresh", app.AuthHandler.Refresh)
+		r.With(app.AuthHandler.AuthMiddleware, app.AuthHandler.RequireSession).Post("/logout", app.AuthHandler.Logout)
+		r.With(app.AuthHandler.AuthMiddleware, app.AuthHandler.RequireSession).Post("/logout-all", app.AuthHandler.LogoutAll)
+		r.Post("/forgot-password", app.AccountHandler.ForgotPassword)
+		r.Post("/reset-password", app.AccountHandler.ResetPassword)
+		r.Post("/verify-email", app.AccountHandler.VerifyEmail)
+		r.With(app.AuthHandler.AuthMiddleware, app.AuthHandler.RequireSession).Post("/verify-email/resend", app.AccountHandler.ResendVerification)
+		r.Get("/oidc/providers", app.OIDCHandler.GetProviders)
+		r.Get("/oidc/{provider}/start", app.OIDCHandler.Start)
+		r.Get("/oidc/{provider}/callback", app.OIDCHandler.Callback)
+		r.Get("/invitations", app.InvitationHandler.GetInvitationByToken)
+		r.Post("/invitations/decline", app.InvitationHandler.DeclineInvitation)
 	})
 
+	// Calendar feeds (public, the token in the URL is the credential)
+	r.Get("/calendar/{token}", app.CalendarHandler.GetFeed)
+
+	// Live setlist streams for browsers (public, the token in the URL is the credential)
+	r.Get("/live", app.LiveHandler.StreamWithToken)
+
 	// Protected routes
 	r.Route("/api", func(r chi.Router) {
-		r.Use(handlers.AuthMiddleware)
+		r.Use(app.AuthHandler.AuthMiddleware)
 
 		// User profile
-		r.Get("/profile", handlers.GetProfile)
+		r.Get("/profile", app.AuthHandler.GetProfile)
+
+		// Account management needs a signed-in session, not a personal access token
+		r.Group(func(r chi.Router) {
+			r.Use(app.AuthHandler.RequireSession)
+
+			r.Put("/profile", app.AccountHandler.UpdateProfile)
+			r.Delete("/profile", app.AccountHandler.DeleteAccount)
+			r.Post("/profile/password", app.AccountHandler.ChangePassword)
+
+			// Signed-in devices of the authenticated user
+			r.Route("/sessions", func(r chi.Router) {
+				r.Get("/", app.AuthHandler.GetSessions)
+				r.Delete("/{sessionId}", app.AuthHandler.RevokeSession)
+			})
+
+			// Two-factor authentication of the authenticated user
+			r.Route("/mfa", func(r chi.Router) {
+				r.Get("/", app.MFAHandler.GetStatus)
+				r.Post("/totp", app.MFAHandler.EnrollTOTP)
+				r.Post("/totp/confirm", app.MFAHandler.ConfirmTOTP)
+				r.Delete("/totp", app.MFAHandler.DisableTOTP)
+				r.Post("/recovery-codes", app.MFAHandler.RegenerateRecoveryCodes)
+			})
+
+			// Personal access tokens for scripts and integrations
+			r.Route("/tokens", func(r chi.Router) {
+				r.Get("/", app.TokenHandler.GetTokens)
+				r.Post("/", app.TokenHandler.CreateToken)
+				r.Delete("/{tokenId}", app.TokenHandler.DeleteToken)
+			})
+
+			// Calendar subscription URLs of the authenticated user
+			r.Route("/calendar-feeds", func(r chi.Router) {
+				r.Get("/", app.CalendarHandler.GetFeeds)
+				r.Post("/", app.CalendarHandler.CreateFeed)
+				r.Delete("/{feedId}", app.CalendarHandler.DeleteFeed)
+			})
+
+			// External identities linked to the authenticated user
+			r.Route("/identities", func(r chi.Router) {
+				r.Get("/", app.OIDCHandler.GetIdentities)
+				r.Delete("/{identityId}", app.OIDCHandler.DeleteIdentity)
+			})
+
+			// Invitations addressed to the authenticated user
+			r.Post("/invitations/accept", app.InvitationHandler.AcceptInvitation)
+		})
 
 		// Playlist routes
 		r.Route("/playlist", func(r chi.Router) {
-			r.Get("/", handlers.GetPlaylist)
-			r.Post("/", handlers.AddToPlaylist)
-			r.Get("/artists", handlers.GetArtists) // Artist autocomplete endpoint
-			r.Get("/users", handlers.GetUserNames) // User name autocomplete endpoint
+			r.Get("/", app.PlaylistHandler.GetPlaylist)
+			r.Post("/", app.PlaylistHandler.AddToPlaylist)
+			r.Get("/artists", app.PlaylistHandler.GetArtists) // Artist autocomplete endpoint
+			r.Get("/users", app.PlaylistHandler.GetUserNames) // User name autocomplete endpoint
 			r.Route("/{id}", func(r chi.Router) {
-				r.Get("/", handlers.GetPlaylistEntry)
-				r.Put("/", handlers.UpdatePlaylistEntry)
-				r.Delete("/", handlers.DeletePlaylistEntry)
+				r.Get("/", app.PlaylistHandler.GetPlaylistEntry)
+				r.Put("/", app.PlaylistHandler.UpdatePlaylistEntry)
+				r.Delete("/", app.PlaylistHandler.DeletePlaylistEntry)
 			})
 		})
 
@@ -76,21 +140,108 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 					r.Delete("/", app.BandHandler.DeleteBandMember)
 				})
 			})
+			// Band users (accounts with access and their roles)
+			r.Route("/{bandId}/users", func(r chi.Router) {
+				r.Get("/", app.BandUserHandler.GetBandUsers)
+				r.Post("/", app.BandUserHandler.AddBandUser)
+				r.Route("/{userId}", func(r chi.Router) {
+					r.Put("/", app.BandUserHandler.UpdateBandUser)
+					r.Delete("/", app.BandUserHandler.RemoveBandUser)
+				})
+			})
+			// Band invitations routes
+			r.Route("/{bandId}/invitations", func(r chi.Router) {
+				r.Get("/", app.InvitationHandler.GetInvitations)
+				r.Post("/", app.InvitationHandler.CreateInvitation)
+				r.Delete("/{invitationId}", app.InvitationHandler.RevokeInvitation)
+			})
+			// Band song catalog routes
+			r.Route("/{bandId}/songs", func(r chi.Router) {
+				r.Get("/", app.BandSongHandler.GetSongs)
+				r.Post("/", app.BandSongHandler.CreateSong)
+				r.Route("/{songId}", func(r chi.Router) {
+					r.Get("/", app.BandSongHandler.GetSong)
+					r.Put("/", app.BandSongHandler.UpdateSong)
+					r.Delete("/", app.BandSongHandler.DeleteSong)
+					r.Get("/chart", app.BandSongHandler.GetChart)
+					r.Put("/chart", app.BandSongHandler.UpdateChart)
+					r.Delete("/chart", app.BandSongHandler.DeleteChart)
+					r.Get("/readiness", app.ReadinessHandler.GetSongReadiness)
+					r.Put("/readiness", app.ReadinessHandler.SetSongReadiness)
+				})
+			})
+			// Band gigs routes
+			r.Route("/{bandId}/gigs", func(r chi.Router) {
+				r.Get("/", app.GigHandler.GetGigs)
+				r.Post("/", app.GigHandler.CreateGig)
+				r.Route("/{gigId}", func(r chi.Router) {
+					r.Get("/", app.GigHandler.GetGig)
+					r.Put("/", app.GigHandler.UpdateGig)
+					r.Delete("/", app.GigHandler.DeleteGig)
+				})
+			})
+			// Band rehearsals and availability polls routes
+			r.Route("/{bandId}/rehearsals", func(r chi.Router) {
+				r.Get("/", app.RehearsalHandler.GetRehearsals)
+				r.Post("/", app.RehearsalHandler.CreateRehearsal)
+				r.Route("/{rehearsalId}", func(r chi.Router) {
+					r.Get("/", app.RehearsalHandler.GetRehearsal)
+					r.Put("/", app.RehearsalHandler.UpdateRehearsal)
+					r.Delete("/", app.RehearsalHandler.DeleteRehearsal)
+					r.Post("/slots", app.RehearsalHandler.AddSlot)
+					r.Delete("/slots/{slotId}", app.RehearsalHandler.DeleteSlot)
+					r.Put("/availability", app.RehearsalHandler.SetAvailability)
+					r.Post("/schedule", app.RehearsalHandler.ScheduleRehearsal)
+					r.Post("/cancel", app.RehearsalHandler.CancelRehearsal)
+					r.Put("/agenda", app.RehearsalHandler.SetAgenda)
+				})
+			})
+			// How ready the band is to play the setlists of its upcoming gigs
+			r.Get("/{bandId}/readiness", app.ReadinessHandler.GetReport)
 			// Band playlists routes
 			r.Route("/{bandId}/playlists", func(r chi.Router) {
 				r.Get("/", app.BandPlaylistHandler.GetPlaylists)
 				r.Post("/", app.BandPlaylistHandler.CreatePlaylist)
+				r.Get("/templates", app.BandPlaylistHandler.GetTemplates)
 				r.Route("/{playlistId}", func(r chi.Router) {
 					r.Get("/", app.BandPlaylistHandler.GetPlaylist)
 					r.Put("/", app.BandPlaylistHandler.UpdatePlaylist)
 					r.Delete("/", app.BandPlaylistHandler.DeletePlaylist)
+					r.Post("/duplicate", app.BandPlaylistHandler.DuplicatePlaylist)
+					r.Post("/import", app.BandPlaylistHandler.ImportSongs)
+					r.Get("/export", app.BandPlaylistHandler.ExportPlaylist)
+					r.Get("/pdf", app.BandPlaylistHandler.PrintPlaylist)
+					r.Get("/readiness", app.ReadinessHandler.GetPlaylistReadiness)
+					// Live gig mode: the leader drives the setlist, clients follow over SSE
+					r.Route("/live", func(r chi.Router) {
+						r.Get("/", app.LiveHandler.Stream)
+						r.With(app.AuthHandler.RequireSession).Post("/token", app.LiveHandler.CreateStreamToken)
+						r.Post("/", app.LiveHandler.Start)
+						r.Delete("/", app.LiveHandler.End)
+						r.Post("/current", app.LiveHandler.SetCurrent)
+						r.Post("/next", app.LiveHandler.SetNext)
+						r.Post("/skip", app.LiveHandler.Skip)
+						r.Post("/encore", app.LiveHandler.InsertEncore)
+					})
+					// Playlist sections (sets, encore) routes
+					r.Route("/sections", func(r chi.Router) {
+						r.Post("/", app.BandPlaylistHandler.CreateSection)
+						r.Route("/{sectionId}", func(r chi.Router) {
+							r.Put("/", app.BandPlaylistHandler.UpdateSection)
+							r.Delete("/", app.BandPlaylistHandler.DeleteSection)
+						})
+					})
 					// Playlist songs routes
 					r.Route("/songs", func(r chi.Router) {
 						r.Get("/", app.BandPlaylistHandler.GetPlaylistSongs)
 						r.Post("/", app.BandPlaylistHandler.AddSong)
+						r.Put("/order", app.BandPlaylistHandler.ReorderSongs)
 						r.Route("/{songId}", func(r chi.Router) {
 							r.Put("/", app.BandPlaylistHandler.UpdateSong)
 							r.Delete("/", app.BandPlaylistHandler.DeleteSong)
+							r.Post("/move", app.BandPlaylistHandler.MoveSong)
+							r.Get("/chart", app.BandPlaylistHandler.GetSongChart)
+							r.Post("/chart/transpose", app.BandPlaylistHandler.TransposeSong)
 						})
 					})
 				})
@@ -0,0 +1,344 @@
+package setlist
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DocumentVersion is the version of the JSON setlist format
+const DocumentVersion = 1
+
+// Document is a setlist as written to files: its sections and its songs in
+// playing order, each naming its section
+type Document struct {
+	Version            int       `json:"version"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	ChangeoverDuration int       `json:"changeover_duration"`
+	SetTargetDuration  *int      `json:"set_target_duration"`
+	Sections           []Section `json:"sections"`
+	Songs              []Song    `json:"songs"`
+}
+
+// Section is a named set of a setlist with its target length in seconds
+type Section struct {
+	Name           string `json:"name"`
+	TargetDuration *int   `json:"target_duration"`
+}
+
+// Song is a song of a setlist. Section is the name of its section, empty
+// for songs before the first section. SectionIndex is the position of the
+// section in the document, starting at 1, which tells apart sections that
+// share a name; songs without one are placed by Section. Durations are in
+// seconds.
+type Song struct {
+	Artist        string `json:"artist"`
+	Title         string `json:"title"`
+	Section       string `json:"section"`
+	SectionIndex  int    `json:"-"`
+	Notes         string `json:"notes"`
+	Key           string `json:"key"`
+	Tempo         *int   `json:"tempo"`
+	TimeSignature string `json:"time_signature"`
+	Duration      *int   `json:"duration"`
+	Capo          *int   `json:"capo"`
+	Tuning        string `json:"tuning"`
+}
+
+// FormatDuration writes seconds as "m:ss", or "h:mm:ss" from an hour on
+func FormatDuration(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Write writes a setlist in the given format
+func Write(format Format, w io.Writer, doc Document) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, doc)
+	case FormatJSON:
+		return writeJSON(w, doc)
+	case FormatM3U:
+		return writeM3U(w, doc)
+	case FormatXSPF:
+		return writeXSPF(w, doc)
+	case FormatMarkdown:
+		return writeMarkdown(w, doc)
+	}
+	return fmt.Errorf("cannot export to %q", format)
+}
+
+// csvExportColumns is the header of exported CSV files, which import reads back
+var csvExportColumns = []string{"artist", "song", "notes", "key", "duration", "tempo", "time_signature", "capo", "tuning", "section"}
+
+func writeCSV(w io.Writer, doc Document) error {
+	writer := csv.NewWriter(w)
+	writer.Write(csvExportColumns)
+	for _, song := range doc.Songs {
+		duration := ""
+		if song.Duration != nil {
+			duration = FormatDuration(*song.Duration)
+		}
+		writer.Write([]string{
+			song.Artist, song.Title, song.Notes, song.Key, duration, optionalInt(song.Tempo),
+			song.TimeSignature, optionalInt(song.Capo), song.Tuning, song.Section,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSON(w io.Writer, doc Document) error {
+	doc.Version = DocumentVersion
+	if doc.Sections == nil {
+		doc.Sections = []Section{}
+	}
+	if doc.Songs == nil {
+		doc.Songs = []Song{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writeM3U writes an extended M3U playlist. Songs have no files, so each
+// entry is named "Artist - Title"; #EXTGRP marks where a section starts.
+func writeM3U(w io.Writer, doc Document) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if doc.Name != "" {
+		fmt.Fprintf(&b, "#PLAYLIST:%s\n", oneLine(doc.Name))
+	}
+
+	section := 0
+	for _, song := range doc.Songs {
+		if index := doc.sectionIndex(song); index != section {
+			section = index
+			fmt.Fprintf(&b, "#EXTGRP:%s\n", oneLine(song.Section))
+		}
+		duration := -1
+		if song.Duration != nil {
+			duration = *song.Duration
+		}
+		name := oneLine(song.Artist + " - " + song.Title)
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", duration, name)
+		b.WriteString(strings.NewReplacer("/", "_", `\`, "_").Replace(name) + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// xspfPlaylist is the XML Shareable Playlist Format document
+type xspfPlaylist struct {
+	XMLName    xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version    int         `xml:"version,attr"`
+	Title      string      `xml:"title,omitempty"`
+	Annotation string      `xml:"annotation,omitempty"`
+	Tracks     []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Title      string `xml:"title"`
+	Creator    string `xml:"creator"`
+	Album      string `xml:"album,omitempty"`
+	Annotation string `xml:"annotation,omitempty"`
+	// Duration is in milliseconds
+	Duration int `xml:"duration,omitempty"`
+}
+
+// writeXSPF writes an XSPF playlist, with the section of each song as its album
+func writeXSPF(w io.Writer, doc Document) error {
+	playlist := xspfPlaylist{Version: 1, Title: doc.Name, Annotation: doc.Description, Tracks: []xspfTrack{}}
+	for _, song := range doc.Songs {
+		track := xspfTrack{Title: song.Title, Creator: song.Artist, Album: song.Section, Annotation: song.Notes}
+		if song.Duration != nil {
+			track.Duration = *song.Duration * 1000
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(playlist)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeMarkdown writes a setlist to read or print: a numbered list of songs
+// per set with its details and the length of each set
+func writeMarkdown(w io.Writer, doc Document) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", oneLine(doc.Name))
+	if doc.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", doc.Description)
+	}
+
+	sets, timing := doc.sets()
+	number := 0
+	for i, set := range sets {
+		if set.Name != "" {
+			fmt.Fprintf(&b, "\n## %s\n", oneLine(set.Name))
+		}
+		if len(set.Songs) > 0 {
+			b.WriteString("\n")
+		}
+		for _, song := range set.Songs {
+			number++
+			fmt.Fprintf(&b, "%d. **%s** – %s", number, markdownEscape(song.Title), markdownEscape(song.Artist))
+			if details := songDetails(song); details != "" {
+				b.WriteString(" · " + markdownEscape(details))
+			}
+			b.WriteString("\n")
+			if song.Notes != "" {
+				fmt.Fprintf(&b, "   _%s_\n", markdownEscape(oneLine(song.Notes)))
+			}
+		}
+		fmt.Fprintf(&b, "\n_%s_\n", setSummary(timing.Sets[i]))
+	}
+	if len(sets) > 1 {
+		fmt.Fprintf(&b, "\n**Total:** %s\n", FormatDuration(timing.Total.Total))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// documentSet is a set of a document with its songs
+type documentSet struct {
+	Section
+	Songs []Song
+}
+
+// sets splits the songs of a document into sets by section and computes
+// their timing. Songs before the first section form a set of their own,
+// which is left out when it is empty and the document has sections. Sets
+// without a target take the document's set target.
+func (doc Document) sets() ([]documentSet, Timing) {
+	groups := make(map[int][]Song)
+	for _, song := range doc.Songs {
+		index := doc.sectionIndex(song)
+		groups[index] = append(groups[index], song)
+	}
+
+	var sets []documentSet
+	var indexes []int
+	if len(groups[0]) > 0 || len(doc.Sections) == 0 {
+		sets = append(sets, documentSet{Section: Section{}})
+		indexes = append(indexes, 0)
+	}
+	for i, section := range doc.Sections {
+		sets = append(sets, documentSet{Section: section})
+		indexes = append(indexes, i+1)
+	}
+
+	timingSets := make([]Set, len(sets))
+	for i := range sets {
+		if sets[i].TargetDuration == nil {
+			sets[i].TargetDuration = doc.SetTargetDuration
+		}
+		sets[i].Songs = groups[indexes[i]]
+		timingSets[i].Target = sets[i].TargetDuration
+		for _, song := range sets[i].Songs {
+			timingSets[i].Durations = append(timingSets[i].Durations, song.Duration)
+		}
+	}
+
+	return sets, Compute(timingSets, doc.ChangeoverDuration)
+}
+
+// sectionIndex returns the position of a song's section in the document,
+// starting at 1, or 0 for songs before the first section. Songs without a
+// SectionIndex belong to the first section with their section's name, and
+// to none, -1, when there is no such section.
+func (doc Document) sectionIndex(song Song) int {
+	if song.SectionIndex > 0 && song.SectionIndex <= len(doc.Sections) {
+		return song.SectionIndex
+	}
+	if song.Section == "" {
+		return 0
+	}
+	for i, section := range doc.Sections {
+		if section.Name == song.Section {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// songDetails lists the key, tempo, time signature, capo, tuning and length of a song
+func songDetails(song Song) string {
+	var details []string
+	if song.Key != "" {
+		details = append(details, song.Key)
+	}
+	if song.Tempo != nil {
+		details = append(details, fmt.Sprintf("%d BPM", *song.Tempo))
+	}
+	if song.TimeSignature != "" {
+		details = append(details, song.TimeSignature)
+	}
+	if song.Capo != nil && *song.Capo > 0 {
+		details = append(details, fmt.Sprintf("capo %d", *song.Capo))
+	}
+	if song.Tuning != "" {
+		details = append(details, song.Tuning)
+	}
+	if song.Duration != nil {
+		details = append(details, FormatDuration(*song.Duration))
+	}
+	return strings.Join(details, " · ")
+}
+
+// setSummary describes the length of a set against its target
+func setSummary(t SetTiming) string {
+	songs := "songs"
+	if t.SongCount == 1 {
+		songs = "song"
+	}
+	summary := fmt.Sprintf("%d %s, %s", t.SongCount, songs, FormatDuration(t.Total))
+	if t.Target != nil {
+		summary += " of " + FormatDuration(*t.Target)
+		if t.Over {
+			summary += ", " + FormatDuration(t.Overrun) + " over"
+		}
+	}
+	if t.UnknownDurations > 0 {
+		summary += fmt.Sprintf(", %d without a length", t.UnknownDurations)
+	}
+	return summary
+}
+
+// markdownEscaper escapes the characters that would change how text is formatted
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// oneLine joins the lines of s so it fits on one line of a file
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
@@ -0,0 +1,176 @@
+package setlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testDocument() Document {
+	return Document{
+		Name:               "Friday",
+		Description:        "Club show",
+		ChangeoverDuration: 20,
+		Sections:           []Section{{Name: "Encore", TargetDuration: seconds(600)}},
+		Songs: []Song{
+			{Artist: "Queen", Title: "Innuendo", Notes: "Long intro", Key: "Dm", Tempo: seconds(76), TimeSignature: "3/4", Duration: seconds(390), Capo: seconds(2), Tuning: "Drop D"},
+			{Artist: "Queen", Title: "Under Pressure", Duration: seconds(250)},
+			{Artist: "Queen", Title: "Bohemian Rhapsody", Section: "Encore"},
+		},
+	}
+}
+
+// roundTrip exports the document and imports it again
+func roundTrip(t *testing.T, format Format) *Import {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := Write(format, &buf, testDocument())
+	if err != nil {
+		t.Fatalf("Write(%s) returned error: %v", format, err)
+	}
+	result, err := Parse(format, &buf)
+	if err != nil {
+		t.Fatalf("Parse(%s) returned error: %v", format, err)
+	}
+	if len(result.Rejected) > 0 {
+		t.Fatalf("Parse(%s) rejected lines: %+v", format, result.Rejected)
+	}
+	return result
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	result := roundTrip(t, FormatJSON)
+
+	doc := testDocument()
+	if !reflect.DeepEqual(result.Sections, doc.Sections) {
+		t.Errorf("sections = %+v, want %+v", result.Sections, doc.Sections)
+	}
+	if len(result.Entries) != len(doc.Songs) {
+		t.Fatalf("got %d songs, want %d", len(result.Entries), len(doc.Songs))
+	}
+	for i, entry := range result.Entries {
+		if entry.Line != i+1 || !reflect.DeepEqual(entry.Song, doc.Songs[i]) {
+			t.Errorf("song %d = %+v, want %+v", i, entry, doc.Songs[i])
+		}
+	}
+}
+
+func TestJSONRejectsUnknownVersions(t *testing.T) {
+	for _, input := range []string{`{"songs": []}`, `{"version": 99, "songs": []}`} {
+		if _, err := Parse(FormatJSON, strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%s) should fail", input)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	result := roundTrip(t, FormatCSV)
+
+	doc := testDocument()
+	for i, entry := range result.Entries {
+		if !reflect.DeepEqual(entry.Song, doc.Songs[i]) {
+			t.Errorf("song %d = %+v, want %+v", i, entry.Song, doc.Songs[i])
+		}
+	}
+}
+
+func TestM3URoundTrip(t *testing.T) {
+	result := roundTrip(t, FormatM3U)
+
+	if len(result.Entries) != 3 {
+		t.Fatalf("got %d songs, want 3", len(result.Entries))
+	}
+	if d := result.Entries[0].Duration; d == nil || *d != 390 {
+		t.Errorf("first duration = %v, want 390", d)
+	}
+	if result.Entries[2].Duration != nil || result.Entries[2].Section != "Encore" || result.Entries[1].Section != "" {
+		t.Errorf("unexpected songs: %+v", result.Entries)
+	}
+}
+
+func TestWriteXSPF(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(FormatXSPF, &buf, testDocument())
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<playlist xmlns="http://xspf.org/ns/0/" version="1">`,
+		"<title>Friday</title>",
+		"<creator>Queen</creator>",
+		"<duration>390000</duration>",
+		"<album>Encore</album>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("XSPF output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(FormatMarkdown, &buf, testDocument())
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	want := "# Friday\n\nClub show\n\n" +
+		"1. **Innuendo** – Queen · Dm · 76 BPM · 3/4 · capo 2 · Drop D · 6:30\n" +
+		"   _Long intro_\n" +
+		"2. **Under Pressure** – Queen · 4:10\n\n" +
+		"_2 songs, 11:00_\n\n" +
+		"## Encore\n\n" +
+		"3. **Bohemian Rhapsody** – Queen\n\n" +
+		"_1 song, 0:00 of 10:00, 1 without a length_\n\n" +
+		"**Total:** 11:00\n"
+	if buf.String() != want {
+		t.Errorf("unexpected Markdown:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestExportSectionsWithTheSameName(t *testing.T) {
+	doc := Document{
+		Sections: []Section{{Name: "Set"}, {Name: "Set"}},
+		Songs: []Song{
+			{Artist: "Queen", Title: "Innuendo", Section: "Set", SectionIndex: 1, Duration: seconds(390)},
+			{Artist: "Queen", Title: "Under Pressure", Section: "Set", SectionIndex: 2, Duration: seconds(250)},
+		},
+	}
+
+	sets, timing := doc.sets()
+	if len(sets) != 2 || len(sets[0].Songs) != 1 || len(sets[1].Songs) != 1 || sets[1].Songs[0].Title != "Under Pressure" {
+		t.Fatalf("sets = %+v", sets)
+	}
+	if timing.Total.Total != 640 {
+		t.Errorf("total = %d, want 640", timing.Total.Total)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(FormatMarkdown, &buf, doc); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "**Innuendo**"); n != 1 {
+		t.Errorf("Markdown lists Innuendo %d times:\n%s", n, buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(FormatM3U, &buf, doc); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "#EXTGRP:Set\n"); n != 2 {
+		t.Errorf("M3U starts %d sections, want 2:\n%s", n, buf.String())
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	cases := map[int]string{0: "0:00", 65: "1:05", 3600: "1:00:00", 3725: "1:02:05"}
+	for input, want := range cases {
+		if got := FormatDuration(input); got != want {
+			t.Errorf("FormatDuration(%d) = %q, want %q", input, got, want)
+		}
+	}
+}
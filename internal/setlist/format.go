@@ -0,0 +1,45 @@
+package setlist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format is a file format setlists are imported from or exported to
+type Format string
+
+const (
+	// FormatCSV has one song per row: artist, song, notes, key and duration,
+	// optionally under a header row naming the columns
+	FormatCSV Format = "csv"
+	// FormatJSON is the versioned Document, which import reads back whole
+	FormatJSON Format = "json"
+	// FormatM3U is an extended M3U playlist, with "#EXTINF:<seconds>,Artist - Title"
+	// before each entry
+	FormatM3U Format = "m3u"
+	// FormatText has one "Artist - Song" line per song
+	FormatText Format = "text"
+	// FormatXSPF is the XML Shareable Playlist Format, for export only
+	FormatXSPF Format = "xspf"
+	// FormatMarkdown is a readable setlist, for export only
+	FormatMarkdown Format = "md"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return FormatCSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "m3u", "m3u8":
+		return FormatM3U, nil
+	case "text", "txt":
+		return FormatText, nil
+	case "xspf":
+		return FormatXSPF, nil
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("unknown setlist format %q", name)
+}
@@ -0,0 +1,360 @@
+package setlist
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Entry is a song read from an imported file. Line is the 1-based line it
+// was read from, or its number in the song list of a JSON document.
+type Entry struct {
+	Line int
+	Song
+}
+
+// Rejection is a line of an imported file that could not be read as a song
+type Rejection struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// Import is the result of reading a file: the sections it lists, the songs
+// in file order and the lines that were rejected. Songs may also name
+// sections that are not listed.
+type Import struct {
+	Sections []Section
+	Entries  []Entry
+	Rejected []Rejection
+}
+
+// Parse reads a setlist in the given format. Lines that cannot be read as
+// songs are rejected without stopping the import; an error is only
+// returned when the input cannot be read at all.
+func Parse(format Format, r io.Reader) (*Import, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSON:
+		return parseJSON(r)
+	case FormatM3U:
+		return parseM3U(r)
+	case FormatText:
+		return parseText(r)
+	}
+	return nil, fmt.Errorf("cannot import from %q", format)
+}
+
+// ParseDuration parses a song length given as seconds ("225"), minutes and
+// seconds ("3:45") or hours, minutes and seconds ("1:02:30")
+func ParseDuration(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, ":")
+	if s == "" || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	total := 0
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && (len(part) != 2 || n > 59)) {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total = total*60 + n
+	}
+	return total, nil
+}
+
+// csvColumns are the columns of a CSV file without a header row, in order.
+// A header row may name the others exported CSV files have.
+var csvColumns = []string{"artist", "song", "notes", "key", "duration"}
+
+// csvAliases maps other header names to the column they stand for
+var csvAliases = map[string]string{
+	"title":     "song",
+	"name":      "song",
+	"length":    "duration",
+	"time":      "duration",
+	"bpm":       "tempo",
+	"signature": "time_signature",
+	"set":       "section",
+}
+
+func parseCSV(r io.Reader) (*Import, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+
+	result := &Import{}
+	columns := csvColumns
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Rejected = append(result.Rejected, Rejection{Line: parseErr.Line, Reason: parseErr.Err.Error()})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		text := strings.Join(record, ",")
+
+		if first {
+			first = false
+			if header, ok := csvHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		if strings.Trim(text, ", \t") == "" {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for i, value := range record {
+			if i < len(columns) {
+				fields[columns[i]] = strings.TrimSpace(strings.TrimPrefix(value, "\ufeff"))
+			}
+		}
+
+		entry, err := csvEntry(line, fields)
+		if err != nil {
+			result.Rejected = append(result.Rejected, Rejection{Line: line, Text: text, Reason: err.Error()})
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// csvEntry reads a song from the fields of a CSV row by column name
+func csvEntry(line int, fields map[string]string) (Entry, error) {
+	entry := Entry{Line: line, Song: Song{
+		Artist:        fields["artist"],
+		Title:         fields["song"],
+		Section:       fields["section"],
+		Notes:         fields["notes"],
+		Key:           fields["key"],
+		TimeSignature: fields["time_signature"],
+		Tuning:        fields["tuning"],
+	}}
+	if entry.Artist == "" || entry.Title == "" {
+		return Entry{}, errors.New("artist and song are required")
+	}
+	if fields["duration"] != "" {
+		duration, err := ParseDuration(fields["duration"])
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.Duration = &duration
+	}
+	for column, target := range map[string]**int{"tempo": &entry.Tempo, "capo": &entry.Capo} {
+		if fields[column] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(fields[column])
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid %s %q", column, fields[column])
+		}
+		*target = &n
+	}
+	return entry, nil
+}
+
+// csvHeader reads a header row, which must name the artist and song columns
+func csvHeader(record []string) ([]string, bool) {
+	columns := make([]string, len(record))
+	found := make(map[string]bool)
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if alias, ok := csvAliases[name]; ok {
+			name = alias
+		}
+		columns[i] = name
+		found[name] = true
+	}
+	return columns, found["artist"] && found["song"]
+}
+
+// extinf matches "#EXTINF:<seconds> <attributes>,<title>"
+var extinf = regexp.MustCompile(`^#EXTINF:\s*(-?\d+)[^,]*,(.*)$`)
+
+func parseM3U(r io.Reader) (*Import, error) {
+	result := &Import{}
+
+	// An #EXTINF line describes the entry on the next path line. Its title
+	// is all that is needed, so one without a path still names a song; one
+	// without an "Artist - Title" is named after the file of its path.
+	var pending *Entry
+	var pendingText string
+	// #EXTGRP starts a group, which is read as a section
+	section := ""
+	flush := func() {
+		if pending == nil {
+			return
+		}
+		if pending.Artist == "" {
+			result.Rejected = append(result.Rejected, Rejection{Line: pending.Line, Text: pendingText, Reason: `expected "Artist - Title"`})
+		} else {
+			result.Entries = append(result.Entries, *pending)
+		}
+		pending = nil
+	}
+
+	err := scanLines(r, func(line int, text string) {
+		switch {
+		case strings.HasPrefix(text, "#EXTINF:"):
+			flush()
+			m := extinf.FindStringSubmatch(text)
+			if m == nil {
+				result.Rejected = append(result.Rejected, Rejection{Line: line, Text: text, Reason: "invalid #EXTINF line"})
+				return
+			}
+			entry := Entry{Line: line, Song: Song{Section: section}}
+			entry.Artist, entry.Title, _ = splitArtistSong(m[2])
+			if seconds, _ := strconv.Atoi(m[1]); seconds > 0 {
+				entry.Duration = &seconds
+			}
+			pending, pendingText = &entry, text
+		case strings.HasPrefix(text, "#EXTGRP:"):
+			flush()
+			section = strings.TrimSpace(strings.TrimPrefix(text, "#EXTGRP:"))
+		case strings.HasPrefix(text, "#"):
+			// Other directives and comments
+		default:
+			if pending != nil && pending.Artist == "" {
+				pending.Artist, pending.Title, _ = fileArtistSong(text)
+			}
+			if pending != nil {
+				flush()
+				return
+			}
+			artist, song, ok := fileArtistSong(text)
+			if !ok {
+				result.Rejected = append(result.Rejected, Rejection{Line: line, Text: text, Reason: `expected a file named "Artist - Title"`})
+				return
+			}
+			result.Entries = append(result.Entries, Entry{Line: line, Song: Song{Artist: artist, Title: song, Section: section}})
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read M3U: %w", err)
+	}
+	flush()
+
+	return result, nil
+}
+
+// fileArtistSong reads "Artist - Title" from the file name of a path or URL
+func fileArtistSong(location string) (string, string, bool) {
+	name := path.Base(strings.ReplaceAll(location, `\`, "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return splitArtistSong(name)
+}
+
+// numbering matches the "1." or "2)" a setlist line may start with
+var numbering = regexp.MustCompile(`^\d+[.)]\s+`)
+
+func parseText(r io.Reader) (*Import, error) {
+	result := &Import{}
+
+	err := scanLines(r, func(line int, text string) {
+		artist, song, ok := splitArtistSong(numbering.ReplaceAllString(text, ""))
+		if !ok {
+			result.Rejected = append(result.Rejected, Rejection{Line: line, Text: text, Reason: `expected "Artist - Song"`})
+			return
+		}
+		result.Entries = append(result.Entries, Entry{Line: line, Song: Song{Artist: artist, Title: song}})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+
+	return result, nil
+}
+
+// parseJSON reads a document written by the JSON export. Its songs are
+// numbered from 1 in place of line numbers.
+func parseJSON(r io.Reader) (*Import, error) {
+	var doc Document
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported setlist version %d", doc.Version)
+	}
+
+	result := &Import{}
+	for _, section := range doc.Sections {
+		section.Name = strings.TrimSpace(section.Name)
+		if section.Name == "" {
+			return nil, errors.New("sections must have a name")
+		}
+		if section.TargetDuration != nil && *section.TargetDuration <= 0 {
+			return nil, fmt.Errorf("section %q: target duration must be positive", section.Name)
+		}
+		result.Sections = append(result.Sections, section)
+	}
+
+	for i, song := range doc.Songs {
+		song.Artist = strings.TrimSpace(song.Artist)
+		song.Title = strings.TrimSpace(song.Title)
+		song.Section = strings.TrimSpace(song.Section)
+		if song.Artist == "" || song.Title == "" {
+			result.Rejected = append(result.Rejected, Rejection{Line: i + 1, Text: song.Artist + " - " + song.Title, Reason: "artist and title are required"})
+			continue
+		}
+		result.Entries = append(result.Entries, Entry{Line: i + 1, Song: song})
+	}
+
+	return result, nil
+}
+
+// scanLines calls fn with each non-blank line, trimmed, and its 1-based number
+func scanLines(r io.Reader, fn func(line int, text string)) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text != "" {
+			fn(line, text)
+		}
+	}
+	return scanner.Err()
+}
+
+// artistSeparators split "Artist - Song", also with en and em dashes
+var artistSeparators = []string{" - ", " – ", " — "}
+
+// splitArtistSong splits "Artist - Song" at the first separator
+func splitArtistSong(s string) (string, string, bool) {
+	for _, sep := range artistSeparators {
+		artist, song, found := strings.Cut(s, sep)
+		artist, song = strings.TrimSpace(artist), strings.TrimSpace(song)
+		if found && artist != "" && song != "" {
+			return artist, song, true
+		}
+	}
+	return "", "", false
+}
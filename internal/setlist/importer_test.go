@@ -0,0 +1,117 @@
+package setlist
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSVWithHeader(t *testing.T) {
+	input := "Title,Artist,Duration,Key\n" +
+		"Innuendo,Queen,6:30,Dm\n" +
+		",Queen,3:00,\n" +
+		"Under Pressure,Queen,four minutes,\n" +
+		"\"Bohemian Rhapsody\",Queen,355,Bb\n"
+
+	result, err := Parse(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(result.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(result.Entries), result.Entries)
+	}
+	first := result.Entries[0]
+	if first.Line != 2 || first.Artist != "Queen" || first.Title != "Innuendo" || first.Key != "Dm" || first.Duration == nil || *first.Duration != 390 {
+		t.Errorf("unexpected first entry: %+v", first)
+	}
+	if result.Entries[1].Line != 5 || *result.Entries[1].Duration != 355 {
+		t.Errorf("unexpected second entry: %+v", result.Entries[1])
+	}
+
+	if len(result.Rejected) != 2 || result.Rejected[0].Line != 3 || result.Rejected[1].Line != 4 {
+		t.Errorf("unexpected rejections: %+v", result.Rejected)
+	}
+}
+
+func TestParseCSVWithoutHeader(t *testing.T) {
+	result, err := Parse(FormatCSV, strings.NewReader("Queen,Innuendo,Long intro\n"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Notes != "Long intro" || result.Entries[0].Line != 1 {
+		t.Errorf("unexpected entries: %+v", result.Entries)
+	}
+}
+
+func TestParseM3U(t *testing.T) {
+	input := "#EXTM3U\n" +
+		"#EXTINF:390,Queen - Innuendo\n" +
+		"music/innuendo.mp3\n" +
+		"\n" +
+		"#EXTINF:-1 tvg-id=\"x\",Queen – Under Pressure\n" +
+		"http://example.com/pressure.mp3\n" +
+		"#EXTINF:200,Untitled\n" +
+		"untitled.mp3\n" +
+		"C:\\Music\\Queen - Bohemian Rhapsody.flac\n" +
+		"#EXTINF:120,Queen - Mustapha\n"
+
+	result, err := Parse(FormatM3U, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	want := []string{"Innuendo", "Under Pressure", "Bohemian Rhapsody", "Mustapha"}
+	if len(result.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(result.Entries), len(want), result.Entries)
+	}
+	for i, song := range want {
+		if result.Entries[i].Title != song || result.Entries[i].Artist != "Queen" {
+			t.Errorf("entry %d = %+v, want Queen - %s", i, result.Entries[i], song)
+		}
+	}
+	if d := result.Entries[0].Duration; d == nil || *d != 390 {
+		t.Errorf("first duration = %v, want 390", d)
+	}
+	if result.Entries[1].Duration != nil {
+		t.Errorf("a -1 duration should be unknown, got %d", *result.Entries[1].Duration)
+	}
+
+	if len(result.Rejected) != 1 || result.Rejected[0].Line != 7 {
+		t.Errorf("unexpected rejections: %+v", result.Rejected)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	input := "1. Queen - Innuendo\n" +
+		"\n" +
+		"Intermission\n" +
+		"2) David Bowie - Space Oddity\n"
+
+	result, err := Parse(FormatText, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(result.Entries) != 2 || result.Entries[1].Artist != "David Bowie" || result.Entries[1].Line != 4 {
+		t.Errorf("unexpected entries: %+v", result.Entries)
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Line != 3 || result.Rejected[0].Text != "Intermission" {
+		t.Errorf("unexpected rejections: %+v", result.Rejected)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]int{"225": 225, "3:45": 225, "1:02:30": 3750, " 0:05 ": 5}
+	for input, want := range cases {
+		got, err := ParseDuration(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "3:5", "3:60", "-1", "a:bc", "1:2:3:4"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", input)
+		}
+	}
+}
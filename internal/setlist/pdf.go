@@ -0,0 +1,202 @@
+package setlist
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nahue/playlists/internal/pdf"
+)
+
+// PDFLayout is how a setlist is laid out on paper
+type PDFLayout string
+
+const (
+	// StageSheet prints the song titles in large type, to read from the floor
+	StageSheet PDFLayout = "stage"
+	// DetailedSheet lists each song with its artist, key, tempo, length and notes
+	DetailedSheet PDFLayout = "detailed"
+)
+
+// ParsePDFLayout returns the layout with the given name
+func ParsePDFLayout(name string) (PDFLayout, error) {
+	switch PDFLayout(strings.ToLower(strings.TrimSpace(name))) {
+	case StageSheet:
+		return StageSheet, nil
+	case DetailedSheet:
+		return DetailedSheet, nil
+	}
+	return "", fmt.Errorf("unknown PDF layout %q", name)
+}
+
+// PDFOptions are the details printed around a setlist. A zero Date is left out.
+type PDFOptions struct {
+	Layout PDFLayout
+	Band   string
+	Date   time.Time
+}
+
+// Page layout in points, for A4 pages
+const (
+	pdfMargin   = 40
+	pdfTop      = 115
+	pdfBottom   = pdf.A4Height - 60
+	pdfRight    = pdf.A4Width - pdfMargin
+	pdfKeyWidth = 80
+)
+
+// pdfSheet lays out a setlist on pages
+type pdfSheet struct {
+	doc   *pdf.Document
+	pages []*pdf.Page
+	page  *pdf.Page
+	y     float64
+	opts  PDFOptions
+	name  string
+}
+
+// WritePDF writes a setlist as a PDF document. Every set starts on a new
+// page, and every page has the band name, the date and the set in its header.
+func WritePDF(w io.Writer, doc Document, opts PDFOptions) error {
+	sheet := &pdfSheet{doc: pdf.New(pdf.A4Width, pdf.A4Height), opts: opts, name: doc.Name}
+	sheet.doc.SetTitle(doc.Name)
+
+	sets, timing := doc.sets()
+	number := 0
+	for i, set := range sets {
+		sheet.newPage(set.Name)
+		if len(set.Songs) == 0 {
+			sheet.page.Text(pdfMargin, sheet.y, pdf.Helvetica, 12, "No songs")
+			sheet.y += 20
+		}
+		for _, song := range set.Songs {
+			number++
+			if opts.Layout == DetailedSheet {
+				sheet.detailedSong(set.Name, number, song)
+			} else {
+				sheet.stageSong(set.Name, number, song)
+			}
+		}
+		if opts.Layout == DetailedSheet {
+			sheet.summary(set.Name, setSummary(timing.Sets[i]))
+			if i == len(sets)-1 && len(sets) > 1 {
+				sheet.summary(set.Name, "Total: "+FormatDuration(timing.Total.Total))
+			}
+		}
+	}
+
+	sheet.footers()
+	_, err := sheet.doc.WriteTo(w)
+	return err
+}
+
+// newPage starts a page with the header of the given set
+func (s *pdfSheet) newPage(set string) {
+	s.page = s.doc.AddPage()
+	s.pages = append(s.pages, s.page)
+
+	band := s.opts.Band
+	date := ""
+	if !s.opts.Date.IsZero() {
+		date = s.opts.Date.Format("Mon 2 Jan 2006")
+	}
+	dateWidth := pdf.TextWidth(pdf.Helvetica, 12, date)
+	s.page.Text(pdfMargin, 50, pdf.HelveticaBold, 16, pdf.Truncate(pdf.HelveticaBold, 16, band, pdfRight-pdfMargin-dateWidth-20))
+	s.page.Text(pdfRight-dateWidth, 50, pdf.Helvetica, 12, date)
+
+	title := s.name
+	if set != "" {
+		title += " · " + set
+	}
+	s.page.Text(pdfMargin, 72, pdf.Helvetica, 12, pdf.Truncate(pdf.Helvetica, 12, title, pdfRight-pdfMargin))
+	s.page.Line(pdfMargin, 82, pdfRight, 82, 1)
+
+	s.y = pdfTop
+}
+
+// fit moves to a new page of the set when height does not fit on this one
+func (s *pdfSheet) fit(set string, height float64) {
+	if s.y+height > pdfBottom {
+		s.newPage(set)
+	}
+}
+
+// stageSong writes a song as a large title with its key on the right
+func (s *pdfSheet) stageSong(set string, number int, song Song) {
+	const size, height = 30, 46
+	s.fit(set, height)
+
+	s.page.Text(pdfMargin, s.y, pdf.Helvetica, 16, strconv.Itoa(number))
+	titleWidth := pdfRight - pdfMargin - 36
+	if song.Key != "" {
+		titleWidth -= pdfKeyWidth
+		keyWidth := pdf.TextWidth(pdf.HelveticaBold, 20, song.Key)
+		s.page.Text(pdfRight-keyWidth, s.y, pdf.HelveticaBold, 20, song.Key)
+	}
+	s.page.Text(pdfMargin+36, s.y, pdf.HelveticaBold, size, pdf.Truncate(pdf.HelveticaBold, size, song.Title, titleWidth))
+	s.y += height
+}
+
+// detailedSong writes a song with its artist, details and notes
+func (s *pdfSheet) detailedSong(set string, number int, song Song) {
+	width := pdfRight - pdfMargin - 24
+	notes := []string{}
+	if song.Notes != "" {
+		notes = pdf.Wrap(pdf.Helvetica, 10, song.Notes, width)
+	}
+	s.fit(set, 34+13*float64(len(notes)))
+
+	duration := ""
+	if song.Duration != nil {
+		duration = FormatDuration(*song.Duration)
+	}
+	durationWidth := pdf.TextWidth(pdf.Helvetica, 12, duration)
+	s.page.Text(pdfMargin, s.y, pdf.HelveticaBold, 13, strconv.Itoa(number)+".")
+	s.page.Text(pdfMargin+24, s.y, pdf.HelveticaBold, 13, pdf.Truncate(pdf.HelveticaBold, 13, song.Title, width-durationWidth-12))
+	s.page.Text(pdfRight-durationWidth, s.y, pdf.Helvetica, 12, duration)
+	s.y += 16
+
+	details := []string{song.Artist}
+	if song.Key != "" {
+		details = append(details, "Key "+song.Key)
+	}
+	if song.Tempo != nil {
+		details = append(details, fmt.Sprintf("%d BPM", *song.Tempo))
+	}
+	if song.TimeSignature != "" {
+		details = append(details, song.TimeSignature)
+	}
+	if song.Capo != nil && *song.Capo > 0 {
+		details = append(details, fmt.Sprintf("Capo %d", *song.Capo))
+	}
+	if song.Tuning != "" {
+		details = append(details, song.Tuning)
+	}
+	s.page.Text(pdfMargin+24, s.y, pdf.Helvetica, 10, pdf.Truncate(pdf.Helvetica, 10, strings.Join(details, " · "), width))
+	s.y += 13
+
+	for _, line := range notes {
+		s.page.Text(pdfMargin+24, s.y, pdf.Helvetica, 10, line)
+		s.y += 13
+	}
+	s.y += 8
+}
+
+// summary writes a line about the length of a set below a rule
+func (s *pdfSheet) summary(set, text string) {
+	s.fit(set, 24)
+	s.page.Line(pdfMargin, s.y-6, pdfRight, s.y-6, 0.5)
+	s.page.Text(pdfMargin, s.y+8, pdf.HelveticaBold, 11, text)
+	s.y += 24
+}
+
+// footers numbers the pages once they are all laid out
+func (s *pdfSheet) footers() {
+	for i, page := range s.pages {
+		text := fmt.Sprintf("%d / %d", i+1, len(s.pages))
+		width := pdf.TextWidth(pdf.Helvetica, 9, text)
+		page.Text((pdf.A4Width-width)/2, pdf.A4Height-30, pdf.Helvetica, 9, text)
+	}
+}
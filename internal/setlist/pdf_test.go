@@ -0,0 +1,88 @@
+package setlist
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfPages returns the decompressed content stream of each page
+func pdfPages(t *testing.T, out []byte) []string {
+	t.Helper()
+
+	var pages []string
+	streams := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(out, -1)
+	for _, stream := range streams {
+		r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatalf("invalid content stream: %v", err)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("invalid content stream: %v", err)
+		}
+		pages = append(pages, string(content))
+	}
+	return pages
+}
+
+func TestWritePDFStartsSetsOnNewPages(t *testing.T) {
+	for _, layout := range []PDFLayout{StageSheet, DetailedSheet} {
+		var buf bytes.Buffer
+		err := WritePDF(&buf, testDocument(), PDFOptions{Layout: layout, Band: "The Band", Date: time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatalf("WritePDF(%s) returned error: %v", layout, err)
+		}
+
+		pages := pdfPages(t, buf.Bytes())
+		if len(pages) != 2 {
+			t.Fatalf("%s: got %d pages, want one per set", layout, len(pages))
+		}
+		for i, page := range pages {
+			if !strings.Contains(page, "(The Band)") || !strings.Contains(page, "(Fri 17 Oct 2025)") {
+				t.Errorf("%s: page %d is missing the band name or date", layout, i+1)
+			}
+		}
+		if !strings.Contains(pages[0], "(Innuendo)") || !strings.Contains(pages[1], "(Bohemian Rhapsody)") {
+			t.Errorf("%s: songs are on the wrong pages", layout)
+		}
+		if !strings.Contains(pages[1], "(Friday \xb7 Encore)") {
+			t.Errorf("%s: second page should be headed with its set", layout)
+		}
+	}
+}
+
+func TestWritePDFDetailedSheetShowsDetails(t *testing.T) {
+	var buf bytes.Buffer
+	err := WritePDF(&buf, testDocument(), PDFOptions{Layout: DetailedSheet, Band: "The Band"})
+	if err != nil {
+		t.Fatalf("WritePDF returned error: %v", err)
+	}
+
+	first := pdfPages(t, buf.Bytes())[0]
+	for _, want := range []string{"Key Dm", "76 BPM", "(6:30)", "(Long intro)", "2 songs, 11:00"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("detailed sheet is missing %q", want)
+		}
+	}
+}
+
+func TestWritePDFContinuesLongSets(t *testing.T) {
+	doc := Document{Name: "Marathon"}
+	for i := 0; i < 40; i++ {
+		doc.Songs = append(doc.Songs, Song{Artist: "Queen", Title: "Song"})
+	}
+
+	var buf bytes.Buffer
+	err := WritePDF(&buf, doc, PDFOptions{Layout: StageSheet})
+	if err != nil {
+		t.Fatalf("WritePDF returned error: %v", err)
+	}
+	if pages := pdfPages(t, buf.Bytes()); len(pages) < 3 {
+		t.Errorf("40 large titles should take several pages, got %d", len(pages))
+	}
+}
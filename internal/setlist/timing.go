@@ -0,0 +1,71 @@
+// Package setlist works with setlists independently of how they are stored:
+// it computes how long sets run and reads setlists from files.
+package setlist
+
+// Set is one set of a setlist as far as timing is concerned
+type Set struct {
+	// Durations holds the length of each song in seconds, nil when unknown
+	Durations []*int
+	// Target is the length in seconds the set should fit in, nil for none
+	Target *int
+}
+
+// SetTiming is the computed length of a set. All durations are in seconds.
+type SetTiming struct {
+	SongCount int `json:"song_count"`
+	// Music is the sum of the known song durations
+	Music int `json:"music"`
+	// Changeovers is the time spent between songs
+	Changeovers int `json:"changeovers"`
+	// Total is Music plus Changeovers
+	Total int `json:"total"`
+	// UnknownDurations counts songs without a duration, which Total leaves out
+	UnknownDurations int  `json:"unknown_durations"`
+	Target           *int `json:"target"`
+	// Overrun is how far Total exceeds Target, 0 when it fits
+	Overrun int  `json:"overrun"`
+	Over    bool `json:"over"`
+}
+
+// Timing is the computed length of every set of a setlist and of the whole setlist
+type Timing struct {
+	Sets  []SetTiming `json:"sets"`
+	Total SetTiming   `json:"total"`
+}
+
+// Compute works out the length of each set, with changeover seconds between
+// consecutive songs of a set. The total adds up the sets and is over when
+// any set is.
+func Compute(sets []Set, changeover int) Timing {
+	timing := Timing{Sets: make([]SetTiming, len(sets))}
+
+	for i, set := range sets {
+		t := SetTiming{SongCount: len(set.Durations), Target: set.Target}
+		for _, duration := range set.Durations {
+			if duration == nil {
+				t.UnknownDurations++
+				continue
+			}
+			t.Music += *duration
+		}
+		if t.SongCount > 1 {
+			t.Changeovers = (t.SongCount - 1) * changeover
+		}
+		t.Total = t.Music + t.Changeovers
+		if t.Target != nil && t.Total > *t.Target {
+			t.Overrun = t.Total - *t.Target
+			t.Over = true
+		}
+		timing.Sets[i] = t
+
+		timing.Total.SongCount += t.SongCount
+		timing.Total.Music += t.Music
+		timing.Total.Changeovers += t.Changeovers
+		timing.Total.Total += t.Total
+		timing.Total.UnknownDurations += t.UnknownDurations
+		timing.Total.Overrun += t.Overrun
+		timing.Total.Over = timing.Total.Over || t.Over
+	}
+
+	return timing
+}
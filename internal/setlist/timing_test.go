@@ -0,0 +1,65 @@
+package setlist
+
+import "testing"
+
+func seconds(v int) *int {
+	return &v
+}
+
+func TestComputeAddsChangeoversBetweenSongs(t *testing.T) {
+	timing := Compute([]Set{
+		{Durations: []*int{seconds(200), seconds(300), seconds(250)}, Target: seconds(800)},
+	}, 30)
+
+	set := timing.Sets[0]
+	if set.SongCount != 3 || set.Music != 750 || set.Changeovers != 60 || set.Total != 810 {
+		t.Fatalf("unexpected set timing: %+v", set)
+	}
+	if !set.Over || set.Overrun != 10 {
+		t.Errorf("set of 810s with an 800s target: over = %v, overrun = %d", set.Over, set.Overrun)
+	}
+}
+
+func TestComputeTotalsSets(t *testing.T) {
+	timing := Compute([]Set{
+		{Durations: []*int{seconds(240), nil}, Target: seconds(2700)},
+		{Durations: []*int{seconds(180)}},
+		{},
+	}, 20)
+
+	if len(timing.Sets) != 3 {
+		t.Fatalf("got %d sets, want 3", len(timing.Sets))
+	}
+
+	first := timing.Sets[0]
+	if first.Total != 260 || first.UnknownDurations != 1 || first.Over {
+		t.Errorf("unexpected first set timing: %+v", first)
+	}
+
+	// A single song has no changeover and an empty set takes no time
+	if timing.Sets[1].Total != 180 || timing.Sets[2].Total != 0 {
+		t.Errorf("unexpected set totals: %d, %d", timing.Sets[1].Total, timing.Sets[2].Total)
+	}
+
+	total := timing.Total
+	if total.SongCount != 3 || total.Music != 420 || total.Changeovers != 20 || total.Total != 440 || total.UnknownDurations != 1 {
+		t.Errorf("unexpected total timing: %+v", total)
+	}
+	if total.Over || total.Target != nil {
+		t.Errorf("total should not be over or have a target: %+v", total)
+	}
+}
+
+func TestComputeTotalIsOverWhenAnySetIs(t *testing.T) {
+	timing := Compute([]Set{
+		{Durations: []*int{seconds(100)}, Target: seconds(200)},
+		{Durations: []*int{seconds(300)}, Target: seconds(200)},
+	}, 0)
+
+	if timing.Sets[0].Over || !timing.Sets[1].Over {
+		t.Fatalf("unexpected set flags: %+v", timing.Sets)
+	}
+	if !timing.Total.Over || timing.Total.Overrun != 100 {
+		t.Errorf("total over = %v, overrun = %d, want true, 100", timing.Total.Over, timing.Total.Overrun)
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/handlers"
+	"github.com/nahue/playlists/internal/mailer"
+	"github.com/nahue/playlists/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHandler_DeleteAccountRequiresSecondFactor(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	logger := log.New(io.Discard, "", 0)
+	userRepo := database.NewUserRepository(db)
+	mfaRepo := database.NewMFARepository(db)
+	handler := handlers.NewAccountHandler(userRepo, database.NewUserTokenRepository(db), database.NewSessionRepository(db), mfaRepo, mailer.NewLogMailer("noreply@example.com", logger), logger, "http://localhost:3000")
+
+	user, err := userRepo.CreateUser(database.CreateUserRequest{FirstName: "Test", LastName: "User", Email: "test@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	require.NoError(t, mfaRepo.BeginTOTPEnrollment(user.ID, secret))
+	_, err = mfaRepo.ConfirmTOTPEnrollment(user.ID, totp.Step(time.Now())-10, nil)
+	require.NoError(t, err)
+
+	deleteAccount := func(req database.DeleteAccountRequest) int {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodDelete, "/api/profile", bytes.NewReader(body))
+		r = r.WithContext(handlers.WithUserID(r.Context(), user.ID))
+		w := httptest.NewRecorder()
+		handler.DeleteAccount(w, r)
+		return w.Code
+	}
+
+	// The password alone is not enough
+	assert.Equal(t, http.StatusUnauthorized, deleteAccount(database.DeleteAccountRequest{Password: "password123"}))
+	assert.Equal(t, http.StatusUnauthorized, deleteAccount(database.DeleteAccountRequest{Password: "password123", MFACodeRequest: database.MFACodeRequest{Code: "000000"}}))
+
+	existing, err := userRepo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, deleteAccount(database.DeleteAccountRequest{Password: "password123", MFACodeRequest: database.MFACodeRequest{Code: code}}))
+}
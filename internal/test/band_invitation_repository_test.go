@@ -0,0 +1,139 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandInvitationRepository_CreateInvitation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandInvitationRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+
+	req := database.CreateInvitationRequest{Email: "drummer@example.com", Role: database.BandRoleEditor}
+	invitation, err := repo.CreateInvitation(band.ID, ownerID, req, "token-1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, invitation)
+	assert.Equal(t, "Test Band", invitation.BandName)
+	assert.Equal(t, database.InvitationPending, invitation.Status)
+
+	// Inviting the same address again replaces the pending invitation
+	_, err = repo.CreateInvitation(band.ID, ownerID, req, "token-2", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	invitations, err := repo.GetInvitations(band.ID, ownerID)
+	require.NoError(t, err)
+	require.Len(t, invitations, 2)
+	assert.Equal(t, database.InvitationPending, invitations[0].Status)
+	assert.Equal(t, database.InvitationRevoked, invitations[1].Status)
+
+	// The superseded invitation can no longer be accepted
+	_, err = repo.AcceptInvitation(invitation.ID, "token-1", ownerID)
+	assert.ErrorIs(t, err, database.ErrInvitationUnavailable)
+}
+
+func TestBandInvitationRepository_AcceptInvitation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandInvitationRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	drummerID := createTestUser(t, db, "Drummer@example.com")
+	otherID := createTestUser(t, db, "other@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{
+		Name:    "Test Band",
+		Members: []database.BandMember{{Name: "Drummer", Role: "Drums"}},
+	})
+	require.NoError(t, err)
+	memberID := band.Members[0].ID
+
+	req := database.CreateInvitationRequest{Email: "drummer@example.com", Role: database.BandRoleEditor, BandMemberID: &memberID}
+	invitation, err := repo.CreateInvitation(band.ID, ownerID, req, "token-1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Only the invited address can accept
+	_, err = repo.AcceptInvitation(invitation.ID, "token-1", otherID)
+	assert.ErrorIs(t, err, database.ErrInvitationEmailMismatch)
+
+	bandUser, err := repo.AcceptInvitation(invitation.ID, "token-1", drummerID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleEditor, bandUser.Role)
+
+	// The band member contact is linked to the account
+	member, err := bandRepo.GetBandMemberByID(memberID, band.ID, drummerID)
+	require.NoError(t, err)
+	require.NotNil(t, member.UserID)
+	assert.Equal(t, drummerID, *member.UserID)
+
+	// An accepted invitation cannot be used again
+	_, err = repo.AcceptInvitation(invitation.ID, "token-1", drummerID)
+	assert.ErrorIs(t, err, database.ErrInvitationUnavailable)
+}
+
+func TestBandInvitationRepository_ExpiredAndDeclined(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandInvitationRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	guestID := createTestUser(t, db, "guest@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+
+	expired, err := repo.CreateInvitation(band.ID, ownerID, database.CreateInvitationRequest{Email: "guest@example.com", Role: database.BandRoleViewer}, "expired", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, database.InvitationExpired, expired.Status)
+
+	_, err = repo.AcceptInvitation(expired.ID, "expired", guestID)
+	assert.ErrorIs(t, err, database.ErrInvitationUnavailable)
+
+	invitation, err := repo.CreateInvitation(band.ID, ownerID, database.CreateInvitationRequest{Email: "guest@example.com", Role: database.BandRoleViewer}, "fresh", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	err = repo.DeclineInvitation(invitation.ID, "fresh")
+	require.NoError(t, err)
+
+	_, err = repo.AcceptInvitation(invitation.ID, "fresh", guestID)
+	assert.ErrorIs(t, err, database.ErrInvitationUnavailable)
+}
+
+func TestBandInvitationRepository_Permissions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	userRepo := database.NewBandUserRepository(db)
+	repo := database.NewBandInvitationRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	adminID := createTestUser(t, db, "admin@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = userRepo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "admin@example.com", Role: database.BandRoleAdmin})
+	require.NoError(t, err)
+
+	// Admins can invite editors but not other admins
+	_, err = repo.CreateInvitation(band.ID, adminID, database.CreateInvitationRequest{Email: "a@example.com", Role: database.BandRoleEditor}, "t1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = repo.CreateInvitation(band.ID, adminID, database.CreateInvitationRequest{Email: "b@example.com", Role: database.BandRoleAdmin}, "t2", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	revoked, err := repo.RevokeInvitation(999999, band.ID, adminID)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
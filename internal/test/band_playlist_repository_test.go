@@ -0,0 +1,353 @@
+package test
+
+import (
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandPlaylistRepository_SectionsAndTiming(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandPlaylistRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	playlist, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday", ChangeoverDuration: 30, SetTargetDuration: intPtr(600)})
+	require.NoError(t, err)
+
+	// An empty playlist is a single empty set
+	require.Len(t, playlist.Timing.Sets, 1)
+	assert.Equal(t, 0, playlist.Timing.Total.Total)
+
+	first, err := repo.CreateSection(playlist.ID, band.ID, userID, database.CreateSectionRequest{Name: "Set 1", Position: 0})
+	require.NoError(t, err)
+	encore, err := repo.CreateSection(playlist.ID, band.ID, userID, database.CreateSectionRequest{Name: "Encore", Position: 1, TargetDuration: intPtr(300)})
+	require.NoError(t, err)
+
+	songs := []struct {
+		title    string
+		duration int
+		section  int
+	}{
+		{"Innuendo", 390, first.ID},
+		{"Under Pressure", 250, first.ID},
+		{"Bohemian Rhapsody", 355, encore.ID},
+	}
+	for _, song := range songs {
+		sectionID := song.section
+		_, err := repo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{
+			Artist:       "Queen",
+			Song:         song.title,
+			SongMetadata: database.SongMetadata{Duration: intPtr(song.duration)},
+			SectionID:    &sectionID,
+		})
+		require.NoError(t, err)
+	}
+
+	got, err := repo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, got.Sections, 2)
+
+	// Without songs outside sections there is one set per section
+	require.Len(t, got.Timing.Sets, 2)
+	set1 := got.Timing.Sets[0]
+	assert.Equal(t, "Set 1", set1.Name)
+	assert.Equal(t, 670, set1.Total)
+	assert.True(t, set1.Over)
+	assert.Equal(t, 70, set1.Overrun)
+
+	encoreTiming := got.Timing.Sets[1]
+	assert.Equal(t, 355, encoreTiming.Total)
+	assert.Equal(t, 55, encoreTiming.Overrun)
+	assert.Equal(t, 1025, got.Timing.Total.Total)
+
+	// Songs of a deleted section stay in the playlist without a section
+	deleted, err := repo.DeleteSection(encore.ID, playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	got, err = repo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, got.Songs, 3)
+	assert.Nil(t, got.Songs[0].SectionID)
+	assert.Equal(t, "Bohemian Rhapsody", got.Songs[0].Song)
+	require.Len(t, got.Timing.Sets, 2)
+	assert.Nil(t, got.Timing.Sets[0].SectionID)
+
+	// Songs cannot be put in sections of other playlists
+	other, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Saturday"})
+	require.NoError(t, err)
+	_, err = repo.AddSong(other.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo", SectionID: &first.ID})
+	assert.ErrorIs(t, err, database.ErrSectionNotFound)
+}
+
+func songTitles(songs []database.BandPlaylistSong) []string {
+	titles := make([]string, len(songs))
+	for i, song := range songs {
+		titles[i] = song.Song
+	}
+	return titles
+}
+
+func TestBandPlaylistRepository_OrderAndMove(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandPlaylistRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	friday, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+	saturday, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Saturday"})
+	require.NoError(t, err)
+
+	// Songs without a position are appended, songs with one are inserted there
+	a, err := repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "A"})
+	require.NoError(t, err)
+	b, err := repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "B"})
+	require.NoError(t, err)
+	c, err := repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "C", Position: intPtr(0)})
+	require.NoError(t, err)
+
+	songs, err := repo.GetPlaylistSongs(friday.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "A", "B"}, songTitles(songs))
+	assert.Equal(t, 2, songs[2].Position)
+
+	// Reordering needs every song exactly once
+	_, err = repo.ReorderSongs(friday.ID, band.ID, userID, []int{a.ID, b.ID})
+	assert.ErrorIs(t, err, database.ErrInvalidSongOrder)
+	_, err = repo.ReorderSongs(friday.ID, band.ID, userID, []int{a.ID, a.ID, b.ID})
+	assert.ErrorIs(t, err, database.ErrInvalidSongOrder)
+
+	songs, err = repo.ReorderSongs(friday.ID, band.ID, userID, []int{b.ID, c.ID, a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"B", "C", "A"}, songTitles(songs))
+
+	// Copying keeps the song in the source playlist
+	copied, err := repo.MoveSong(a.ID, friday.ID, band.ID, userID, database.MoveSongRequest{PlaylistID: saturday.ID, Copy: true})
+	require.NoError(t, err)
+	assert.NotEqual(t, a.ID, copied.ID)
+	assert.Equal(t, saturday.ID, copied.PlaylistID)
+
+	// Moving takes it out and closes the gap
+	moved, err := repo.MoveSong(c.ID, friday.ID, band.ID, userID, database.MoveSongRequest{PlaylistID: saturday.ID, Position: intPtr(0)})
+	require.NoError(t, err)
+	assert.Equal(t, c.ID, moved.ID)
+
+	songs, err = repo.GetPlaylistSongs(friday.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"B", "A"}, songTitles(songs))
+	assert.Equal(t, 1, songs[1].Position)
+
+	songs, err = repo.GetPlaylistSongs(saturday.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "A"}, songTitles(songs))
+
+	// Songs only move between playlists of the same band
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	foreign, err := repo.CreatePlaylist(otherBand.ID, userID, database.CreatePlaylistRequest{Name: "Elsewhere"})
+	require.NoError(t, err)
+	_, err = repo.MoveSong(b.ID, friday.ID, band.ID, userID, database.MoveSongRequest{PlaylistID: foreign.ID})
+	assert.ErrorIs(t, err, database.ErrPlaylistNotFound)
+}
+
+func TestBandPlaylistRepository_DuplicateAndTemplates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandPlaylistRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	friday, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday", ChangeoverDuration: 20})
+	require.NoError(t, err)
+	encore, err := repo.CreateSection(friday.ID, band.ID, userID, database.CreateSectionRequest{Name: "Encore"})
+	require.NoError(t, err)
+	_, err = repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo"})
+	require.NoError(t, err)
+	_, err = repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Bohemian Rhapsody", SectionID: &encore.ID})
+	require.NoError(t, err)
+	_, err = repo.AddSong(friday.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo", Notes: "Short version"})
+	require.NoError(t, err)
+
+	// A duplicate has its own sections and songs, overrides included
+	copied, err := repo.DuplicatePlaylist(friday.ID, band.ID, userID, database.DuplicatePlaylistRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "Friday (copy)", copied.Name)
+	assert.Equal(t, 20, copied.ChangeoverDuration)
+	require.Len(t, copied.Sections, 1)
+	assert.NotEqual(t, encore.ID, copied.Sections[0].ID)
+	require.Len(t, copied.Songs, 3)
+	assert.Equal(t, []string{"Innuendo", "Innuendo", "Bohemian Rhapsody"}, songTitles(copied.Songs))
+	assert.Equal(t, "Short version", copied.Songs[1].Notes)
+	require.NotNil(t, copied.Songs[2].SectionID)
+	assert.Equal(t, copied.Sections[0].ID, *copied.Songs[2].SectionID)
+
+	missing, err := repo.DuplicatePlaylist(friday.ID+1000, band.ID, userID, database.DuplicatePlaylistRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// Only templates are listed as templates and can be created from
+	template, err := repo.DuplicatePlaylist(friday.ID, band.ID, userID, database.DuplicatePlaylistRequest{Name: "Standard set", IsTemplate: true})
+	require.NoError(t, err)
+	assert.True(t, template.IsTemplate)
+
+	templates, err := repo.GetTemplates(band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, template.ID, templates[0].ID)
+
+	_, err = repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Saturday", TemplateID: &friday.ID})
+	assert.ErrorIs(t, err, database.ErrTemplateNotFound)
+
+	saturday, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Saturday", TemplateID: &template.ID})
+	require.NoError(t, err)
+	assert.False(t, saturday.IsTemplate)
+	assert.Equal(t, 20, saturday.ChangeoverDuration)
+	assert.Len(t, saturday.Songs, 3)
+	assert.Len(t, saturday.Sections, 1)
+}
+
+func TestBandPlaylistRepository_ImportSongs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	songRepo := database.NewBandSongRepository(db)
+	repo := database.NewBandPlaylistRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	playlist, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+	_, err = repo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo"})
+	require.NoError(t, err)
+
+	songs := database.ImportSongsRequest{
+		Sections: []database.CreateSectionRequest{{Name: "Encore", TargetDuration: intPtr(600)}},
+		Songs: []database.ImportSongRequest{
+			{AddSongRequest: database.AddSongRequest{Artist: "Queen", Song: "Under Pressure", SongMetadata: database.SongMetadata{Duration: intPtr(250)}}},
+			{AddSongRequest: database.AddSongRequest{Artist: "Queen", Song: "Bohemian Rhapsody"}, Section: "encore"},
+		},
+	}
+
+	// A dry run previews the songs without saving them, their catalog entries or sections
+	preview, err := repo.ImportSongs(playlist.ID, band.ID, userID, songs, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Under Pressure", "Bohemian Rhapsody"}, songTitles(preview))
+	assert.Equal(t, 1, preview[0].Position)
+
+	current, err := repo.GetPlaylistSongs(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, current, 1)
+	catalog, err := songRepo.GetSongs(band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, catalog, 1)
+	got, err := repo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Sections)
+
+	// Imported songs are appended in file order
+	imported, err := repo.ImportSongs(playlist.ID, band.ID, userID, songs, false)
+	require.NoError(t, err)
+	require.Len(t, imported, 2)
+	assert.Equal(t, 250, *imported[0].Duration)
+
+	current, err = repo.GetPlaylistSongs(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Innuendo", "Under Pressure", "Bohemian Rhapsody"}, songTitles(current))
+
+	// Sections are matched by name, so importing again reuses the encore
+	got, err = repo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, got.Sections, 1)
+	assert.Equal(t, "Encore", got.Sections[0].Name)
+	assert.Equal(t, 600, *got.Sections[0].TargetDuration)
+	require.NotNil(t, current[2].SectionID)
+	assert.Equal(t, got.Sections[0].ID, *current[2].SectionID)
+
+	_, err = repo.ImportSongs(playlist.ID, band.ID, userID, database.ImportSongsRequest{
+		Songs: []database.ImportSongRequest{{AddSongRequest: database.AddSongRequest{Artist: "Queen", Song: "Mustapha"}, Section: "ENCORE"}},
+	}, false)
+	require.NoError(t, err)
+	got, err = repo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, got.Sections, 1)
+	assert.Equal(t, []string{"Innuendo", "Under Pressure", "Bohemian Rhapsody", "Mustapha"}, songTitles(got.Songs))
+
+	// A failing song leaves the playlist unchanged
+	missing := 999999
+	_, err = repo.ImportSongs(playlist.ID, band.ID, userID, database.ImportSongsRequest{
+		Songs: []database.ImportSongRequest{
+			{AddSongRequest: database.AddSongRequest{Artist: "Queen", Song: "Innuendo"}},
+			{AddSongRequest: database.AddSongRequest{BandSongID: &missing}},
+		},
+	}, false)
+	assert.ErrorIs(t, err, database.ErrBandSongNotFound)
+
+	current, err = repo.GetPlaylistSongs(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, current, 4)
+}
+
+func TestBandPlaylistRepository_SongChartAndKey(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	songRepo := database.NewBandSongRepository(db)
+	repo := database.NewBandPlaylistRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	playlist, err := repo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+
+	catalog, err := songRepo.CreateSong(band.ID, userID, database.CreateBandSongRequest{Artist: "The Beatles", Title: "Let It Be", SongMetadata: database.SongMetadata{SongKey: "C"}})
+	require.NoError(t, err)
+	_, err = songRepo.SetChart(catalog.ID, band.ID, userID, "[C]When I find myself")
+	require.NoError(t, err)
+	song, err := repo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{BandSongID: &catalog.ID})
+	require.NoError(t, err)
+
+	songChart, err := repo.GetSongChart(song.ID, playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.NotNil(t, songChart)
+	assert.Equal(t, "[C]When I find myself", songChart.Chart.ChordPro)
+	assert.Equal(t, "C", songChart.Chart.SongKey)
+
+	// The key is saved as the playlist's override
+	updated, err := repo.SetSongKey(song.ID, playlist.ID, band.ID, userID, "D")
+	require.NoError(t, err)
+	assert.Equal(t, "D", updated.SongKey)
+	require.NotNil(t, updated.Overrides.SongKey)
+	assert.Equal(t, "D", *updated.Overrides.SongKey)
+
+	// Going back to the catalog key clears the override
+	updated, err = repo.SetSongKey(song.ID, playlist.ID, band.ID, userID, "C")
+	require.NoError(t, err)
+	assert.Equal(t, "C", updated.SongKey)
+	assert.Nil(t, updated.Overrides.SongKey)
+
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	songChart, err = repo.GetSongChart(song.ID, playlist.ID, otherBand.ID, userID)
+	require.NoError(t, err)
+	assert.Nil(t, songChart)
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestBandSongRepository_CreateSong(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandSongRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+
+	song, err := repo.CreateSong(band.ID, userID, database.CreateBandSongRequest{
+		Artist:       "Queen",
+		Title:        "Innuendo",
+		SongMetadata: database.SongMetadata{SongKey: "Em", Tempo: intPtr(96), TimeSignature: "4/4", Duration: intPtr(390)},
+		Notes:        "Long intro",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, song)
+	assert.Equal(t, "Innuendo", song.Title)
+	assert.Equal(t, "Em", song.SongKey)
+	require.NotNil(t, song.Tempo)
+	assert.Equal(t, 96, *song.Tempo)
+	assert.Equal(t, "4/4", song.TimeSignature)
+	require.NotNil(t, song.Duration)
+	assert.Equal(t, 390, *song.Duration)
+
+	// Artist and title are unique per band regardless of case
+	_, err = repo.CreateSong(band.ID, userID, database.CreateBandSongRequest{Artist: "queen", Title: " INNUENDO "})
+	assert.ErrorIs(t, err, database.ErrBandSongExists)
+
+	songs, err := repo.GetSongs(band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, songs, 1)
+
+	// Users outside the band do not see the catalog
+	otherID := createTestUser(t, db, "other@example.com")
+	songs, err = repo.GetSongs(band.ID, otherID)
+	require.NoError(t, err)
+	assert.Nil(t, songs)
+}
+
+func TestBandSongRepository_PlaylistSongsShareCatalog(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewBandSongRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	first, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+	second, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Saturday"})
+	require.NoError(t, err)
+
+	// Adding by artist and song creates the catalog song with the given details
+	added, err := playlistRepo.AddSong(first.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo", Notes: "Long intro", SongMetadata: database.SongMetadata{SongKey: "Em"}})
+	require.NoError(t, err)
+	require.NotNil(t, added)
+	assert.Equal(t, "Long intro", added.Notes)
+	assert.Nil(t, added.Overrides.Notes)
+
+	// The same song in another playlist reuses the catalog song and overrides its key
+	reused, err := playlistRepo.AddSong(second.ID, band.ID, userID, database.AddSongRequest{Artist: "QUEEN", Song: "innuendo", SongMetadata: database.SongMetadata{SongKey: "Dm", Capo: intPtr(0)}})
+	require.NoError(t, err)
+	assert.Equal(t, added.BandSongID, reused.BandSongID)
+	assert.Equal(t, "Innuendo", reused.Song)
+	assert.Equal(t, "Dm", reused.SongKey)
+	require.NotNil(t, reused.Overrides.SongKey)
+
+	songs, err := repo.GetSongs(band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, songs, 1)
+
+	// Catalog changes show up in every playlist except where overridden
+	_, err = repo.UpdateSong(added.BandSongID, band.ID, userID, database.UpdateBandSongRequest{Artist: "Queen", Title: "Innuendo", SongMetadata: database.SongMetadata{SongKey: "F#m", Capo: intPtr(2)}, Notes: "Flamenco break"})
+	require.NoError(t, err)
+
+	firstSongs, err := playlistRepo.GetPlaylistSongs(first.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, firstSongs, 1)
+	assert.Equal(t, "Flamenco break", firstSongs[0].Notes)
+	assert.Equal(t, "F#m", firstSongs[0].SongKey)
+
+	secondSongs, err := playlistRepo.GetPlaylistSongs(second.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, secondSongs, 1)
+	assert.Equal(t, "Flamenco break", secondSongs[0].Notes)
+	assert.Equal(t, "Dm", secondSongs[0].SongKey)
+	require.NotNil(t, secondSongs[0].Capo)
+	assert.Equal(t, 0, *secondSongs[0].Capo)
+
+	// Songs used in playlists cannot be removed from the catalog
+	_, err = repo.DeleteSong(added.BandSongID, band.ID, userID)
+	assert.ErrorIs(t, err, database.ErrBandSongInUse)
+
+	require.NoError(t, playlistRepo.DeleteSong(added.ID, first.ID, band.ID, userID))
+	require.NoError(t, playlistRepo.DeleteSong(reused.ID, second.ID, band.ID, userID))
+	deleted, err := repo.DeleteSong(added.BandSongID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+func TestBandSongRepository_AddSongByID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewBandSongRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	playlist, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+
+	song, err := repo.CreateSong(band.ID, userID, database.CreateBandSongRequest{Artist: "Queen", Title: "Innuendo", SongMetadata: database.SongMetadata{Tempo: intPtr(96)}})
+	require.NoError(t, err)
+	foreign, err := repo.CreateSong(otherBand.ID, userID, database.CreateBandSongRequest{Artist: "Queen", Title: "Innuendo"})
+	require.NoError(t, err)
+
+	// An override equal to the catalog value follows the catalog
+	added, err := playlistRepo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{BandSongID: &song.ID, SongMetadata: database.SongMetadata{Tempo: intPtr(96)}})
+	require.NoError(t, err)
+	assert.Equal(t, song.ID, added.BandSongID)
+	assert.Nil(t, added.Overrides.Tempo)
+
+	// Songs of another band's catalog cannot be used
+	_, err = playlistRepo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{BandSongID: &foreign.ID})
+	assert.ErrorIs(t, err, database.ErrBandSongNotFound)
+}
+
+func TestBandSongRepository_Chart(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandSongRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	song, err := repo.CreateSong(band.ID, userID, database.CreateBandSongRequest{Artist: "The Beatles", Title: "Let It Be"})
+	require.NoError(t, err)
+	assert.False(t, song.HasChart)
+
+	chart, err := repo.SetChart(song.ID, band.ID, userID, "{title: Let It Be}\n[C]When I find myself")
+	require.NoError(t, err)
+	require.NotNil(t, chart)
+	assert.Equal(t, song.ID, chart.SongID)
+
+	chart, err = repo.GetChart(song.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "{title: Let It Be}\n[C]When I find myself", chart.ChordPro)
+
+	song, err = repo.GetSong(song.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, song.HasChart)
+
+	// Clearing the chart removes it
+	_, err = repo.SetChart(song.ID, band.ID, userID, "")
+	require.NoError(t, err)
+	song, err = repo.GetSong(song.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.False(t, song.HasChart)
+
+	// Songs of other bands are not found
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	chart, err = repo.SetChart(song.ID, otherBand.ID, userID, "[C]x")
+	require.NoError(t, err)
+	assert.Nil(t, chart)
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandUserRepository_CreateBandMakesOwner(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandUserRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleOwner, band.Role)
+
+	role, err := repo.GetRole(band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleOwner, role)
+}
+
+func TestBandUserRepository_AddBandUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandUserRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	viewerID := createTestUser(t, db, "viewer@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+
+	bandUser, err := repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleViewer})
+	require.NoError(t, err)
+	require.NotNil(t, bandUser)
+	assert.Equal(t, viewerID, bandUser.UserID)
+	assert.Equal(t, database.BandRoleViewer, bandUser.Role)
+	assert.Equal(t, "viewer@example.com", bandUser.Email)
+
+	// The shared band shows up for the new user
+	bands, err := bandRepo.GetBandsByUserID(viewerID)
+	require.NoError(t, err)
+	require.Len(t, bands, 1)
+	assert.Equal(t, database.BandRoleViewer, bands[0].Role)
+
+	// Adding the same user twice fails
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleEditor})
+	assert.ErrorIs(t, err, database.ErrAlreadyMember)
+
+	// Unknown users cannot be added
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "nobody@example.com", Role: database.BandRoleViewer})
+	assert.ErrorIs(t, err, database.ErrUserNotFound)
+
+	// Viewers cannot manage membership
+	createTestUser(t, db, "other@example.com")
+	_, err = repo.AddBandUser(band.ID, viewerID, database.AddBandUserRequest{Email: "other@example.com", Role: database.BandRoleViewer})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	users, err := repo.GetBandUsers(band.ID, viewerID)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestBandUserRepository_RolePermissions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewBandUserRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	editorID := createTestUser(t, db, "editor@example.com")
+	viewerID := createTestUser(t, db, "viewer@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "editor@example.com", Role: database.BandRoleEditor})
+	require.NoError(t, err)
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleViewer})
+	require.NoError(t, err)
+
+	// Editors can change playlists
+	playlist, err := playlistRepo.CreatePlaylist(band.ID, editorID, database.CreatePlaylistRequest{Name: "Setlist"})
+	require.NoError(t, err)
+	require.NotNil(t, playlist)
+
+	// Viewers can read but not write
+	playlists, err := playlistRepo.GetPlaylistsByBandID(band.ID, viewerID)
+	require.NoError(t, err)
+	assert.Len(t, playlists, 1)
+
+	_, err = playlistRepo.CreatePlaylist(band.ID, viewerID, database.CreatePlaylistRequest{Name: "Nope"})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	// Editors cannot change band details or delete the band
+	_, err = bandRepo.UpdateBand(band.ID, editorID, database.UpdateBandRequest{Name: "Renamed"})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	err = bandRepo.DeleteBand(band.ID, editorID)
+	assert.ErrorIs(t, err, database.ErrForbidden)
+}
+
+func TestBandUserRepository_UpdateBandUserRole(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandUserRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	adminID := createTestUser(t, db, "admin@example.com")
+	editorID := createTestUser(t, db, "editor@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "admin@example.com", Role: database.BandRoleAdmin})
+	require.NoError(t, err)
+	_, err = repo.AddBandUser(band.ID, adminID, database.AddBandUserRequest{Email: "editor@example.com", Role: database.BandRoleEditor})
+	require.NoError(t, err)
+
+	// Admins can change editors and viewers
+	bandUser, err := repo.UpdateBandUserRole(band.ID, editorID, adminID, database.UpdateBandUserRequest{Role: database.BandRoleViewer})
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleViewer, bandUser.Role)
+
+	// Admins cannot promote to owner
+	_, err = repo.UpdateBandUserRole(band.ID, editorID, adminID, database.UpdateBandUserRequest{Role: database.BandRoleOwner})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	// The last owner cannot be demoted
+	_, err = repo.UpdateBandUserRole(band.ID, ownerID, ownerID, database.UpdateBandUserRequest{Role: database.BandRoleAdmin})
+	assert.ErrorIs(t, err, database.ErrLastOwner)
+
+	// Once there is a second owner, the first can step down
+	_, err = repo.UpdateBandUserRole(band.ID, adminID, ownerID, database.UpdateBandUserRequest{Role: database.BandRoleOwner})
+	require.NoError(t, err)
+	bandUser, err = repo.UpdateBandUserRole(band.ID, ownerID, ownerID, database.UpdateBandUserRequest{Role: database.BandRoleAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleAdmin, bandUser.Role)
+}
+
+func TestBandUserRepository_RemoveBandUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	repo := database.NewBandUserRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	viewerID := createTestUser(t, db, "viewer@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = repo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleViewer})
+	require.NoError(t, err)
+
+	// Viewers cannot remove other users
+	_, err = repo.RemoveBandUser(band.ID, ownerID, viewerID)
+	assert.ErrorIs(t, err, database.ErrForbidden)
+
+	// The last owner cannot leave
+	_, err = repo.RemoveBandUser(band.ID, ownerID, ownerID)
+	assert.ErrorIs(t, err, database.ErrLastOwner)
+
+	// Any member can leave the band
+	removed, err := repo.RemoveBandUser(band.ID, viewerID, viewerID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	fetched, err := bandRepo.GetBandByID(band.ID, viewerID)
+	require.NoError(t, err)
+	assert.Nil(t, fetched)
+}
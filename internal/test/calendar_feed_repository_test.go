@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarFeedRepository_Feeds(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	gigRepo := database.NewGigRepository(db)
+	repo := database.NewCalendarFeedRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Side Project"})
+	require.NoError(t, err)
+
+	details := database.GigDetails{Name: "Album launch", StartsAt: time.Now().Add(24 * time.Hour), Timezone: "Europe/Madrid"}
+	require.NoError(t, details.Normalize())
+	gig, err := gigRepo.CreateGig(band.ID, userID, database.CreateGigRequest{GigDetails: details})
+	require.NoError(t, err)
+	assert.Equal(t, 0, gig.Sequence)
+	_, err = gigRepo.CreateGig(otherBand.ID, userID, database.CreateGigRequest{GigDetails: details})
+	require.NoError(t, err)
+
+	// Changing a gig raises its sequence
+	details.Status = database.GigCancelled
+	gig, err = gigRepo.UpdateGig(gig.ID, band.ID, userID, database.UpdateGigRequest{GigDetails: details})
+	require.NoError(t, err)
+	assert.Equal(t, 1, gig.Sequence)
+
+	bandFeed, err := repo.CreateFeed(userID, database.CreateCalendarFeedRequest{BandID: &band.ID}, "cal_band-token", "cal_band-tok")
+	require.NoError(t, err)
+	require.NotNil(t, bandFeed)
+	assert.Equal(t, "Test Band", bandFeed.BandName)
+	allFeed, err := repo.CreateFeed(userID, database.CreateCalendarFeedRequest{}, "cal_all-token", "cal_all-toke")
+	require.NoError(t, err)
+	assert.Nil(t, allFeed.BandID)
+
+	feed, err := repo.AuthenticateFeed("cal_band-token")
+	require.NoError(t, err)
+	require.NotNil(t, feed)
+	assert.NotNil(t, feed.LastUsedAt)
+
+	gigs, err := repo.GetFeedGigs(feed)
+	require.NoError(t, err)
+	require.Len(t, gigs, 1)
+	assert.Equal(t, "Test Band", gigs[0].BandName)
+	assert.Equal(t, database.GigCancelled, gigs[0].Status)
+	assert.Equal(t, "Europe/Madrid", gigs[0].StartsAt.Location().String())
+
+	gigs, err = repo.GetFeedGigs(allFeed)
+	require.NoError(t, err)
+	assert.Len(t, gigs, 2)
+
+	// Creating a feed again replaces the old token
+	_, err = repo.CreateFeed(userID, database.CreateCalendarFeedRequest{BandID: &band.ID}, "cal_new-token", "cal_new-toke")
+	require.NoError(t, err)
+	feed, err = repo.AuthenticateFeed("cal_band-token")
+	require.NoError(t, err)
+	assert.Nil(t, feed)
+
+	feeds, err := repo.GetFeeds(userID)
+	require.NoError(t, err)
+	assert.Len(t, feeds, 2)
+
+	// Only band members get a feed of the band
+	otherID := createTestUser(t, db, "other@example.com")
+	feed, err = repo.CreateFeed(otherID, database.CreateCalendarFeedRequest{BandID: &band.ID}, "cal_other-token", "cal_other-to")
+	require.NoError(t, err)
+	assert.Nil(t, feed)
+
+	deleted, err := repo.DeleteFeed(allFeed.ID, otherID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = repo.DeleteFeed(allFeed.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	feed, err = repo.AuthenticateFeed("cal_all-token")
+	require.NoError(t, err)
+	assert.Nil(t, feed)
+}
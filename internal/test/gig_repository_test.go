@@ -0,0 +1,141 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGigRepository_CreateAndListGigs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewGigRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	first, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "First set"})
+	require.NoError(t, err)
+	second, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Second set"})
+	require.NoError(t, err)
+
+	startsAt := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second)
+	loadIn := startsAt.Add(-3 * time.Hour)
+	req := database.CreateGigRequest{
+		GigDetails: database.GigDetails{
+			Name:      "Album launch",
+			StartsAt:  startsAt,
+			Timezone:  "Europe/Madrid",
+			VenueName: "Sala Apolo",
+			LoadInAt:  &loadIn,
+			Fee:       intPtr(50000),
+			Currency:  "eur",
+		},
+		PlaylistIDs: []int{second.ID, first.ID},
+	}
+	require.NoError(t, req.Normalize())
+
+	gig, err := repo.CreateGig(band.ID, userID, req)
+	require.NoError(t, err)
+	require.NotNil(t, gig)
+	assert.Equal(t, database.GigTentative, gig.Status)
+	assert.Equal(t, "EUR", gig.Currency)
+	assert.Equal(t, "Europe/Madrid", gig.StartsAt.Location().String())
+	assert.True(t, startsAt.Equal(gig.StartsAt))
+	require.NotNil(t, gig.LoadInAt)
+	assert.True(t, loadIn.Equal(*gig.LoadInAt))
+	assert.Equal(t, []int64{int64(second.ID), int64(first.ID)}, []int64(gig.PlaylistIDs))
+
+	withPlaylists, err := repo.GetGig(gig.ID, band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, withPlaylists.Playlists, 2)
+	assert.Equal(t, "Second set", withPlaylists.Playlists[0].Name)
+
+	past := database.GigDetails{Name: "Last year", StartsAt: time.Now().AddDate(-1, 0, 0), Timezone: "UTC"}
+	require.NoError(t, past.Normalize())
+	_, err = repo.CreateGig(band.ID, userID, database.CreateGigRequest{GigDetails: past})
+	require.NoError(t, err)
+
+	upcoming, err := repo.GetGigs(band.ID, userID, database.EventsUpcoming)
+	require.NoError(t, err)
+	require.Len(t, upcoming, 1)
+	assert.Equal(t, "Album launch", upcoming[0].Name)
+
+	pastGigs, err := repo.GetGigs(band.ID, userID, database.EventsPast)
+	require.NoError(t, err)
+	require.Len(t, pastGigs, 1)
+	assert.Equal(t, "Last year", pastGigs[0].Name)
+	assert.Empty(t, pastGigs[0].PlaylistIDs)
+
+	all, err := repo.GetGigs(band.ID, userID, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	// Users outside the band do not see its gigs
+	otherID := createTestUser(t, db, "other@example.com")
+	all, err = repo.GetGigs(band.ID, otherID, "")
+	require.NoError(t, err)
+	assert.Nil(t, all)
+}
+
+func TestGigRepository_UpdateAndDeleteGig(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewGigRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	playlist, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Main set"})
+	require.NoError(t, err)
+	foreign, err := playlistRepo.CreatePlaylist(otherBand.ID, userID, database.CreatePlaylistRequest{Name: "Not ours"})
+	require.NoError(t, err)
+
+	details := database.GigDetails{Name: "Festival", StartsAt: time.Now().Add(48 * time.Hour), Timezone: "America/New_York"}
+	require.NoError(t, details.Normalize())
+
+	// Playlists of another band cannot be linked
+	_, err = repo.CreateGig(band.ID, userID, database.CreateGigRequest{GigDetails: details, PlaylistIDs: []int{foreign.ID}})
+	assert.ErrorIs(t, err, database.ErrPlaylistNotFound)
+
+	gig, err := repo.CreateGig(band.ID, userID, database.CreateGigRequest{GigDetails: details, PlaylistIDs: []int{playlist.ID}})
+	require.NoError(t, err)
+
+	details.Status = database.GigConfirmed
+	details.VenueAddress = "1 Main St"
+	updated, err := repo.UpdateGig(gig.ID, band.ID, userID, database.UpdateGigRequest{GigDetails: details})
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.Equal(t, database.GigConfirmed, updated.Status)
+	assert.Equal(t, "1 Main St", updated.VenueAddress)
+	assert.Empty(t, updated.PlaylistIDs)
+
+	missing, err := repo.UpdateGig(gig.ID, otherBand.ID, userID, database.UpdateGigRequest{GigDetails: details})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	deleted, err := repo.DeleteGig(gig.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	// Deleting a gig keeps its playlists
+	kept, err := playlistRepo.GetPlaylistByID(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.NotNil(t, kept)
+
+	deleted, err = repo.DeleteGig(gig.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
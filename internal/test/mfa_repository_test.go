@@ -0,0 +1,147 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFARepository_Enrollment(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewMFARepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	userTOTP, err := repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.Nil(t, userTOTP)
+
+	// Enrollment stays pending until confirmed and can be restarted
+	require.NoError(t, repo.BeginTOTPEnrollment(userID, "SECRET1"))
+	require.NoError(t, repo.BeginTOTPEnrollment(userID, "SECRET2"))
+
+	userTOTP, err = repo.GetTOTP(userID)
+	require.NoError(t, err)
+	require.NotNil(t, userTOTP)
+	assert.Equal(t, "SECRET2", userTOTP.Secret)
+	assert.False(t, userTOTP.Enabled())
+
+	enabled, err := repo.ConfirmTOTPEnrollment(userID, 100, []string{"aaaa-bbbb", "cccc-dddd"})
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	userTOTP, err = repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.True(t, userTOTP.Enabled())
+
+	count, err := repo.CountRecoveryCodes(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// An enabled secret cannot be replaced or confirmed again
+	err = repo.BeginTOTPEnrollment(userID, "SECRET3")
+	assert.ErrorIs(t, err, database.ErrMFAAlreadyEnabled)
+
+	enabled, err = repo.ConfirmTOTPEnrollment(userID, 101, nil)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	disabled, err := repo.DisableTOTP(userID)
+	require.NoError(t, err)
+	assert.True(t, disabled)
+
+	userTOTP, err = repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.Nil(t, userTOTP)
+
+	count, err = repo.CountRecoveryCodes(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestMFARepository_CodesAreSingleUse(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewMFARepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+	otherID := createTestUser(t, db, "other@example.com")
+
+	require.NoError(t, repo.BeginTOTPEnrollment(userID, "SECRET"))
+	_, err := repo.ConfirmTOTPEnrollment(userID, 100, []string{"aaaa-bbbb"})
+	require.NoError(t, err)
+
+	// The confirming step and earlier ones cannot be replayed
+	used, err := repo.UseTOTPStep(userID, 100)
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = repo.UseTOTPStep(userID, 101)
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = repo.UseTOTPStep(userID, 101)
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	// Recovery codes belong to their user and work once
+	used, err = repo.UseRecoveryCode(otherID, "aaaa-bbbb")
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = repo.UseRecoveryCode(userID, "aaaa-bbbb")
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = repo.UseRecoveryCode(userID, "aaaa-bbbb")
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	// New codes replace the old ones
+	require.NoError(t, repo.ReplaceRecoveryCodes(userID, []string{"eeee-ffff", "gggg-hhhh"}))
+	count, err := repo.CountRecoveryCodes(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestMFARepository_RecordFailedAttempt(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewMFARepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	require.NoError(t, repo.BeginTOTPEnrollment(userID, "SECRET"))
+	_, err := repo.ConfirmTOTPEnrollment(userID, 100, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, repo.RecordFailedAttempt(userID, 3, time.Minute))
+	}
+
+	userTOTP, err := repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, userTOTP.FailedAttempts)
+	assert.False(t, userTOTP.Locked(time.Now()))
+
+	require.NoError(t, repo.RecordFailedAttempt(userID, 3, time.Minute))
+
+	userTOTP, err = repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.True(t, userTOTP.Locked(time.Now()))
+
+	// A valid code clears the failures
+	used, err := repo.UseTOTPStep(userID, 101)
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	userTOTP, err = repo.GetTOTP(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, userTOTP.FailedAttempts)
+	assert.False(t, userTOTP.Locked(time.Now()))
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalAccessTokenRepository_CreateAndAuthenticate(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPersonalAccessTokenRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	req := database.CreatePersonalAccessTokenRequest{
+		Name:   "Setlist script",
+		Scopes: []database.TokenScope{database.ScopeRead, database.ScopeBandsWrite},
+	}
+	pat, err := repo.CreateToken(userID, req, "plt_secret-1", "plt_secret-")
+	require.NoError(t, err)
+	assert.Equal(t, "Setlist script", pat.Name)
+	assert.True(t, pat.HasScope(database.ScopeBandsWrite))
+	assert.Nil(t, pat.LastUsedAt)
+
+	authenticated, err := repo.AuthenticateToken("plt_secret-1")
+	require.NoError(t, err)
+	require.NotNil(t, authenticated)
+	assert.Equal(t, pat.ID, authenticated.ID)
+	assert.Equal(t, userID, authenticated.UserID)
+	assert.NotNil(t, authenticated.LastUsedAt)
+
+	authenticated, err = repo.AuthenticateToken("plt_unknown")
+	require.NoError(t, err)
+	assert.Nil(t, authenticated)
+
+	// Expired tokens are rejected
+	past := time.Now().Add(-time.Minute)
+	_, err = repo.CreateToken(userID, database.CreatePersonalAccessTokenRequest{Name: "Old", Scopes: []database.TokenScope{database.ScopeRead}, ExpiresAt: &past}, "plt_secret-2", "plt_secret-")
+	require.NoError(t, err)
+
+	authenticated, err = repo.AuthenticateToken("plt_secret-2")
+	require.NoError(t, err)
+	assert.Nil(t, authenticated)
+}
+
+func TestPersonalAccessTokenRepository_GetAndDeleteTokens(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPersonalAccessTokenRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+	otherID := createTestUser(t, db, "other@example.com")
+
+	req := database.CreatePersonalAccessTokenRequest{Name: "Script", Scopes: []database.TokenScope{database.ScopeRead}}
+	pat, err := repo.CreateToken(userID, req, "plt_secret-1", "plt_secret-")
+	require.NoError(t, err)
+
+	tokens, err := repo.GetTokens(userID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, []string{"read"}, []string(tokens[0].Scopes))
+
+	tokens, err = repo.GetTokens(otherID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	// Only the owner can revoke a token
+	deleted, err := repo.DeleteToken(pat.ID, otherID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = repo.DeleteToken(pat.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	authenticated, err := repo.AuthenticateToken("plt_secret-1")
+	require.NoError(t, err)
+	assert.Nil(t, authenticated)
+}
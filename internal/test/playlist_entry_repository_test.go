@@ -0,0 +1,136 @@
+package test
+
+import (
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylistEntryRepository_CreateEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPlaylistEntryRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	req := database.CreatePlaylistEntryRequest{Artist: "Queen", Song: "Bohemian Rhapsody", UserName: "Freddie"}
+
+	entry, err := repo.CreateEntry(userID, req)
+	require.NoError(t, err)
+	assert.NotZero(t, entry.ID)
+	assert.Equal(t, "Queen", entry.Artist)
+	assert.Equal(t, "Bohemian Rhapsody", entry.Song)
+	assert.Equal(t, "Freddie", entry.UserName)
+	assert.Equal(t, userID, entry.UserID)
+
+	entries, err := repo.GetEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPlaylistEntryRepository_GetEntryByID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPlaylistEntryRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	created, err := repo.CreateEntry(userID, database.CreatePlaylistEntryRequest{Artist: "Queen", Song: "Innuendo", UserName: "Brian"})
+	require.NoError(t, err)
+
+	entry, err := repo.GetEntryByID(created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, "Innuendo", entry.Song)
+
+	// Test getting non-existent entry
+	entry, err = repo.GetEntryByID(99999)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestPlaylistEntryRepository_UpdateEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPlaylistEntryRepository(db)
+	userID := createTestUser(t, db, "user1@example.com")
+
+	created, err := repo.CreateEntry(userID, database.CreatePlaylistEntryRequest{Artist: "Queen", Song: "Innuendo", UserName: "Brian"})
+	require.NoError(t, err)
+
+	updateReq := database.UpdatePlaylistEntryRequest{Artist: "Queen", Song: "Under Pressure", UserName: "Roger"}
+	entry, err := repo.UpdateEntry(created.ID, updateReq)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, "Under Pressure", entry.Song)
+	assert.Equal(t, "Roger", entry.UserName)
+	assert.Equal(t, userID, entry.UserID)
+
+	// Test updating a non-existent entry
+	entry, err = repo.UpdateEntry(created.ID+1, updateReq)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestPlaylistEntryRepository_DeleteEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPlaylistEntryRepository(db)
+	userID := createTestUser(t, db, "user1@example.com")
+
+	created, err := repo.CreateEntry(userID, database.CreatePlaylistEntryRequest{Artist: "Queen", Song: "Innuendo", UserName: "Brian"})
+	require.NoError(t, err)
+
+	deleted, err := repo.DeleteEntry(created.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	entry, err := repo.GetEntryByID(created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+
+	// Test deleting an entry that is already gone
+	deleted, err = repo.DeleteEntry(created.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestPlaylistEntryRepository_Autocomplete(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewPlaylistEntryRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	for _, req := range []database.CreatePlaylistEntryRequest{
+		{Artist: "Queen", Song: "Innuendo", UserName: "Brian"},
+		{Artist: "Queen", Song: "Under Pressure", UserName: "Roger"},
+		{Artist: "Queens of the Stone Age", Song: "No One Knows", UserName: "Brianna"},
+		{Artist: "100% Pure", Song: "Percent", UserName: "Nick"},
+	} {
+		_, err := repo.CreateEntry(userID, req)
+		require.NoError(t, err)
+	}
+
+	artists, err := repo.GetArtists("quee", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Queen", "Queens of the Stone Age"}, artists)
+
+	artists, err = repo.GetArtists("queen", 1)
+	require.NoError(t, err)
+	assert.Len(t, artists, 1)
+
+	// Wildcards in the search term are matched literally
+	artists, err = repo.GetArtists("%", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100% Pure"}, artists)
+
+	userNames, err := repo.GetUserNames("bri", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Brian", "Brianna"}, userNames)
+}
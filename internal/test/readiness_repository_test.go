@@ -0,0 +1,149 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/nahue/playlists/internal/readiness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessRepository_SetReadiness(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	bandUserRepo := database.NewBandUserRepository(db)
+	songRepo := database.NewBandSongRepository(db)
+	repo := database.NewReadinessRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	viewerID := createTestUser(t, db, "viewer@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = bandUserRepo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleViewer})
+	require.NoError(t, err)
+	singer, err := bandRepo.AddBandMember(band.ID, ownerID, database.AddMemberRequest{Name: "Singer", Role: "Vocals"})
+	require.NoError(t, err)
+	drummer, err := bandRepo.AddBandMember(band.ID, ownerID, database.AddMemberRequest{Name: "Drummer", Role: "Drums"})
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE band_members SET user_id = $1 WHERE id = $2`, viewerID, drummer.ID)
+	require.NoError(t, err)
+	song, err := songRepo.CreateSong(band.ID, ownerID, database.CreateBandSongRequest{Artist: "Queen", Title: "Innuendo"})
+	require.NoError(t, err)
+
+	// Nobody has said how ready they are yet
+	got, err := repo.GetSongReadiness(song.ID, band.ID, viewerID)
+	require.NoError(t, err)
+	require.Len(t, got.Members, 2)
+	assert.Equal(t, readiness.Status(""), got.Members[0].Status)
+	assert.Equal(t, 2, got.Unreported)
+	assert.True(t, got.AtRisk)
+
+	// Members answer for themselves
+	rehearsed := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	got, err = repo.SetReadiness(song.ID, band.ID, viewerID, database.SetReadinessRequest{Status: readiness.Rough, LastRehearsedAt: &rehearsed})
+	require.NoError(t, err)
+	assert.Equal(t, "Drummer", got.Members[0].MemberName)
+	assert.Equal(t, readiness.Rough, got.Members[0].Status)
+	require.NotNil(t, got.LastRehearsedAt)
+	assert.True(t, rehearsed.Equal(*got.LastRehearsedAt))
+
+	// The last rehearsal is kept when a status changes without one
+	got, err = repo.SetReadiness(song.ID, band.ID, viewerID, database.SetReadinessRequest{Status: readiness.Solid})
+	require.NoError(t, err)
+	assert.Equal(t, readiness.Solid, got.Members[0].Status)
+	require.NotNil(t, got.Members[0].LastRehearsedAt)
+	assert.True(t, rehearsed.Equal(*got.Members[0].LastRehearsedAt))
+
+	// Only editors answer for other members
+	_, err = repo.SetReadiness(song.ID, band.ID, viewerID, database.SetReadinessRequest{MemberID: &singer.ID, Status: readiness.Solid})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+	_, err = repo.SetReadiness(song.ID, band.ID, ownerID, database.SetReadinessRequest{Status: readiness.Solid})
+	assert.ErrorIs(t, err, database.ErrBandMemberNotFound)
+	got, err = repo.SetReadiness(song.ID, band.ID, ownerID, database.SetReadinessRequest{MemberID: &singer.ID, Status: readiness.Solid, Notes: "Knows the flamenco part"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Solid)
+	assert.False(t, got.AtRisk)
+	assert.Empty(t, got.Reasons)
+
+	// Songs of other bands are not found
+	otherBand, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	got, err = repo.SetReadiness(song.ID, otherBand.ID, ownerID, database.SetReadinessRequest{Status: readiness.Solid})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestReadinessRepository_GetReadinessReport(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	gigRepo := database.NewGigRepository(db)
+	rehearsalRepo := database.NewRehearsalRepository(db)
+	repo := database.NewReadinessRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	member, err := bandRepo.AddBandMember(band.ID, userID, database.AddMemberRequest{Name: "Singer", Role: "Vocals"})
+	require.NoError(t, err)
+	playlist, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+	ready, err := playlistRepo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo"})
+	require.NoError(t, err)
+	learning, err := playlistRepo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Bicycle Race"})
+	require.NoError(t, err)
+
+	_, err = repo.SetReadiness(ready.BandSongID, band.ID, userID, database.SetReadinessRequest{MemberID: &member.ID, Status: readiness.Solid})
+	require.NoError(t, err)
+	_, err = repo.SetReadiness(learning.BandSongID, band.ID, userID, database.SetReadinessRequest{MemberID: &member.ID, Status: readiness.Learning})
+	require.NoError(t, err)
+
+	// A past rehearsal with the song on its agenda counts as rehearsing it
+	rehearsedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	details := database.RehearsalDetails{Timezone: "UTC"}
+	require.NoError(t, details.Normalize())
+	rehearsal, err := rehearsalRepo.CreateRehearsal(band.ID, userID, database.CreateRehearsalRequest{RehearsalDetails: details, Slots: []time.Time{rehearsedAt}})
+	require.NoError(t, err)
+	_, err = rehearsalRepo.ScheduleRehearsal(rehearsal.ID, band.ID, userID, database.ScheduleRehearsalRequest{SlotID: &rehearsal.Slots[0].ID})
+	require.NoError(t, err)
+	_, err = rehearsalRepo.SetAgenda(rehearsal.ID, band.ID, userID, database.SetAgendaRequest{Items: []database.AgendaItemRequest{{PlaylistSongID: &ready.ID}}})
+	require.NoError(t, err)
+
+	gig := database.CreateGigRequest{
+		GigDetails:  database.GigDetails{Name: "Friday show", StartsAt: time.Now().Add(7 * 24 * time.Hour), Timezone: "UTC"},
+		PlaylistIDs: []int{playlist.ID},
+	}
+	require.NoError(t, gig.Normalize())
+	_, err = gigRepo.CreateGig(band.ID, userID, gig)
+	require.NoError(t, err)
+	cancelled := gig
+	cancelled.Status = database.GigCancelled
+	_, err = gigRepo.CreateGig(band.ID, userID, cancelled)
+	require.NoError(t, err)
+
+	report, err := repo.GetReadinessReport(band.ID, userID)
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, "Friday show", report[0].Name)
+	assert.Equal(t, 1, report[0].AtRisk)
+	require.Len(t, report[0].Playlists, 1)
+	songs := report[0].Playlists[0].Songs
+	require.Len(t, songs, 2)
+	assert.Equal(t, "Innuendo", songs[0].Title)
+	assert.False(t, songs[0].AtRisk)
+	require.NotNil(t, songs[0].LastRehearsedAt)
+	assert.True(t, rehearsedAt.Equal(*songs[0].LastRehearsedAt))
+	assert.True(t, songs[1].AtRisk)
+	assert.Equal(t, []string{"1 member is still learning it", "never rehearsed"}, songs[1].Reasons)
+
+	playlistReadiness, err := repo.GetPlaylistReadiness(playlist.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, playlistReadiness.AtRisk)
+}
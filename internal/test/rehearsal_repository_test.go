@@ -0,0 +1,188 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/availability"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRehearsalRepository_PollAndSchedule(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	bandUserRepo := database.NewBandUserRepository(db)
+	feedRepo := database.NewCalendarFeedRepository(db)
+	repo := database.NewRehearsalRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	viewerID := createTestUser(t, db, "viewer@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	_, err = bandUserRepo.AddBandUser(band.ID, ownerID, database.AddBandUserRequest{Email: "viewer@example.com", Role: database.BandRoleViewer})
+	require.NoError(t, err)
+	singer, err := bandRepo.AddBandMember(band.ID, ownerID, database.AddMemberRequest{Name: "Singer", Role: "Vocals"})
+	require.NoError(t, err)
+	drummer, err := bandRepo.AddBandMember(band.ID, ownerID, database.AddMemberRequest{Name: "Drummer", Role: "Drums"})
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE band_members SET user_id = $1 WHERE id = $2`, viewerID, drummer.ID)
+	require.NoError(t, err)
+
+	first := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	second := first.Add(24 * time.Hour)
+	details := database.RehearsalDetails{Title: "Album prep", Timezone: "Europe/Madrid"}
+	require.NoError(t, details.Normalize())
+
+	rehearsal, err := repo.CreateRehearsal(band.ID, ownerID, database.CreateRehearsalRequest{RehearsalDetails: details, Slots: []time.Time{second, first}})
+	require.NoError(t, err)
+	require.NotNil(t, rehearsal)
+	assert.Equal(t, database.RehearsalPolling, rehearsal.Status)
+	assert.Equal(t, 2*60*60, rehearsal.Duration)
+	require.Len(t, rehearsal.Slots, 2)
+	assert.True(t, first.Equal(rehearsal.Slots[0].StartsAt))
+	assert.Nil(t, rehearsal.BestSlotID)
+	firstSlot, secondSlot := rehearsal.Slots[0].ID, rehearsal.Slots[1].ID
+
+	// Members answer for themselves
+	poll, err := repo.SetAvailability(rehearsal.ID, band.ID, viewerID, database.AvailabilityRequest{Answers: []database.SlotAvailability{
+		{SlotID: firstSlot, Answer: availability.No},
+		{SlotID: secondSlot, Answer: availability.Yes},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, availability.Tally{No: 1, Unanswered: 1}, poll.Slots[0].Tally)
+	require.NotNil(t, poll.BestSlotID)
+	assert.Equal(t, secondSlot, *poll.BestSlotID)
+
+	// Only editors answer for other members
+	_, err = repo.SetAvailability(rehearsal.ID, band.ID, viewerID, database.AvailabilityRequest{MemberID: &singer.ID, Answers: []database.SlotAvailability{{SlotID: firstSlot, Answer: availability.Yes}}})
+	assert.ErrorIs(t, err, database.ErrForbidden)
+	_, err = repo.SetAvailability(rehearsal.ID, band.ID, ownerID, database.AvailabilityRequest{Answers: []database.SlotAvailability{{SlotID: firstSlot, Answer: availability.Yes}}})
+	assert.ErrorIs(t, err, database.ErrBandMemberNotFound)
+	poll, err = repo.SetAvailability(rehearsal.ID, band.ID, ownerID, database.AvailabilityRequest{MemberID: &singer.ID, Answers: []database.SlotAvailability{
+		{SlotID: firstSlot, Answer: availability.Maybe},
+		{SlotID: secondSlot, Answer: availability.Yes},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, availability.Tally{Yes: 2}, poll.Slots[1].Tally)
+	require.Len(t, poll.Slots[1].Answers, 2)
+	assert.Equal(t, "Drummer", poll.Slots[1].Answers[0].MemberName)
+
+	// Scheduling without a slot picks the best one
+	scheduled, err := repo.ScheduleRehearsal(rehearsal.ID, band.ID, ownerID, database.ScheduleRehearsalRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, database.RehearsalScheduled, scheduled.Status)
+	require.NotNil(t, scheduled.StartsAt)
+	assert.True(t, second.Equal(*scheduled.StartsAt))
+	assert.True(t, second.Add(2*time.Hour).Equal(*scheduled.EndsAt))
+	assert.Equal(t, 1, scheduled.Sequence)
+
+	// The poll is closed once the rehearsal is scheduled
+	_, err = repo.SetAvailability(rehearsal.ID, band.ID, viewerID, database.AvailabilityRequest{Answers: []database.SlotAvailability{{SlotID: firstSlot, Answer: availability.Yes}}})
+	assert.ErrorIs(t, err, database.ErrRehearsalClosed)
+	_, err = repo.AddSlot(rehearsal.ID, band.ID, ownerID, database.AddSlotRequest{StartsAt: first.Add(time.Hour)})
+	assert.ErrorIs(t, err, database.ErrRehearsalClosed)
+
+	upcoming, err := repo.GetRehearsals(band.ID, viewerID, database.EventsUpcoming)
+	require.NoError(t, err)
+	assert.Len(t, upcoming, 1)
+
+	// Scheduled rehearsals show up in calendar feeds, cancelled ones as cancelled
+	cancelled, err := repo.CancelRehearsal(rehearsal.ID, band.ID, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cancelled.Sequence)
+	feed, err := feedRepo.CreateFeed(viewerID, database.CreateCalendarFeedRequest{}, "cal_viewer-token", "cal_viewer-t")
+	require.NoError(t, err)
+	rehearsals, err := feedRepo.GetFeedRehearsals(feed)
+	require.NoError(t, err)
+	require.Len(t, rehearsals, 1)
+	assert.Equal(t, database.RehearsalCancelled, rehearsals[0].Status)
+
+	_, err = repo.ScheduleRehearsal(rehearsal.ID, band.ID, ownerID, database.ScheduleRehearsalRequest{SlotID: &firstSlot})
+	assert.ErrorIs(t, err, database.ErrRehearsalClosed)
+}
+
+func TestRehearsalRepository_SlotsAndAgenda(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bandRepo := database.NewBandRepository(db)
+	playlistRepo := database.NewBandPlaylistRepository(db)
+	repo := database.NewRehearsalRepository(db)
+	userID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Test Band"})
+	require.NoError(t, err)
+	otherBand, err := bandRepo.CreateBand(userID, database.CreateBandRequest{Name: "Other Band"})
+	require.NoError(t, err)
+	playlist, err := playlistRepo.CreatePlaylist(band.ID, userID, database.CreatePlaylistRequest{Name: "Friday"})
+	require.NoError(t, err)
+	song, err := playlistRepo.AddSong(playlist.ID, band.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Innuendo", SongMetadata: database.SongMetadata{SongKey: "Em"}})
+	require.NoError(t, err)
+	otherPlaylist, err := playlistRepo.CreatePlaylist(otherBand.ID, userID, database.CreatePlaylistRequest{Name: "Not ours"})
+	require.NoError(t, err)
+	otherSong, err := playlistRepo.AddSong(otherPlaylist.ID, otherBand.ID, userID, database.AddSongRequest{Artist: "Queen", Song: "Bicycle Race"})
+	require.NoError(t, err)
+
+	details := database.RehearsalDetails{Timezone: "UTC", Duration: 90 * 60}
+	require.NoError(t, details.Normalize())
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	rehearsal, err := repo.CreateRehearsal(band.ID, userID, database.CreateRehearsalRequest{RehearsalDetails: details, Slots: []time.Time{start}})
+	require.NoError(t, err)
+
+	// Adding a slot at an existing time returns that slot
+	slot, err := repo.AddSlot(rehearsal.ID, band.ID, userID, database.AddSlotRequest{StartsAt: start})
+	require.NoError(t, err)
+	assert.Equal(t, rehearsal.Slots[0].ID, slot.ID)
+	slot, err = repo.AddSlot(rehearsal.ID, band.ID, userID, database.AddSlotRequest{StartsAt: start.Add(time.Hour)})
+	require.NoError(t, err)
+	deleted, err := repo.DeleteSlot(slot.ID, rehearsal.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	// Polls are limited to MaxRehearsalSlots slots
+	for i := 1; i < database.MaxRehearsalSlots; i++ {
+		_, err = repo.AddSlot(rehearsal.ID, band.ID, userID, database.AddSlotRequest{StartsAt: start.Add(time.Duration(i) * time.Hour)})
+		require.NoError(t, err)
+	}
+	_, err = repo.AddSlot(rehearsal.ID, band.ID, userID, database.AddSlotRequest{StartsAt: start.Add(-time.Hour)})
+	assert.ErrorIs(t, err, database.ErrTooManySlots)
+	_, err = db.Exec(`DELETE FROM rehearsal_slots WHERE rehearsal_id = $1 AND starts_at <> $2`, rehearsal.ID, start)
+	require.NoError(t, err)
+
+	// Nobody has answered, so there is no best slot to schedule
+	_, err = repo.ScheduleRehearsal(rehearsal.ID, band.ID, userID, database.ScheduleRehearsalRequest{})
+	assert.ErrorIs(t, err, database.ErrNoAvailableSlot)
+
+	// Agenda songs come from the band's own playlists
+	_, err = repo.SetAgenda(rehearsal.ID, band.ID, userID, database.SetAgendaRequest{Items: []database.AgendaItemRequest{{PlaylistSongID: &otherSong.ID}}})
+	assert.ErrorIs(t, err, database.ErrPlaylistSongNotFound)
+
+	agenda, err := repo.SetAgenda(rehearsal.ID, band.ID, userID, database.SetAgendaRequest{Items: []database.AgendaItemRequest{
+		{Title: "Warm up", Duration: intPtr(600)},
+		{PlaylistSongID: &song.ID, Notes: "Work on the middle section"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, agenda, 2)
+	assert.Equal(t, "Warm up", agenda[0].Title)
+	assert.Equal(t, "Innuendo", agenda[1].Song)
+	assert.Equal(t, "Em", agenda[1].SongKey)
+	require.NotNil(t, agenda[1].PlaylistID)
+	assert.Equal(t, playlist.ID, *agenda[1].PlaylistID)
+
+	got, err := repo.GetRehearsal(rehearsal.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Len(t, got.Agenda, 2)
+	assert.Len(t, got.Slots, 1)
+
+	deleted, err = repo.DeleteRehearsal(rehearsal.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	got, err = repo.GetRehearsal(rehearsal.ID, band.ID, userID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
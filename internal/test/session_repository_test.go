@@ -0,0 +1,140 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRepository_RotateRefreshToken(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewSessionRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	session, err := repo.CreateSession(userID, database.CreateSessionRequest{UserAgent: "test-agent", IPAddress: "127.0.0.1"}, "refresh-1", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, userID, session.UserID)
+	assert.Equal(t, "test-agent", session.UserAgent)
+
+	rotated, err := repo.RotateRefreshToken("refresh-1", "refresh-2", expiresAt)
+	require.NoError(t, err)
+	require.NotNil(t, rotated)
+	assert.Equal(t, session.ID, rotated.ID)
+
+	// Unknown tokens are rejected without side effects
+	rotated, err = repo.RotateRefreshToken("unknown", "refresh-x", expiresAt)
+	require.NoError(t, err)
+	assert.Nil(t, rotated)
+
+	// Reusing a rotated token revokes the whole session
+	_, err = repo.RotateRefreshToken("refresh-1", "refresh-3", expiresAt)
+	assert.ErrorIs(t, err, database.ErrRefreshTokenReused)
+
+	rotated, err = repo.RotateRefreshToken("refresh-2", "refresh-4", expiresAt)
+	require.NoError(t, err)
+	assert.Nil(t, rotated)
+
+	sessions, err := repo.GetActiveSessions(userID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestSessionRepository_IsAccessTokenActive(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewSessionRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+	otherID := createTestUser(t, db, "other@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	session, err := repo.CreateSession(userID, database.CreateSessionRequest{}, "refresh-1", expiresAt)
+	require.NoError(t, err)
+
+	active, err := repo.IsAccessTokenActive(session.ID, userID, "jti-1")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	// The session belongs to another user
+	active, err = repo.IsAccessTokenActive(session.ID, otherID, "jti-1")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	// Revoked access tokens are denied while the session stays active
+	err = repo.RevokeAccessToken("jti-1", expiresAt)
+	require.NoError(t, err)
+
+	active, err = repo.IsAccessTokenActive(session.ID, userID, "jti-1")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	active, err = repo.IsAccessTokenActive(session.ID, userID, "jti-2")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestSessionRepository_RevokeSessions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewSessionRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+	otherID := createTestUser(t, db, "other@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	laptop, err := repo.CreateSession(userID, database.CreateSessionRequest{UserAgent: "laptop"}, "refresh-1", expiresAt)
+	require.NoError(t, err)
+	_, err = repo.CreateSession(userID, database.CreateSessionRequest{UserAgent: "phone"}, "refresh-2", expiresAt)
+	require.NoError(t, err)
+	_, err = repo.CreateSession(otherID, database.CreateSessionRequest{}, "refresh-3", expiresAt)
+	require.NoError(t, err)
+
+	// Users cannot revoke each other's sessions
+	revoked, err := repo.RevokeSession(laptop.ID, otherID)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = repo.RevokeSession(laptop.ID, userID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	sessions, err := repo.GetActiveSessions(userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "phone", sessions[0].UserAgent)
+
+	err = repo.RevokeAllSessions(userID)
+	require.NoError(t, err)
+
+	sessions, err = repo.GetActiveSessions(userID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	sessions, err = repo.GetActiveSessions(otherID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestSessionRepository_IsTokenRevoked(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewSessionRepository(db)
+
+	revoked, err := repo.IsTokenRevoked("mfa-jti")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, repo.RevokeAccessToken("mfa-jti", time.Now().Add(time.Minute)))
+
+	revoked, err = repo.IsTokenRevoked("mfa-jti")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -12,7 +12,7 @@ func TestMigrationsApplied(t *testing.T) {
 	defer db.Close()
 
 	// Check that all expected tables exist
-	tables := []string{"users", "bands", "band_members", "playlist_entries"}
+	tables := []string{"users", "bands", "band_members", "band_users", "band_invitations", "playlist_entries", "sessions", "refresh_tokens", "revoked_tokens", "user_tokens", "user_totp", "recovery_codes", "personal_access_tokens", "user_identities", "band_songs", "band_playlist_sections", "gigs", "gig_playlists", "calendar_feeds", "rehearsals", "rehearsal_slots", "rehearsal_availability", "rehearsal_agenda_items", "song_readiness"}
 
 	for _, table := range tables {
 		var exists bool
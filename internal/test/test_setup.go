@@ -61,6 +61,7 @@ func cleanupTables(t *testing.T, db *sqlx.DB) {
 	db.MustExec("DELETE FROM band_members")
 	db.MustExec("DELETE FROM bands")
 	db.MustExec("DELETE FROM playlist_entries")
+	db.MustExec("DELETE FROM revoked_tokens")
 	db.MustExec("DELETE FROM users")
 
 	// Verify cleanup worked
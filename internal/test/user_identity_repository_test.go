@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserIdentityRepository_SignInCreatesAccount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserIdentityRepository(db)
+	identity := database.ExternalIdentity{
+		Provider:      "idp",
+		Subject:       "subject-1",
+		Email:         "new@example.com",
+		EmailVerified: true,
+		FirstName:     "New",
+		LastName:      "User",
+	}
+
+	user, err := repo.SignIn(identity)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "New", user.FirstName)
+	assert.NotNil(t, user.EmailVerifiedAt)
+
+	// Signing in again returns the same account, even after an email change at the provider
+	identity.Email = "renamed@example.com"
+	again, err := repo.SignIn(identity)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+
+	identities, err := repo.GetIdentities(user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, "idp", identities[0].Provider)
+	assert.Equal(t, "renamed@example.com", identities[0].Email)
+	assert.NotNil(t, identities[0].LastLoginAt)
+}
+
+func TestUserIdentityRepository_SignInLinksExistingAccount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserIdentityRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	// Unverified addresses are never linked
+	_, err := repo.SignIn(database.ExternalIdentity{Provider: "idp", Subject: "subject-1", Email: "test@example.com"})
+	assert.ErrorIs(t, err, database.ErrIdentityEmailUnverified)
+
+	user, err := repo.SignIn(database.ExternalIdentity{Provider: "idp", Subject: "subject-1", Email: "TEST@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	assert.Equal(t, userID, user.ID)
+	assert.NotNil(t, user.EmailVerifiedAt)
+
+	// A second account of the same provider cannot be linked to the user
+	_, err = repo.SignIn(database.ExternalIdentity{Provider: "idp", Subject: "subject-2", Email: "test@example.com", EmailVerified: true})
+	assert.ErrorIs(t, err, database.ErrIdentityConflict)
+
+	identities, err := repo.GetIdentities(userID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+
+	deleted, err := repo.DeleteIdentity(identities[0].ID, userID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.DeleteIdentity(identities[0].ID, userID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
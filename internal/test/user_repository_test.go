@@ -202,6 +202,31 @@ func TestUserRepository_UpdateUser(t *testing.T) {
 	assert.Nil(t, updatedUser)
 }
 
+func TestUserRepository_UpdateUser_PartialAndReverification(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserRepository(db)
+	userID := createTestUser(t, db, "partial@example.com")
+
+	_, err := repo.MarkEmailVerified(userID, "partial@example.com")
+	require.NoError(t, err)
+
+	// Empty fields keep their values and an unchanged email stays verified
+	updatedUser, err := repo.UpdateUser(userID, database.UpdateUserRequest{FirstName: "Renamed"})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updatedUser.FirstName)
+	assert.Equal(t, "User", updatedUser.LastName)
+	assert.Equal(t, "partial@example.com", updatedUser.Email)
+	assert.NotNil(t, updatedUser.EmailVerifiedAt)
+
+	// A new address must be verified again
+	updatedUser, err = repo.UpdateUser(userID, database.UpdateUserRequest{Email: "moved@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "moved@example.com", updatedUser.Email)
+	assert.Nil(t, updatedUser.EmailVerifiedAt)
+}
+
 func TestUserRepository_UpdateUser_DuplicateEmail(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -312,6 +337,93 @@ func TestUserRepository_DeleteUser(t *testing.T) {
 	assert.Contains(t, err.Error(), "user not found")
 }
 
+func TestUserRepository_VerifyPassword(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserRepository(db)
+	user, err := repo.CreateUser(database.CreateUserRequest{FirstName: "Pat", LastName: "Smith", Email: "pat@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	ok, err := repo.VerifyPassword(user.ID, "password123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.VerifyPassword(user.ID, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestUserRepository_DeleteAccount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserRepository(db)
+	bandRepo := database.NewBandRepository(db)
+	bandUserRepo := database.NewBandUserRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+	adminID := createTestUser(t, db, "admin@example.com")
+	coOwnerID := createTestUser(t, db, "coowner@example.com")
+
+	solo, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Solo Band"})
+	require.NoError(t, err)
+	withAdmin, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Band With Admin"})
+	require.NoError(t, err)
+	shared, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Shared Band"})
+	require.NoError(t, err)
+
+	_, err = bandUserRepo.AddBandUser(withAdmin.ID, ownerID, database.AddBandUserRequest{Email: "admin@example.com", Role: database.BandRoleAdmin})
+	require.NoError(t, err)
+	_, err = bandUserRepo.AddBandUser(shared.ID, ownerID, database.AddBandUserRequest{Email: "coowner@example.com", Role: database.BandRoleOwner})
+	require.NoError(t, err)
+
+	// Sole-owned bands need a policy
+	_, err = repo.DeleteAccount(ownerID, "")
+	assert.ErrorIs(t, err, database.ErrBandPolicyRequired)
+
+	// The solo band has nobody to transfer to
+	_, err = repo.DeleteAccount(ownerID, database.BandPolicyTransfer)
+	assert.ErrorIs(t, err, database.ErrNoTransferTarget)
+
+	err = bandRepo.DeleteBand(solo.ID, ownerID)
+	require.NoError(t, err)
+
+	deleted, err := repo.DeleteAccount(ownerID, database.BandPolicyTransfer)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	// The admin now owns the band and the co-owner keeps the shared band
+	role, err := bandUserRepo.GetRole(withAdmin.ID, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BandRoleOwner, role)
+
+	band, err := bandRepo.GetBandByID(shared.ID, coOwnerID)
+	require.NoError(t, err)
+	require.NotNil(t, band)
+	assert.Equal(t, coOwnerID, band.UserID)
+}
+
+func TestUserRepository_DeleteAccount_DeleteBands(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserRepository(db)
+	bandRepo := database.NewBandRepository(db)
+	ownerID := createTestUser(t, db, "owner@example.com")
+
+	band, err := bandRepo.CreateBand(ownerID, database.CreateBandRequest{Name: "Solo Band"})
+	require.NoError(t, err)
+
+	deleted, err := repo.DeleteAccount(ownerID, database.BandPolicyDelete)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	var count int
+	err = db.Get(&count, `SELECT COUNT(*) FROM bands WHERE id = $1`, band.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
 func TestUserRepository_GetAllUsers(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/nahue/playlists/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTokenRepository_ResetPassword(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserTokenRepository(db)
+	userRepo := database.NewUserRepository(db)
+	sessionRepo := database.NewSessionRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	_, err := sessionRepo.CreateSession(userID, database.CreateSessionRequest{}, "refresh-1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	err = repo.CreateToken(userID, database.TokenPasswordReset, "test@example.com", "reset-1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	err = repo.CreateToken(userID, database.TokenPasswordReset, "test@example.com", "reset-2", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Tokens are bound to their purpose
+	userToken, err := repo.VerifyEmail("reset-1")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+
+	userToken, err = repo.ResetPassword("reset-1", "new-password")
+	require.NoError(t, err)
+	require.NotNil(t, userToken)
+	assert.Equal(t, userID, userToken.UserID)
+
+	user, err := userRepo.AuthenticateUser(database.LoginRequest{Email: "test@example.com", Password: "new-password"})
+	require.NoError(t, err)
+	assert.NotNil(t, user.EmailVerifiedAt)
+
+	// Tokens are single use, and resetting revokes the other reset tokens and all sessions
+	userToken, err = repo.ResetPassword("reset-1", "another-password")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+
+	userToken, err = repo.ResetPassword("reset-2", "another-password")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+
+	sessions, err := sessionRepo.GetActiveSessions(userID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestUserTokenRepository_ResetPasswordAfterEmailChange(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserTokenRepository(db)
+	userRepo := database.NewUserRepository(db)
+	userID := createTestUser(t, db, "old@example.com")
+
+	err := repo.CreateToken(userID, database.TokenPasswordReset, "old@example.com", "reset-1", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Renaming keeps the link working
+	_, err = userRepo.UpdateUser(userID, database.UpdateUserRequest{FirstName: "Renamed", Email: "OLD@example.com"})
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM user_tokens WHERE user_id = $1`, userID))
+	assert.Equal(t, 1, count)
+
+	// A link mailed to the old address stops working once the address changes
+	_, err = userRepo.UpdateUser(userID, database.UpdateUserRequest{Email: "new@example.com"})
+	require.NoError(t, err)
+
+	userToken, err := repo.ResetPassword("reset-1", "new-password")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+}
+
+func TestUserTokenRepository_VerifyEmail(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserTokenRepository(db)
+	userRepo := database.NewUserRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	err := repo.CreateToken(userID, database.TokenEmailVerification, "test@example.com", "expired", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	userToken, err := repo.VerifyEmail("expired")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+
+	err = repo.CreateToken(userID, database.TokenEmailVerification, "old@example.com", "old-address", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Tokens issued for a previous address do not verify the current one
+	userToken, err = repo.VerifyEmail("old-address")
+	require.NoError(t, err)
+	assert.Nil(t, userToken)
+
+	err = repo.CreateToken(userID, database.TokenEmailVerification, "test@example.com", "valid", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	userToken, err = repo.VerifyEmail("valid")
+	require.NoError(t, err)
+	require.NotNil(t, userToken)
+
+	user, err := userRepo.GetUserByID(userID)
+	require.NoError(t, err)
+	assert.NotNil(t, user.EmailVerifiedAt)
+}
+
+func TestUserTokenRepository_CountRecentTokens(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := database.NewUserTokenRepository(db)
+	userID := createTestUser(t, db, "test@example.com")
+
+	for _, token := range []string{"a", "b"} {
+		err := repo.CreateToken(userID, database.TokenPasswordReset, "test@example.com", token, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+	}
+	err := repo.CreateToken(userID, database.TokenEmailVerification, "test@example.com", "c", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	count, err := repo.CountRecentTokens("TEST@example.com", database.TokenPasswordReset, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = repo.CountRecentTokens("test@example.com", database.TokenPasswordReset, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
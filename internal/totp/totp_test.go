@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors
+const rfcSecret = "12345678901234567890"
+
+func TestHOTPMatchesRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got := hotp([]byte(rfcSecret), uint64(Step(time.Unix(tt.unix, 0))), 8)
+		if got != tt.want {
+			t.Errorf("hotp at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAndValidate(t *testing.T) {
+	secret := encoding.EncodeToString([]byte(rfcSecret))
+	now := time.Unix(1111111109, 0)
+
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+	if code != "081804" {
+		t.Errorf("Code() = %s, want 081804", code)
+	}
+
+	step, ok := Validate(secret, code, now, 1)
+	if !ok || step != Step(now) {
+		t.Errorf("Validate() = %d, %v, want %d, true", step, ok, Step(now))
+	}
+
+	// One step of clock drift is accepted, two are not
+	if _, ok := Validate(secret, code, now.Add(Period), 1); !ok {
+		t.Error("Expected a code from the previous step to be accepted")
+	}
+	if _, ok := Validate(secret, code, now.Add(2*Period), 1); ok {
+		t.Error("Expected a code from two steps ago to be rejected")
+	}
+
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Error("Expected a short code to be rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	key, err := decodeSecret(strings.ToLower(secret))
+	if err != nil || len(key) != secretSize {
+		t.Errorf("Expected a %d byte secret, got %d bytes (%v)", secretSize, len(key), err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("JBSWY3DPEHPK3PXP", "Playlists", "john@example.com")
+
+	for _, want := range []string{"otpauth://totp/Playlists:john@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Playlists", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("Expected URI to contain %q, got %s", want, uri)
+		}
+	}
+}
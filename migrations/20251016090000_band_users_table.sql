@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE band_users (
+    band_id INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (band_id, user_id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_band_users_user_id ON band_users(user_id);
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_band_users_updated_at BEFORE UPDATE ON band_users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Every existing band is owned by the user that created it
+INSERT INTO band_users (band_id, user_id, role)
+SELECT id, user_id, 'owner' FROM bands;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_band_users_updated_at ON band_users;
+DROP INDEX IF EXISTS idx_band_users_user_id;
+DROP TABLE IF EXISTS band_users;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Band members can be linked to the user account that plays that part
+ALTER TABLE band_members ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_band_members_user_id ON band_members(user_id);
+
+CREATE TABLE band_invitations (
+    id SERIAL PRIMARY KEY,
+    band_id INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'editor', 'viewer')),
+    band_member_id INTEGER REFERENCES band_members(id) ON DELETE SET NULL,
+    token_id VARCHAR(64) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'revoked')),
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    responded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_band_invitations_band_id ON band_invitations(band_id);
+-- Only one pending invitation per email address and band
+CREATE UNIQUE INDEX idx_band_invitations_pending ON band_invitations(band_id, LOWER(email)) WHERE status = 'pending';
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_band_invitations_updated_at BEFORE UPDATE ON band_invitations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_band_invitations_updated_at ON band_invitations;
+DROP INDEX IF EXISTS idx_band_invitations_pending;
+DROP INDEX IF EXISTS idx_band_invitations_band_id;
+DROP TABLE IF EXISTS band_invitations;
+DROP INDEX IF EXISTS idx_band_members_user_id;
+ALTER TABLE band_members DROP COLUMN IF EXISTS user_id;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts at external OpenID Connect providers linked to users. A provider
+-- identifies an account by its subject; each user links at most one
+-- account per provider.
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_user_identities_updated_at BEFORE UPDATE ON user_identities
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_user_identities_updated_at ON user_identities;
+DROP TABLE IF EXISTS user_identities;
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Catalog of the songs a band plays. Playlist songs reference a catalog
+-- song and may override its notes, key and tempo for that setlist.
+CREATE TABLE band_songs (
+    id SERIAL PRIMARY KEY,
+    band_id INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+    artist VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    song_key VARCHAR(10) NOT NULL DEFAULT '',
+    tempo INTEGER,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A song appears once per band regardless of case
+CREATE UNIQUE INDEX idx_band_songs_band_id_artist_title ON band_songs(band_id, LOWER(artist), LOWER(title));
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_band_songs_updated_at BEFORE UPDATE ON band_songs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Move existing playlist songs into the catalog, one catalog song per band,
+-- artist and title. The earliest copy provides the spelling and notes.
+INSERT INTO band_songs (band_id, artist, title, notes, created_at)
+SELECT DISTINCT ON (p.band_id, LOWER(TRIM(s.artist)), LOWER(TRIM(s.song)))
+    p.band_id, TRIM(s.artist), TRIM(s.song), COALESCE(s.notes, ''), s.created_at
+FROM band_playlist_songs s
+JOIN band_playlists p ON p.id = s.playlist_id
+ORDER BY p.band_id, LOWER(TRIM(s.artist)), LOWER(TRIM(s.song)), s.created_at, s.id;
+
+ALTER TABLE band_playlist_songs
+    ADD COLUMN band_song_id INTEGER REFERENCES band_songs(id),
+    ADD COLUMN song_key VARCHAR(10),
+    ADD COLUMN tempo INTEGER;
+
+UPDATE band_playlist_songs s
+SET band_song_id = bs.id
+FROM band_playlists p, band_songs bs
+WHERE p.id = s.playlist_id
+    AND bs.band_id = p.band_id
+    AND LOWER(bs.artist) = LOWER(TRIM(s.artist))
+    AND LOWER(bs.title) = LOWER(TRIM(s.song));
+
+-- Notes of playlist songs are now setlist overrides of the catalog notes
+UPDATE band_playlist_songs s
+SET notes = NULL
+FROM band_songs bs
+WHERE bs.id = s.band_song_id AND COALESCE(s.notes, '') = bs.notes;
+
+ALTER TABLE band_playlist_songs
+    ALTER COLUMN band_song_id SET NOT NULL,
+    DROP COLUMN artist,
+    DROP COLUMN song;
+
+CREATE INDEX idx_band_playlist_songs_band_song_id ON band_playlist_songs(band_song_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE band_playlist_songs
+    ADD COLUMN artist VARCHAR(255),
+    ADD COLUMN song VARCHAR(255);
+
+UPDATE band_playlist_songs s
+SET artist = bs.artist, song = bs.title, notes = COALESCE(s.notes, bs.notes)
+FROM band_songs bs
+WHERE bs.id = s.band_song_id;
+
+ALTER TABLE band_playlist_songs
+    ALTER COLUMN artist SET NOT NULL,
+    ALTER COLUMN song SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_band_playlist_songs_band_song_id;
+ALTER TABLE band_playlist_songs
+    DROP COLUMN tempo,
+    DROP COLUMN song_key,
+    DROP COLUMN band_song_id;
+
+DROP TRIGGER IF EXISTS update_band_songs_updated_at ON band_songs;
+DROP INDEX IF EXISTS idx_band_songs_band_id_artist_title;
+DROP TABLE IF EXISTS band_songs;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Musical details of catalog songs. Keys and time signatures are validated
+-- by the application; durations are in seconds.
+ALTER TABLE band_songs
+    ADD COLUMN time_signature VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN duration INTEGER CHECK (duration > 0),
+    ADD COLUMN capo INTEGER CHECK (capo >= 0),
+    ADD COLUMN tuning VARCHAR(50) NOT NULL DEFAULT '',
+    ADD CONSTRAINT band_songs_tempo_check CHECK (tempo > 0);
+
+-- Setlist overrides of the same details; NULL follows the catalog
+ALTER TABLE band_playlist_songs
+    ADD COLUMN time_signature VARCHAR(10),
+    ADD COLUMN duration INTEGER CHECK (duration > 0),
+    ADD COLUMN capo INTEGER CHECK (capo >= 0),
+    ADD COLUMN tuning VARCHAR(50),
+    ADD CONSTRAINT band_playlist_songs_tempo_check CHECK (tempo > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE band_playlist_songs
+    DROP CONSTRAINT IF EXISTS band_playlist_songs_tempo_check,
+    DROP COLUMN tuning,
+    DROP COLUMN capo,
+    DROP COLUMN duration,
+    DROP COLUMN time_signature;
+
+ALTER TABLE band_songs
+    DROP CONSTRAINT IF EXISTS band_songs_tempo_check,
+    DROP COLUMN tuning,
+    DROP COLUMN capo,
+    DROP COLUMN duration,
+    DROP COLUMN time_signature;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Timing settings of a playlist, in seconds: the gap between songs and the
+-- length each set should fit in
+ALTER TABLE band_playlists
+    ADD COLUMN changeover_duration INTEGER NOT NULL DEFAULT 0 CHECK (changeover_duration >= 0),
+    ADD COLUMN set_target_duration INTEGER CHECK (set_target_duration > 0);
+
+-- Named sections of a playlist, such as sets separated by breaks or an
+-- encore. A section may have its own target length.
+CREATE TABLE band_playlist_sections (
+    id SERIAL PRIMARY KEY,
+    playlist_id INTEGER NOT NULL REFERENCES band_playlists(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    target_duration INTEGER CHECK (target_duration > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Songs outside any section come before the first section
+ALTER TABLE band_playlist_songs
+    ADD COLUMN section_id INTEGER REFERENCES band_playlist_sections(id) ON DELETE SET NULL;
+
+-- Create indexes for better performance
+CREATE INDEX idx_band_playlist_sections_playlist_id ON band_playlist_sections(playlist_id);
+CREATE INDEX idx_band_playlist_songs_section_id ON band_playlist_songs(section_id);
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_band_playlist_sections_updated_at BEFORE UPDATE ON band_playlist_sections
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_band_playlist_sections_updated_at ON band_playlist_sections;
+DROP INDEX IF EXISTS idx_band_playlist_songs_section_id;
+DROP INDEX IF EXISTS idx_band_playlist_sections_playlist_id;
+ALTER TABLE band_playlist_songs DROP COLUMN section_id;
+DROP TABLE IF EXISTS band_playlist_sections;
+ALTER TABLE band_playlists
+    DROP COLUMN set_target_duration,
+    DROP COLUMN changeover_duration;
+-- +goose StatementEnd
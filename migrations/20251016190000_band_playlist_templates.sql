@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Templates are playlists new playlists can start from
+ALTER TABLE band_playlists ADD COLUMN is_template BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_band_playlists_templates ON band_playlists(band_id) WHERE is_template;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_band_playlists_templates;
+ALTER TABLE band_playlists DROP COLUMN is_template;
+-- +goose StatementEnd
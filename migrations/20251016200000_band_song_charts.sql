@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ChordPro lyric and chord sheet of a catalog song; empty when it has none
+ALTER TABLE band_songs ADD COLUMN chordpro TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE band_songs DROP COLUMN chordpro;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Shows a band plays. Times are instants; timezone is the IANA zone of the
+-- venue they are shown in. The fee is in cents of currency.
+CREATE TABLE gigs (
+    id SERIAL PRIMARY KEY,
+    band_id INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    venue_name VARCHAR(255) NOT NULL DEFAULT '',
+    venue_address TEXT NOT NULL DEFAULT '',
+    load_in_at TIMESTAMP WITH TIME ZONE,
+    soundcheck_at TIMESTAMP WITH TIME ZONE,
+    set_at TIMESTAMP WITH TIME ZONE,
+    fee INTEGER CHECK (fee >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'tentative' CHECK (status IN ('tentative', 'confirmed', 'cancelled')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The playlists played at a gig, in order
+CREATE TABLE gig_playlists (
+    gig_id INTEGER NOT NULL REFERENCES gigs(id) ON DELETE CASCADE,
+    playlist_id INTEGER NOT NULL REFERENCES band_playlists(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (gig_id, playlist_id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_gigs_band_id_starts_at ON gigs(band_id, starts_at);
+CREATE INDEX idx_gig_playlists_playlist_id ON gig_playlists(playlist_id);
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_gigs_updated_at BEFORE UPDATE ON gigs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_gigs_updated_at ON gigs;
+DROP INDEX IF EXISTS idx_gig_playlists_playlist_id;
+DROP INDEX IF EXISTS idx_gigs_band_id_starts_at;
+DROP TABLE IF EXISTS gig_playlists;
+DROP TABLE IF EXISTS gigs;
+-- +goose StatementEnd
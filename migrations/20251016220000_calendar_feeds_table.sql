@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Revision number of a gig, raised on every change so subscribed calendars
+-- replace their copy
+ALTER TABLE gigs ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+
+-- Secret iCalendar subscription URLs, stored as SHA-256 hashes. A feed
+-- covers one band, or every band of the user when band_id is NULL.
+CREATE TABLE calendar_feeds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    band_id INTEGER REFERENCES bands(id) ON DELETE CASCADE,
+    token_prefix VARCHAR(12) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each user has at most one feed per band and one for all their bands
+CREATE UNIQUE INDEX idx_calendar_feeds_user_id_band_id ON calendar_feeds(user_id, band_id) WHERE band_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_calendar_feeds_user_id_all_bands ON calendar_feeds(user_id) WHERE band_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_calendar_feeds_user_id_all_bands;
+DROP INDEX IF EXISTS idx_calendar_feeds_user_id_band_id;
+DROP TABLE IF EXISTS calendar_feeds;
+ALTER TABLE gigs DROP COLUMN sequence;
+-- +goose StatementEnd
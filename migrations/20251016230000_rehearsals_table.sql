@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rehearsals of a band. A rehearsal is polled over candidate slots until it
+-- is scheduled at one of them; starts_at is set from then on. Duration is in
+-- seconds and timezone is the IANA zone times are shown in.
+CREATE TABLE rehearsals (
+    id SERIAL PRIMARY KEY,
+    band_id INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL,
+    duration INTEGER NOT NULL CHECK (duration > 0),
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'polling' CHECK (status IN ('polling', 'scheduled', 'cancelled')),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    sequence INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Candidate start times members are polled on
+CREATE TABLE rehearsal_slots (
+    id SERIAL PRIMARY KEY,
+    rehearsal_id INTEGER NOT NULL REFERENCES rehearsals(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (rehearsal_id, starts_at)
+);
+
+-- Whether each band member can make a slot
+CREATE TABLE rehearsal_availability (
+    slot_id INTEGER NOT NULL REFERENCES rehearsal_slots(id) ON DELETE CASCADE,
+    band_member_id INTEGER NOT NULL REFERENCES band_members(id) ON DELETE CASCADE,
+    answer VARCHAR(10) NOT NULL CHECK (answer IN ('yes', 'maybe', 'no')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (slot_id, band_member_id)
+);
+
+-- What a rehearsal works on, in order: songs of the band's playlists or
+-- other items named by title. Duration is in seconds.
+CREATE TABLE rehearsal_agenda_items (
+    id SERIAL PRIMARY KEY,
+    rehearsal_id INTEGER NOT NULL REFERENCES rehearsals(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    playlist_song_id INTEGER REFERENCES band_playlist_songs(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    duration INTEGER CHECK (duration > 0),
+    CHECK (playlist_song_id IS NOT NULL OR title <> '')
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_rehearsals_band_id_starts_at ON rehearsals(band_id, starts_at);
+CREATE INDEX idx_rehearsal_availability_band_member_id ON rehearsal_availability(band_member_id);
+CREATE INDEX idx_rehearsal_agenda_items_rehearsal_id ON rehearsal_agenda_items(rehearsal_id, position);
+CREATE INDEX idx_rehearsal_agenda_items_playlist_song_id ON rehearsal_agenda_items(playlist_song_id);
+
+-- Create trigger to update updated_at timestamp
+CREATE TRIGGER update_rehearsals_updated_at BEFORE UPDATE ON rehearsals
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_rehearsals_updated_at ON rehearsals;
+DROP INDEX IF EXISTS idx_rehearsal_agenda_items_playlist_song_id;
+DROP INDEX IF EXISTS idx_rehearsal_agenda_items_rehearsal_id;
+DROP INDEX IF EXISTS idx_rehearsal_availability_band_member_id;
+DROP INDEX IF EXISTS idx_rehearsals_band_id_starts_at;
+DROP TABLE IF EXISTS rehearsal_agenda_items;
+DROP TABLE IF EXISTS rehearsal_availability;
+DROP TABLE IF EXISTS rehearsal_slots;
+DROP TABLE IF EXISTS rehearsals;
+-- +goose StatementEnd
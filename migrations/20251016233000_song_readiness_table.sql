@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- How ready each band member is to play a song of the band's catalog. The
+-- status holds for every playlist the song is in. last_rehearsed_at is when
+-- the member last rehearsed the song, on their own or with the band.
+CREATE TABLE song_readiness (
+    band_song_id INTEGER NOT NULL REFERENCES band_songs(id) ON DELETE CASCADE,
+    band_member_id INTEGER NOT NULL REFERENCES band_members(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('learning', 'rough', 'needs_work', 'solid')),
+    last_rehearsed_at TIMESTAMP WITH TIME ZONE,
+    notes TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (band_song_id, band_member_id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_song_readiness_band_member_id ON song_readiness(band_member_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_song_readiness_band_member_id;
+DROP TABLE IF EXISTS song_readiness;
+-- +goose StatementEnd